@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+	"github.com/upper/db/v4"
+)
+
+// advisorySeverities are the advisory severities, least severe first.
+var advisorySeverities = []string{"LOW", "MODERATE", "HIGH", "CRITICAL"}
+
+// starAdvisory is a vulnerability affecting the package of a star.
+type starAdvisory struct {
+	FullName string `json:"full_name"`
+	githubclient.Vulnerability
+	// Confirmed is set when the advisory references the repository, the
+	// package being matched by name otherwise.
+	Confirmed bool `json:"confirmed"`
+}
+
+// reportAdvisories looks up the packages of the starred repositories in the
+// GitHub Advisory Database, reporting the known advisories affecting them.
+func reportAdvisories(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("advisories", flag.ExitOnError)
+	severity := flags.String("severity", "critical", "Minimum severity reported: low, moderate, high or critical")
+	loose := flags.Bool("loose", false, "Report the advisories of packages matched by name too, even when they don't reference the repository")
+	asJSON := flags.Bool("json", false, "Print the advisories as JSON")
+	flags.Parse(args)
+
+	var severities []string
+	for i, s := range advisorySeverities {
+		if strings.EqualFold(s, *severity) {
+			severities = advisorySeverities[i:]
+		}
+	}
+	if severities == nil {
+		return fmt.Errorf("unknown --severity %q, expected low, moderate, high or critical", *severity)
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	var repos []Repository
+	err = sess.Collection("starred_repos").
+		Find(db.Cond{"source": sourceStarred, "unstarred_at IS": nil}).
+		Select("full_name", "language").
+		OrderBy("full_name").
+		All(&repos)
+	if err != nil {
+		return err
+	}
+
+	// owners maps the packages to the stars they were guessed from.
+	owners := map[githubclient.Package][]string{}
+	var packages []githubclient.Package
+	for _, r := range repos {
+		p, ok := advisoryPackage(r)
+		if !ok {
+			continue
+		}
+		if owners[p] == nil {
+			packages = append(packages, p)
+		}
+		owners[p] = append(owners[p], r.FullName)
+	}
+	logger.Infof("Looking up %d packages in the GitHub Advisory Database", len(packages))
+
+	gh, err := newGitHubClient()
+	if err != nil {
+		return err
+	}
+	vulns, err := gh.Vulnerabilities(ctx, packages, severities)
+	if err != nil {
+		return fmt.Errorf("querying the GitHub Advisory Database: %w", err)
+	}
+
+	var found []starAdvisory
+	for _, v := range vulns {
+		for _, fullName := range owners[v.Package] {
+			a := starAdvisory{FullName: fullName, Vulnerability: v, Confirmed: referencesRepo(v, fullName)}
+			if a.Confirmed || *loose || nameIsRepo(v.Package) {
+				found = append(found, a)
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].FullName != found[j].FullName {
+			return found[i].FullName < found[j].FullName
+		}
+		return found[i].PublishedAt.After(found[j].PublishedAt)
+	})
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if found == nil {
+			found = []starAdvisory{}
+		}
+		return enc.Encode(found)
+	}
+
+	return writeAdvisories(os.Stdout, found)
+}
+
+// advisoryPackage returns the package r is likely published as, guessed
+// from its language and name.
+func advisoryPackage(r Repository) (githubclient.Package, bool) {
+	_, name, _ := strings.Cut(r.FullName, "/")
+
+	switch r.Language {
+	case "Go":
+		return githubclient.Package{Ecosystem: "GO", Name: "github.com/" + r.FullName}, true
+	case "Swift":
+		return githubclient.Package{Ecosystem: "SWIFT", Name: "github.com/" + r.FullName}, true
+	case "PHP":
+		return githubclient.Package{Ecosystem: "COMPOSER", Name: strings.ToLower(r.FullName)}, true
+	case "JavaScript", "TypeScript":
+		return githubclient.Package{Ecosystem: "NPM", Name: strings.ToLower(name)}, true
+	case "Python":
+		return githubclient.Package{Ecosystem: "PIP", Name: name}, true
+	case "Rust":
+		return githubclient.Package{Ecosystem: "RUST", Name: name}, true
+	case "Ruby":
+		return githubclient.Package{Ecosystem: "RUBYGEMS", Name: name}, true
+	case "C#":
+		return githubclient.Package{Ecosystem: "NUGET", Name: name}, true
+	case "Dart":
+		return githubclient.Package{Ecosystem: "PUB", Name: name}, true
+	case "Elixir", "Erlang":
+		return githubclient.Package{Ecosystem: "ERLANG", Name: name}, true
+	}
+
+	return githubclient.Package{}, false
+}
+
+// nameIsRepo reports whether the package name is the repository path, so
+// that it can't belong to another project.
+func nameIsRepo(p githubclient.Package) bool {
+	return p.Ecosystem == "GO" || p.Ecosystem == "SWIFT"
+}
+
+// referencesRepo reports whether the advisory references the repository.
+func referencesRepo(v githubclient.Vulnerability, fullName string) bool {
+	prefix := "https://github.com/" + strings.ToLower(fullName)
+	for _, ref := range v.References {
+		ref = strings.ToLower(ref)
+		if ref == prefix || strings.HasPrefix(ref, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func writeAdvisories(out io.Writer, found []starAdvisory) error {
+	if len(found) == 0 {
+		logger.Info("No known advisories affect the stars")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tADVISORY\tSEVERITY\tVULNERABLE\tPATCHED\tSUMMARY")
+	for _, a := range found {
+		id := a.GHSAID
+		if len(a.CVEs) > 0 {
+			id = a.CVEs[0]
+		}
+		patched := a.FirstPatched
+		if patched == "" {
+			patched = "-"
+		}
+		name := a.FullName
+		if !a.Confirmed && !nameIsRepo(a.Package) {
+			name += " (?)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name, id, strings.ToLower(a.Severity), a.VulnerableRange, patched, a.Summary)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/upper/db/v4"
+)
+
+const (
+	airtableAPI = "https://api.airtable.com/v0"
+	// airtableBatch is the maximum number of records Airtable accepts per
+	// request.
+	airtableBatch = 10
+	// airtableRateLimitWait is how long Airtable asks clients exceeding
+	// the rate limit to wait.
+	airtableRateLimitWait = 30 * time.Second
+)
+
+// airtableRecord is a record sent to Airtable and returned by upserts.
+type airtableRecord struct {
+	ID     string         `json:"id,omitempty"`
+	Fields map[string]any `json:"fields"`
+}
+
+// pushAirtable upserts a row per public star into the table from the
+// [push.airtable] configuration section, keyed by the ID field, the GitHub
+// repository ID. Only the rows of the stars that changed since the previous
+// push are sent. Fields other than the pushed ones are left alone, so the
+// table can hold notes and tags of its own.
+func pushAirtable(ctx context.Context, sess db.Session, _ pushOptions) error {
+	cfg := config.Push.Airtable
+	if cfg.Token == "" || cfg.BaseID == "" || cfg.Table == "" {
+		return fmt.Errorf("no Airtable table configured, set token, base_id and table in the [push.airtable] section of %s", configFile)
+	}
+	base := airtableAPI
+	if cfg.BaseURL != "" {
+		base = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	endpoint := base + "/" + url.PathEscape(cfg.BaseID) + "/" + url.PathEscape(cfg.Table)
+	client := newHTTPClient(httpTimeout)
+
+	var repos []Repository
+	err := sess.Collection("starred_repos").
+		Find(db.Cond{"source": sourceStarred, "unstarred_at IS": nil, "private": false}).
+		OrderBy("starred_at").
+		All(&repos)
+	if err != nil {
+		return err
+	}
+	pushed, err := pushedItems(sess, "airtable")
+	if err != nil {
+		return err
+	}
+
+	// pending are the records of the batch, with their hashes by ID.
+	var pending []airtableRecord
+	hashes := map[int]string{}
+	created, updated := 0, 0
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		var resp struct {
+			Records        []airtableRecord `json:"records"`
+			CreatedRecords []string         `json:"createdRecords"`
+		}
+		err := airtableUpsert(ctx, client, endpoint, cfg.Token, pending, &resp)
+		if err != nil {
+			return err
+		}
+		for _, rec := range resp.Records {
+			id, ok := rec.Fields["ID"].(float64)
+			if !ok {
+				continue
+			}
+			item := pushedItem{RemoteID: rec.ID, Hash: hashes[int(id)]}
+			if err := savePushed(sess, "airtable", int(id), item); err != nil {
+				return err
+			}
+		}
+		created += len(resp.CreatedRecords)
+		updated += len(resp.Records) - len(resp.CreatedRecords)
+		pending, hashes = nil, map[int]string{}
+
+		return nil
+	}
+
+	for _, r := range repos {
+		r.Topics = normalizeTopics(r.Topics, config.Topics.Aliases)
+		fields := airtableFields(r)
+		hash := pushHash(fields)
+		if item, ok := pushed[r.ID]; ok && item.Hash == hash {
+			continue
+		}
+		pending = append(pending, airtableRecord{Fields: fields})
+		hashes[r.ID] = hash
+		if len(pending) == airtableBatch {
+			if err := flush(); err != nil {
+				return fmt.Errorf("pushing to Airtable: %w", err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return fmt.Errorf("pushing to Airtable: %w", err)
+	}
+	logger.Infof("Airtable rows created: %d, updated: %d", created, updated)
+
+	return nil
+}
+
+// airtableFields returns the fields of the row of r.
+func airtableFields(r Repository) map[string]any {
+	topics := []string{}
+	for _, t := range r.Topics {
+		if t != "" {
+			topics = append(topics, t)
+		}
+	}
+	fields := map[string]any{
+		"ID":          r.ID,
+		"Name":        r.FullName,
+		"URL":         r.HTMLURL,
+		"Description": strings.TrimSpace(r.Description),
+		"Language":    nil,
+		"Topics":      topics,
+		"Stars":       r.StargazersCount,
+		"Starred":     r.StarredAt.UTC().Format(time.RFC3339),
+	}
+	if r.Language != "" {
+		fields["Language"] = r.Language
+	}
+	return fields
+}
+
+// airtableUpsert upserts the records merging on the ID field, typecasting
+// the values so that new languages and topics become select options.
+func airtableUpsert(ctx context.Context, client *http.Client, endpoint, token string, records []airtableRecord, v any) error {
+	payload, err := json.Marshal(map[string]any{
+		"performUpsert": map[string]any{"fieldsToMergeOn": []string{"ID"}},
+		"typecast":      true,
+		"records":       records,
+	})
+	if err != nil {
+		return err
+	}
+
+	return retryPush(ctx, "Airtable", airtableRateLimitWait, func() error {
+		req, err := http.NewRequestWithContext(ctx, "PATCH", endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		return doPush(client, req, v)
+	})
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/upper/db/v4"
+)
+
+// annotationsVersion is the version of the annotations file format.
+const annotationsVersion = 1
+
+// Sections of the annotations file.
+const (
+	annotationPins      = "pins"
+	annotationPaths     = "paths"
+	annotationBookmarks = "bookmarks"
+	annotationReasons   = "reasons"
+)
+
+var annotationSections = []string{annotationPins, annotationPaths, annotationBookmarks, annotationReasons}
+
+// annotations are the user-generated data of the database: everything a
+// sync can't rebuild from GitHub.
+type annotations struct {
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Pins       []pinAnnotation `json:"pins,omitempty"`
+	// PathBookmarks are the bookmarked repository sub-paths.
+	PathBookmarks []PathBookmark `json:"path_bookmarks,omitempty"`
+	// Bookmarks are the repositories bookmarked without starring them.
+	Bookmarks []bookmarkAnnotation `json:"bookmarks,omitempty"`
+	// Reasons are why the repositories were starred.
+	Reasons []reasonAnnotation `json:"reasons,omitempty"`
+}
+
+type pinAnnotation struct {
+	FullName string     `json:"full_name"`
+	Priority int        `json:"priority"`
+	PinnedAt *time.Time `json:"pinned_at,omitempty"`
+}
+
+type bookmarkAnnotation struct {
+	FullName     string    `json:"full_name"`
+	BookmarkedAt time.Time `json:"bookmarked_at"`
+}
+
+type reasonAnnotation struct {
+	FullName  string     `json:"full_name"`
+	Reason    string     `json:"reason"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// parseSections parses a comma separated list of annotation sections.
+func parseSections(s string) (map[string]bool, error) {
+	sections := map[string]bool{}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		valid := false
+		for _, known := range annotationSections {
+			valid = valid || name == known
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown section %q, expected %s", name, strings.Join(annotationSections, ", "))
+		}
+		sections[name] = true
+	}
+
+	return sections, nil
+}
+
+// exportAnnotationsCmd writes the pins, path bookmarks, bookmarks and star
+// reasons to a JSON file, to be restored with import-annotations.
+func exportAnnotationsCmd(args []string) error {
+	flags := flag.NewFlagSet("export-annotations", flag.ExitOnError)
+	output := flags.String("output", "", "Write the annotations to a file instead of stdout")
+	include := flags.String("include", strings.Join(annotationSections, ","), "Sections exported: "+strings.Join(annotationSections, ", "))
+	flags.Parse(args)
+
+	sections, err := parseSections(*include)
+	if err != nil {
+		return err
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	a, err := loadAnnotations(sess, sections)
+	if err != nil {
+		return err
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(a); err != nil {
+		return err
+	}
+	logger.Infof("Exported %d pins, %d path bookmarks, %d bookmarks and %d reasons", len(a.Pins), len(a.PathBookmarks), len(a.Bookmarks), len(a.Reasons))
+
+	return nil
+}
+
+func loadAnnotations(sess db.Session, sections map[string]bool) (annotations, error) {
+	a := annotations{Version: annotationsVersion, ExportedAt: time.Now().UTC()}
+	starred := sess.Collection("starred_repos")
+
+	if sections[annotationPins] {
+		var pinned []Repository
+		if err := starred.Find(db.Cond{"pinned": true}).OrderBy(pinnedOrder...).All(&pinned); err != nil {
+			return a, err
+		}
+		for _, r := range pinned {
+			a.Pins = append(a.Pins, pinAnnotation{FullName: r.FullName, Priority: r.PinPriority, PinnedAt: r.PinnedAt})
+		}
+	}
+
+	if sections[annotationPaths] {
+		if err := sess.Collection("path_bookmarks").Find().OrderBy("full_name", "path").All(&a.PathBookmarks); err != nil {
+			return a, err
+		}
+		for i := range a.PathBookmarks {
+			a.PathBookmarks[i].ID = 0
+		}
+	}
+
+	if sections[annotationBookmarks] {
+		var bookmarked []Repository
+		if err := starred.Find(db.Cond{"source": sourceManual}).OrderBy("starred_at").All(&bookmarked); err != nil {
+			return a, err
+		}
+		for _, r := range bookmarked {
+			a.Bookmarks = append(a.Bookmarks, bookmarkAnnotation{FullName: r.FullName, BookmarkedAt: r.StarredAt.UTC()})
+		}
+	}
+
+	if sections[annotationReasons] {
+		var reasoned []Repository
+		if err := starred.Find(db.Cond{"reason !=": ""}).OrderBy("full_name").All(&reasoned); err != nil {
+			return a, err
+		}
+		for _, r := range reasoned {
+			a.Reasons = append(a.Reasons, reasonAnnotation{FullName: r.FullName, Reason: r.Reason, UpdatedAt: r.ReasonAt})
+		}
+	}
+
+	return a, nil
+}
+
+// importAnnotationsCmd restores the annotations written by
+// export-annotations. Bookmarked repositories missing from the database are
+// fetched from GitHub, pins of missing repositories are skipped. Pins,
+// reasons and path bookmark notes different from the local ones are
+// resolved by the --prefer rule, or asking with --interactive.
+func importAnnotationsCmd(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import-annotations", flag.ExitOnError)
+	include := flags.String("include", strings.Join(annotationSections, ","), "Sections imported: "+strings.Join(annotationSections, ", "))
+	prefer := flags.String("prefer", preferNewest, "Value kept on conflicts: newest, local or remote")
+	interactive := flags.Bool("interactive", false, "Ask which value is kept on every conflict, the --prefer one by default")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: gh-stars-exporter db import-annotations [--include SECTIONS] [--prefer RULE] [--interactive] FILE")
+	}
+
+	sections, err := parseSections(*include)
+	if err != nil {
+		return err
+	}
+	resolver, err := newConflictResolver(*prefer, *interactive)
+	if err != nil {
+		return err
+	}
+
+	b, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	var a annotations
+	if err := json.Unmarshal(b, &a); err != nil {
+		return fmt.Errorf("decoding %s: %w", flags.Arg(0), err)
+	}
+	if a.Version != annotationsVersion {
+		return fmt.Errorf("unsupported annotations version %d", a.Version)
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	// Bookmarks first, so the pins and reasons of bookmarked repositories
+	// apply.
+	if sections[annotationBookmarks] && len(a.Bookmarks) > 0 {
+		gh, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		added, failed := 0, 0
+		for _, bm := range a.Bookmarks {
+			fullName, ok, err := addBookmark(ctx, gh, sess, bm.FullName, bm.BookmarkedAt)
+			if err != nil {
+				logger.Warnf("Bookmarking %s: %s", bm.FullName, err)
+				failed++
+				continue
+			}
+			if ok {
+				logger.Debugf("Bookmarked %s", fullName)
+				added++
+			}
+		}
+		logger.Infof("Bookmarks: %d added, %d already in the database, %d failed", added, len(a.Bookmarks)-added-failed, failed)
+	}
+
+	if sections[annotationPaths] {
+		added, replaced, err := importPathBookmarks(sess, a.PathBookmarks, resolver)
+		if err != nil {
+			return err
+		}
+		logger.Infof("Path bookmarks: %d added, %d notes replaced, %d already in the database", added, replaced, len(a.PathBookmarks)-added-replaced)
+	}
+
+	if sections[annotationPins] {
+		pinned, kept := 0, 0
+		for _, p := range a.Pins {
+			res := sess.Collection("starred_repos").Find(db.Raw("full_name = ? COLLATE NOCASE", p.FullName))
+			var local Repository
+			if err := res.One(&local); errors.Is(err, db.ErrNoMoreRows) {
+				logger.Warnf("Not pinning %s, it's not in the database", p.FullName)
+				continue
+			} else if err != nil {
+				return err
+			}
+			pinnedAt := timeOr(p.PinnedAt, a.ExportedAt)
+			if local.Pinned && local.PinPriority != p.Priority {
+				remote, err := resolver.useRemote(annotationConflict{
+					What:     "pin priority of " + local.FullName,
+					Local:    strconv.Itoa(local.PinPriority),
+					Remote:   strconv.Itoa(p.Priority),
+					LocalAt:  timeOr(local.PinnedAt, time.Time{}),
+					RemoteAt: pinnedAt,
+				})
+				if err != nil {
+					return err
+				}
+				if !remote {
+					kept++
+					continue
+				}
+			}
+			if err := res.Update(map[string]interface{}{"pinned": true, "pin_priority": p.Priority, "pinned_at": pinnedAt}); err != nil {
+				return err
+			}
+			pinned++
+		}
+		logger.Infof("Pins: %d restored, %d local ones kept", pinned, kept)
+	}
+
+	if sections[annotationReasons] {
+		restored, kept := 0, 0
+		for _, r := range a.Reasons {
+			res := sess.Collection("starred_repos").Find(db.Raw("full_name = ? COLLATE NOCASE", r.FullName))
+			var local Repository
+			if err := res.One(&local); errors.Is(err, db.ErrNoMoreRows) {
+				logger.Warnf("Not restoring the reason of %s, it's not in the database", r.FullName)
+				continue
+			} else if err != nil {
+				return err
+			}
+			updatedAt := timeOr(r.UpdatedAt, a.ExportedAt)
+			if local.Reason != "" && local.Reason != r.Reason {
+				remote, err := resolver.useRemote(annotationConflict{
+					What:     "reason of " + local.FullName,
+					Local:    local.Reason,
+					Remote:   r.Reason,
+					LocalAt:  timeOr(local.ReasonAt, time.Time{}),
+					RemoteAt: updatedAt,
+				})
+				if err != nil {
+					return err
+				}
+				if !remote {
+					kept++
+					continue
+				}
+			}
+			if err := res.Update(map[string]interface{}{"reason": r.Reason, "reason_at": updatedAt}); err != nil {
+				return err
+			}
+			restored++
+		}
+		logger.Infof("Reasons: %d restored, %d local ones kept", restored, kept)
+	}
+
+	return nil
+}
+
+// importPathBookmarks stores the path bookmarks whose URL isn't bookmarked
+// yet, and replaces the notes of the bookmarked ones the resolver picks,
+// returning the number of bookmarks added and notes replaced.
+func importPathBookmarks(sess db.Session, bookmarks []PathBookmark, resolver *conflictResolver) (int, int, error) {
+	added, replaced := 0, 0
+	err := sess.Tx(func(tx db.Session) error {
+		col := tx.Collection("path_bookmarks")
+		for _, b := range bookmarks {
+			res := col.Find(db.Cond{"url": b.URL})
+			var local PathBookmark
+			err := res.One(&local)
+			if err == nil {
+				if local.Note == b.Note || b.Note == "" {
+					continue
+				}
+				remote := local.Note == ""
+				if !remote {
+					remote, err = resolver.useRemote(annotationConflict{
+						What:     "note of the bookmark " + b.URL,
+						Local:    local.Note,
+						Remote:   b.Note,
+						LocalAt:  local.CreatedAt,
+						RemoteAt: b.CreatedAt,
+					})
+					if err != nil {
+						return err
+					}
+				}
+				if remote {
+					if err := res.Update(map[string]interface{}{"note": b.Note}); err != nil {
+						return err
+					}
+					replaced++
+				}
+				continue
+			}
+			if !errors.Is(err, db.ErrNoMoreRows) {
+				return err
+			}
+			b.ID = 0
+			if _, err := col.Insert(b); err != nil {
+				return err
+			}
+			added++
+		}
+		return nil
+	})
+
+	return added, replaced, err
+}
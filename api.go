@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/export"
+	"github.com/upper/db/v4"
+)
+
+// Pagination styles of the /api/stars endpoint.
+const (
+	paginationOffset = "offset"
+	paginationCursor = "cursor"
+)
+
+// Page sizes of the /api/stars endpoint.
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// starsPage is the position of a page of /api/stars: an offset, or the
+// cursor of the last star of the previous page.
+type starsPage struct {
+	style  string
+	limit  int
+	offset int
+	// after is the cursor, nil for the first page.
+	after *starsCursor
+}
+
+// starsCursor identifies a star in the listing order, newest first. Stars
+// starred later than the cursor don't move the following pages.
+type starsCursor struct {
+	StarredAt time.Time
+	ID        int
+}
+
+func (c starsCursor) String() string {
+	s := c.StarredAt.UTC().Format(time.RFC3339Nano) + "," + strconv.Itoa(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func parseStarsCursor(s string) (*starsCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	at, id, ok := strings.Cut(string(b), ",")
+	if !ok {
+		return nil, errors.New("invalid cursor")
+	}
+	c := &starsCursor{}
+	if c.StarredAt, err = time.Parse(time.RFC3339Nano, at); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	if c.ID, err = strconv.Atoi(id); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return c, nil
+}
+
+// parseStarsPage reads the page requested from the query: offset selects
+// offset pagination and cursor cursor pagination, the server default
+// applies when neither is given.
+func parseStarsPage(q url.Values, style string) (starsPage, error) {
+	p := starsPage{style: style, limit: defaultPageSize}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return p, fmt.Errorf("invalid limit %q", v)
+		}
+		p.limit = min(n, maxPageSize)
+	}
+
+	if q.Has("offset") && q.Has("cursor") {
+		return p, errors.New("offset and cursor can't be combined")
+	}
+	if v := q.Get("offset"); q.Has("offset") {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return p, fmt.Errorf("invalid offset %q", v)
+		}
+		p.style, p.offset = paginationOffset, n
+	}
+	if v := q.Get("cursor"); q.Has("cursor") {
+		p.style = paginationCursor
+		if v != "" {
+			c, err := parseStarsCursor(v)
+			if err != nil {
+				return p, err
+			}
+			p.after = c
+		}
+	}
+
+	return p, nil
+}
+
+// handleStars lists the public stars, newest first, a page at a time. Pages
+// are addressed by offset, with the total in X-Total-Count, or by cursor,
+// which keeps the iteration stable while new stars are stored. The next
+// pages are linked in the Link header (RFC 5988). READMEs are left out
+// unless readme=true.
+func (s *server) handleStars(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, err := parseStarsPage(q, s.pagination)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	readme, _ := strconv.ParseBool(q.Get("readme"))
+
+	res := s.sess.Collection("starred_repos").
+		Find(db.Cond{"source": sourceStarred, "unstarred_at IS": nil, "private": false}).
+		// Times are compared as such, older databases store them in other
+		// formats.
+		OrderBy(db.Raw("julianday(starred_at) DESC"), "-id")
+	if page.after != nil {
+		at := page.after.StarredAt
+		res = res.And(db.Raw("(julianday(starred_at) < julianday(?) OR (julianday(starred_at) = julianday(?) AND id < ?))", at, at, page.after.ID))
+	}
+
+	var links []string
+	if page.style == paginationOffset {
+		total, err := res.Count()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Total-Count", strconv.FormatUint(total, 10))
+
+		last := 0
+		if total > 0 {
+			last = int((total - 1) / uint64(page.limit) * uint64(page.limit))
+		}
+		links = append(links, pageLink(r, "first", "offset", "0"))
+		if page.offset > 0 {
+			links = append(links, pageLink(r, "prev", "offset", strconv.Itoa(max(page.offset-page.limit, 0))))
+		}
+		if page.offset+page.limit < int(total) {
+			links = append(links, pageLink(r, "next", "offset", strconv.Itoa(page.offset+page.limit)))
+		}
+		links = append(links, pageLink(r, "last", "offset", strconv.Itoa(last)))
+		res = res.Offset(page.offset)
+	}
+
+	// One more star tells whether there's a next page.
+	var stars []*Repository
+	if err := res.Limit(page.limit + 1).All(&stars); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	more := len(stars) > page.limit
+	if more {
+		stars = stars[:page.limit]
+	}
+	if page.style == paginationCursor {
+		links = append(links, pageLink(r, "first", "cursor", ""))
+		if more {
+			last := stars[len(stars)-1]
+			next := starsCursor{StarredAt: last.StarredAt, ID: last.ID}
+			links = append(links, pageLink(r, "next", "cursor", next.String()))
+		}
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := export.Write(w, "json", stars, export.Options{NoReadme: !readme}); err != nil {
+		logger.Errorf("writing response: %s", err)
+	}
+}
+
+// pageLink returns a Link header value pointing to the request URL with
+// the page parameter set to value, along with the page size.
+func pageLink(r *http.Request, rel, param, value string) string {
+	u, _ := url.Parse(requestURL(r))
+	q := u.Query()
+	q.Del("offset")
+	q.Del("cursor")
+	q.Set(param, value)
+	u.RawQuery = q.Encode()
+
+	return fmt.Sprintf(`<%s>; rel="%s"`, u, rel)
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/BurntSushi/toml"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/term"
+)
+
+const authUsage = `Usage: gh-stars-exporter auth <command>
+
+Commands:
+  set-token   Store the GitHub token encrypted with age or in the OS keyring
+`
+
+// keyringService is the service the GitHub token is stored under in the
+// OS keyring.
+const keyringService = "gh-stars-exporter"
+
+// Prefixes of the [auth] token references.
+const (
+	tokenRefKeyring = "keyring:"
+	tokenRefAge     = "age:"
+)
+
+// authCmd manages the stored GitHub token.
+func authCmd(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, authUsage)
+		return errUsage
+	}
+
+	switch args[0] {
+	case "set-token":
+		return authSetToken(args[1:])
+	default:
+		fmt.Fprint(os.Stderr, authUsage)
+		return errUsage
+	}
+}
+
+// authSetToken reads a token from the terminal or stdin, stores it in the
+// OS keyring or encrypted with age, and references it from the [auth]
+// section of the configuration file. age tokens are encrypted with a
+// passphrase unless --identity gives an existing identity to encrypt them
+// to: an identity kept next to the token would protect nothing.
+func authSetToken(args []string) error {
+	flags := flag.NewFlagSet("set-token", flag.ExitOnError)
+	backend := flags.String("backend", "age", "Where the token is stored: age or keyring")
+	identity := flags.String("identity", "", "Existing age identity file to encrypt the token to, instead of a passphrase. Keep it away from the token")
+	output := flags.String("output", "", "Encrypted token file (default: token.age next to the configuration file)")
+	user := flags.String("user", "github-token", "Keyring account the token is stored as")
+	flags.Parse(args)
+
+	if configFile == "" {
+		return fmt.Errorf("no configuration file to reference the token from, use --config")
+	}
+	dir := filepath.Dir(configFile)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	token, err := readToken()
+	if err != nil {
+		return err
+	}
+
+	// The paths are stored absolute, commands referencing the token run
+	// from other directories, e.g. under cron.
+	if *identity != "" {
+		if *identity, err = filepath.Abs(expandHome(*identity)); err != nil {
+			return err
+		}
+	}
+
+	auth := AuthConfig{}
+	switch *backend {
+	case "keyring":
+		if err := keyring.Set(keyringService, *user, token); err != nil {
+			return fmt.Errorf("storing the token in the keyring: %w", err)
+		}
+		auth.Token = tokenRefKeyring + *user
+		logger.Infof("Token stored in the OS keyring as %s/%s", keyringService, *user)
+	case "age":
+		if *output == "" {
+			*output = filepath.Join(dir, "token.age")
+		}
+		if *output, err = filepath.Abs(expandHome(*output)); err != nil {
+			return err
+		}
+		recipient, err := tokenRecipient(*identity)
+		if err != nil {
+			return err
+		}
+		if err := encryptToken(token, recipient, *output); err != nil {
+			return err
+		}
+		auth.Token = tokenRefAge + *output
+		auth.Identity = *identity
+		if *identity != "" {
+			logger.Infof("Token encrypted to %s for the identity in %s", *output, *identity)
+		} else {
+			logger.Infof("Token encrypted to %s with a passphrase, asked for on every run", *output)
+		}
+	default:
+		return fmt.Errorf("unknown backend %q, expected age or keyring", *backend)
+	}
+
+	// The identity of a previous token would be used to decrypt this one.
+	var unset []string
+	if auth.Identity == "" {
+		unset = append(unset, "identity")
+	}
+	if err := setConfigValues(configFile, "auth", auth, unset...); err != nil {
+		return fmt.Errorf("updating %s: %w", configFile, err)
+	}
+	logger.Infof("Updated %s, GITHUB_TOKEN is no longer needed", configFile)
+
+	return nil
+}
+
+// readToken prompts for the token without echoing it when stdin is a
+// terminal, and reads it from stdin otherwise.
+func readToken() (string, error) {
+	var b []byte
+	var err error
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "GitHub token: ")
+		b, err = term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+	} else {
+		b, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading the token: %w", err)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("empty token")
+	}
+
+	return token, nil
+}
+
+// tokenRecipient returns the recipient the token is encrypted to: the one
+// of the X25519 identity in identityPath, or a passphrase read from the
+// terminal when identityPath is empty.
+func tokenRecipient(identityPath string) (age.Recipient, error) {
+	if identityPath == "" {
+		passphrase, err := readPassphrase("Passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		confirm, err := readPassphrase("Confirm passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if passphrase != confirm {
+			return nil, fmt.Errorf("the passphrases don't match")
+		}
+		return age.NewScryptRecipient(passphrase)
+	}
+
+	identities, err := loadIdentities(identityPath)
+	if err != nil {
+		return nil, err
+	}
+	id, ok := identities[0].(*age.X25519Identity)
+	if !ok {
+		return nil, fmt.Errorf("%s: only X25519 identities are supported", identityPath)
+	}
+
+	return id.Recipient(), nil
+}
+
+// encryptToken encrypts token to recipient, writing it to output.
+func encryptToken(token string, recipient age.Recipient, output string) error {
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, token); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(output, buf.Bytes(), 0o600)
+}
+
+// readPassphrase reads the passphrase of age tokens, replaced in tests.
+var readPassphrase = terminalPassphrase
+
+// terminalPassphrase prompts for a passphrase on the terminal without
+// echoing it. The terminal is used even when stdin is piped, e.g. the
+// token.
+func terminalPassphrase(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		defer tty.Close()
+		fd = int(tty.Fd())
+	}
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("the passphrase of the token needs a terminal, use --identity or --backend keyring for unattended runs")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading the passphrase: %w", err)
+	}
+	if len(b) == 0 {
+		return "", fmt.Errorf("empty passphrase")
+	}
+
+	return string(b), nil
+}
+
+func loadIdentities(path string) ([]age.Identity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return identities, nil
+}
+
+// storedToken returns the token the [auth] section references, reading it
+// from the OS keyring or decrypting it with age, with the identity or the
+// passphrase read from the terminal.
+func storedToken(auth AuthConfig) (string, error) {
+	if user, ok := strings.CutPrefix(auth.Token, tokenRefKeyring); ok {
+		return keyring.Get(keyringService, user)
+	}
+
+	path, ok := strings.CutPrefix(auth.Token, tokenRefAge)
+	if !ok {
+		return "", fmt.Errorf("invalid token reference %q, expected keyring:USER or age:PATH", auth.Token)
+	}
+	var identities []age.Identity
+	if auth.Identity != "" {
+		var err error
+		if identities, err = loadIdentities(expandHome(auth.Identity)); err != nil {
+			return "", err
+		}
+	} else {
+		passphrase, err := readPassphrase(fmt.Sprintf("Passphrase of %s: ", path))
+		if err != nil {
+			return "", err
+		}
+		id, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return "", err
+		}
+		identities = []age.Identity{id}
+	}
+
+	f, err := os.Open(expandHome(path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	r, err := age.Decrypt(f, identities...)
+	if err != nil {
+		return "", fmt.Errorf("decrypting %s: %w", path, err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// expandHome replaces a leading ~/ with the home directory.
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, rest)
+}
+
+// setConfigValues sets the keys of a section of the TOML file at path to
+// the non-empty fields of v and removes the unset keys, keeping the rest of
+// the file, comments included, untouched. The section and the file are
+// created when missing.
+func setConfigValues(path, section string, v interface{}, unset ...string) error {
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	encoded, err := toml.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	if len(b) == 0 {
+		lines = nil
+	}
+
+	// Bounds of the section, from its header to the next one.
+	start, end := -1, len(lines)
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if start < 0 && line == "["+section+"]" {
+			start = i
+			continue
+		}
+		if start >= 0 && strings.HasPrefix(line, "[") {
+			end = i
+			break
+		}
+	}
+	if start >= 0 {
+		for i := end - 1; i > start; i-- {
+			k, _, ok := strings.Cut(lines[i], "=")
+			if ok && slices.Contains(unset, strings.TrimSpace(k)) {
+				lines = slices.Delete(lines, i, i+1)
+				end--
+			}
+		}
+	}
+	if start < 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+section+"]")
+		start, end = len(lines)-1, len(lines)
+	}
+
+	for _, kv := range strings.Split(strings.TrimSpace(string(encoded)), "\n") {
+		key, _, _ := strings.Cut(kv, " = ")
+		replaced := false
+		for i := start + 1; i < end; i++ {
+			k, _, ok := strings.Cut(lines[i], "=")
+			if ok && strings.TrimSpace(k) == key {
+				lines[i] = kv
+				replaced = true
+				break
+			}
+		}
+		if replaced {
+			continue
+		}
+		// Append after the last non-blank line of the section.
+		at := end
+		for at > start+1 && strings.TrimSpace(lines[at-1]) == "" {
+			at--
+		}
+		lines = append(lines[:at], append([]string{kv}, lines[at:]...)...)
+		end++
+	}
+
+	mode := os.FileMode(0o600)
+	if st, err := os.Stat(path); err == nil {
+		mode = st.Mode().Perm()
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), mode)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// setToken runs auth set-token with args, reading token from stdin.
+func setToken(t *testing.T, token string, args ...string) {
+	t.Helper()
+	stdin, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	if _, err := stdin.WriteString(token + "\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stdin.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = saved }()
+	if err := authSetToken(args); err != nil {
+		t.Fatalf("set-token %s: %s", strings.Join(args, " "), err)
+	}
+}
+
+func TestAuthSetTokenTwice(t *testing.T) {
+	dir := t.TempDir()
+	configFile = filepath.Join(dir, "config.toml")
+	t.Cleanup(func() { configFile = "" })
+	readPassphrase = func(string) (string, error) { return "secret", nil }
+	t.Cleanup(func() { readPassphrase = terminalPassphrase })
+
+	// Relative paths are resolved from the working directory of the run.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("key.txt", []byte(id.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	setToken(t, "ghp_first", "--identity", "key.txt", "--output", "first.age")
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "key.txt"); cfg.Auth.Identity != want {
+		t.Errorf("identity = %q, want %q", cfg.Auth.Identity, want)
+	}
+	if want := tokenRefAge + filepath.Join(dir, "first.age"); cfg.Auth.Token != want {
+		t.Errorf("token = %q, want %q", cfg.Auth.Token, want)
+	}
+	if token, err := storedToken(cfg.Auth); err != nil || token != "ghp_first" {
+		t.Errorf("stored token = %q, %v, want ghp_first", token, err)
+	}
+
+	// A passphrase replaces the identity of the previous token.
+	setToken(t, "ghp_second")
+	cfg, err = loadConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Auth.Identity != "" {
+		t.Errorf("identity = %q, want none", cfg.Auth.Identity)
+	}
+	if want := tokenRefAge + filepath.Join(dir, "token.age"); cfg.Auth.Token != want {
+		t.Errorf("token = %q, want %q", cfg.Auth.Token, want)
+	}
+	if token, err := storedToken(cfg.Auth); err != nil || token != "ghp_second" {
+		t.Errorf("stored token = %q, %v, want ghp_second", token, err)
+	}
+	b, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(b), "[auth]") != 1 {
+		t.Errorf("config has several [auth] sections:\n%s", b)
+	}
+}
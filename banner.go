@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+	"golang.org/x/term"
+)
+
+// bannerNames is the number of repositories named per banner line.
+const bannerNames = 3
+
+// showBanner reports whether the weekly banner is printed before the
+// command cmd: when enabled, to a terminal, for an existing database and
+// short-lived commands.
+func showBanner(cmd string) bool {
+	if !config.Banner.Weekly || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return false
+	}
+	switch cmd {
+	case "serve", "daemon", "auth":
+		return false
+	}
+	_, err := os.Stat(dbFile)
+
+	return err == nil
+}
+
+// weeklyBanner prints the stars added, unstarred and archived since the
+// previous banner, when it was printed in an earlier week. The first run
+// only remembers when it happened. The database is not migrated, the
+// banner waits for a command migrating it instead.
+func weeklyBanner(out io.Writer, now time.Time) error {
+	current, err := schemaCurrent()
+	if err != nil || !current {
+		return err
+	}
+	sess, err := openDB()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	last, err := store.GetState(sess, stateLastBanner)
+	if err != nil {
+		return err
+	}
+	if last != "" {
+		since, err := time.Parse(time.RFC3339, last)
+		if err != nil {
+			return fmt.Errorf("invalid %s state %q: %w", stateLastBanner, last, err)
+		}
+		if sameWeek(since, now) {
+			return nil
+		}
+		if err := writeBanner(out, sess, since); err != nil {
+			return err
+		}
+	}
+
+	return store.SetState(sess, stateLastBanner, now.UTC().Format(time.RFC3339))
+}
+
+// schemaCurrent reports whether the database has no pending migrations.
+func schemaCurrent() (bool, error) {
+	m, src, err := store.NewMigrate(dbFile)
+	if err != nil {
+		return false, err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return false, err
+	}
+	pending, err := pendingMigrations(src, version)
+	if err != nil {
+		return false, err
+	}
+
+	return !dirty && len(pending) == 0, nil
+}
+
+// sameWeek reports whether a and b fall in the same ISO week, in the
+// display time zone.
+func sameWeek(a, b time.Time) bool {
+	ay, aw := displayTime(a).ISOWeek()
+	by, bw := displayTime(b).ISOWeek()
+
+	return ay == by && aw == bw
+}
+
+func writeBanner(out io.Writer, sess db.Session, since time.Time) error {
+	lines := []struct {
+		label string
+		cond  db.Cond
+		order string
+	}{
+		{"new stars", db.Cond{"source": sourceStarred, "starred_at >": since, "unstarred_at IS": nil}, "-starred_at"},
+		{"unstarred", db.Cond{"unstarred_at >": since}, "-unstarred_at"},
+		{"newly archived", db.Cond{"archived_at >": since, "archived": true}, "-archived_at"},
+	}
+
+	var b strings.Builder
+	for _, l := range lines {
+		var repos []Repository
+		err := sess.Collection("starred_repos").Find(l.cond).Select("full_name").OrderBy(l.order).All(&repos)
+		if err != nil {
+			return err
+		}
+		if len(repos) == 0 {
+			continue
+		}
+
+		var names []string
+		for _, r := range repos[:min(bannerNames, len(repos))] {
+			names = append(names, r.FullName)
+		}
+		if len(repos) > bannerNames {
+			names = append(names, fmt.Sprintf("and %d more", len(repos)-bannerNames))
+		}
+		fmt.Fprintf(&b, "  %d %s: %s\n", len(repos), l.label, strings.Join(names, ", "))
+	}
+
+	if b.Len() == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(out, "Since %s:\n%s\n", displayTime(since).Format("Mon, 02 Jan"), b.String())
+
+	return err
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/upper/db/v4"
+)
+
+const bookmarkUsage = `Usage: gh-stars-exporter bookmark <command>
+
+Commands:
+  add [--note TEXT] URL   Bookmark a repository sub-path (e.g. golang/go/tree/master/src/net/http)
+  list [--json]           List the bookmarked paths
+  rm ID|URL               Remove a bookmark
+`
+
+// bookmarkCmd manages the path bookmarks.
+func bookmarkCmd(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, bookmarkUsage)
+		return errUsage
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	bookmarks := sess.Collection("path_bookmarks")
+	switch args[0] {
+	case "add":
+		flags := flag.NewFlagSet("bookmark add", flag.ExitOnError)
+		note := flags.String("note", "", "Note attached to the bookmark")
+		flags.Parse(args[1:])
+		if flags.NArg() != 1 {
+			return fmt.Errorf("bookmark add requires a URL")
+		}
+
+		b, err := parsePathURL(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		b.Note = *note
+		b.CreatedAt = time.Now().UTC()
+		if _, err := bookmarks.Insert(b); err != nil {
+			return err
+		}
+		logger.Infof("Bookmarked %s", b.URL)
+	case "list":
+		flags := flag.NewFlagSet("bookmark list", flag.ExitOnError)
+		asJSON := flags.Bool("json", false, "Print the bookmarks as JSON")
+		flags.Parse(args[1:])
+
+		var all []PathBookmark
+		if err := bookmarks.Find().OrderBy("full_name", "path").All(&all); err != nil {
+			return err
+		}
+		if *asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(all)
+		}
+		for _, b := range all {
+			fmt.Printf("%d\t%s\t%s\n", b.ID, b.URL, b.Note)
+		}
+	case "rm":
+		if len(args) != 2 {
+			return fmt.Errorf("bookmark rm requires a bookmark ID or URL")
+		}
+
+		cond := db.Cond{"url": args[1]}
+		if id, err := strconv.Atoi(args[1]); err == nil {
+			cond = db.Cond{"id": id}
+		} else if b, err := parsePathURL(args[1]); err == nil {
+			cond = db.Cond{"url": b.URL}
+		}
+		res := bookmarks.Find(cond)
+		if n, err := res.Count(); err != nil {
+			return err
+		} else if n == 0 {
+			return fmt.Errorf("bookmark %s not found", args[1])
+		}
+		return res.Delete()
+	default:
+		fmt.Fprint(os.Stderr, bookmarkUsage)
+		return errUsage
+	}
+
+	return nil
+}
+
+// parsePathURL parses a GitHub URL pointing to a directory or file of a
+// repository, like https://github.com/golang/go/tree/master/src/net/http.
+// The scheme and host may be omitted.
+func parsePathURL(s string) (PathBookmark, error) {
+	path := strings.TrimPrefix(strings.TrimPrefix(s, "https://"), "http://")
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "www."), "github.com/")
+	if u, err := url.Parse(path); err == nil {
+		path = u.Path
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 5 || (parts[2] != "tree" && parts[2] != "blob") {
+		return PathBookmark{}, fmt.Errorf("%s is not a repository path URL (owner/repo/tree/ref/path)", s)
+	}
+
+	b := PathBookmark{
+		FullName: parts[0] + "/" + parts[1],
+		Ref:      parts[3],
+		Path:     strings.Join(parts[4:], "/"),
+	}
+	b.URL = fmt.Sprintf("https://github.com/%s/%s/%s/%s", b.FullName, parts[2], b.Ref, b.Path)
+
+	return b, nil
+}
+
+// attachPathBookmarks sets the PathBookmarks of the repositories that have
+// any.
+func attachPathBookmarks(sess db.Session, repos []*Repository) error {
+	byRepo, err := pathBookmarksByRepo(sess)
+	if err != nil {
+		return err
+	}
+	for _, r := range repos {
+		r.PathBookmarks = byRepo[strings.ToLower(r.FullName)]
+	}
+
+	return nil
+}
+
+// pathBookmarksByRepo returns the path bookmarks keyed by the lowercased
+// full name of their repository.
+func pathBookmarksByRepo(sess db.Session) (map[string][]PathBookmark, error) {
+	var all []PathBookmark
+	if err := sess.Collection("path_bookmarks").Find().OrderBy("path").All(&all); err != nil {
+		return nil, err
+	}
+
+	byRepo := map[string][]PathBookmark{}
+	for _, b := range all {
+		key := strings.ToLower(b.FullName)
+		byRepo[key] = append(byRepo[key], b)
+	}
+
+	return byRepo, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/export"
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// changelogCmd prints a Markdown changelog of the stars added and removed
+// since the given date.
+func changelogCmd(args []string) error {
+	flags := flag.NewFlagSet("changelog", flag.ExitOnError)
+	since := flags.String("since", "last-run", "Start date (YYYY-MM-DD, RFC 3339, a duration such as 7d, or last-run)")
+	flags.Parse(args)
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	from, err := resolveSince(sess, *since, stateLastChangelog)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if err := writeChangelog(sess, os.Stdout, from); err != nil {
+		return err
+	}
+
+	return store.SetState(sess, stateLastChangelog, now.Format(time.RFC3339))
+}
+
+func writeChangelog(sess db.Session, w io.Writer, from time.Time) error {
+	from = from.UTC()
+	stars := sess.Collection("starred_repos")
+
+	var added, removed []Repository
+	err := stars.Find(db.Cond{"source": sourceStarred, "starred_at >": from, "unstarred_at IS": nil}).
+		OrderBy("-starred_at").
+		All(&added)
+	if err != nil {
+		return err
+	}
+	err = stars.Find(db.Cond{"unstarred_at >": from}).OrderBy("-unstarred_at").All(&removed)
+	if err != nil {
+		return err
+	}
+
+	if from.IsZero() {
+		fmt.Fprintf(w, "# Starring activity\n")
+	} else {
+		fmt.Fprintf(w, "# Starring activity since %s\n", displayTime(from).Format("2006-01-02"))
+	}
+
+	if len(added) == 0 && len(removed) == 0 {
+		fmt.Fprintf(w, "\nNothing new.\n")
+		return nil
+	}
+
+	if len(added) > 0 {
+		fmt.Fprintf(w, "\n## Starred (%d)\n\n", len(added))
+		for _, r := range added {
+			fmt.Fprintln(w, export.MarkdownEntry(r))
+		}
+	}
+
+	if len(removed) > 0 {
+		fmt.Fprintf(w, "\n## Unstarred (%d)\n\n", len(removed))
+		for _, r := range removed {
+			fmt.Fprintln(w, export.MarkdownEntry(r))
+		}
+	}
+
+	return nil
+}
+
+// resolveSince parses the --since flag of the commands remembering their
+// last run under key. last-run returns the time stored under key, or the zero
+// time when the command never ran.
+func resolveSince(sess db.Session, since, key string) (time.Time, error) {
+	if since != "last-run" {
+		return parseSince(since, time.Now())
+	}
+
+	last, err := store.GetState(sess, key)
+	if err != nil || last == "" {
+		return time.Time{}, err
+	}
+
+	return time.Parse(time.RFC3339, last)
+}
+
+// parseSince parses an absolute date (YYYY-MM-DD or RFC 3339) or a duration
+// relative to now, such as 36h, 7d or 2w.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+
+	d, err := parseAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+
+	return now.Add(-d), nil
+}
+
+// parseAge parses a Go duration, also accepting days (d) and weeks (w).
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+
+	return time.ParseDuration(s)
+}
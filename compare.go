@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/upper/db/v4"
+)
+
+// users is a repeatable flag.Value of GitHub logins.
+type users []string
+
+func (u *users) String() string {
+	return strings.Join(*u, ",")
+}
+
+func (u *users) Set(s string) error {
+	*u = append(*u, s)
+	return nil
+}
+
+// comparedStar is a repository starred by any of the compared users.
+type comparedStar struct {
+	FullName string
+	Language string
+	Stars    int
+}
+
+// comparison is the outcome of comparing the stars of two users.
+type comparison struct {
+	Users [2]string
+	Both  []comparedStar
+	// Only are the stars of each user missing from the other one's.
+	Only [2][]comparedStar
+}
+
+// compareCmd compares the public stars of two GitHub users, or of a user
+// and the stars in the database.
+func compareCmd(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+	var logins users
+	flags.Var(&logins, "user", "GitHub user whose public stars are compared, given twice or once to compare with the database")
+	limit := flags.Int("limit", 20, "Number of repositories listed per section, 0 lists all")
+	flags.Parse(args)
+
+	if len(logins) == 0 || len(logins) > 2 {
+		return fmt.Errorf("usage: gh-stars-exporter compare --user USER [--user USER]")
+	}
+
+	gh, err := newGitHubClient()
+	if err != nil {
+		return err
+	}
+	var sets [2][]comparedStar
+	var names [2]string
+	for i, login := range logins {
+		logger.Infof("Fetching the stars of %s from github.com...", login)
+		stars, err := gh.UserStarred(ctx, login)
+		if err != nil {
+			return fmt.Errorf("fetching the stars of %s: %w", login, err)
+		}
+		for _, sr := range stars {
+			sets[i] = append(sets[i], comparedStar{FullName: sr.Repo.FullName, Language: sr.Repo.Language, Stars: sr.Repo.StargazersCount})
+		}
+		names[i] = login
+	}
+
+	if len(logins) == 1 {
+		stored, err := storedStars()
+		if err != nil {
+			return err
+		}
+		sets[0], sets[1] = stored, sets[0]
+		names[0], names[1] = "you", logins[0]
+	}
+
+	return writeComparison(os.Stdout, compareStars(names, sets), *limit)
+}
+
+// storedStars returns the public stars in the database.
+func storedStars() ([]comparedStar, error) {
+	sess, err := dbInit()
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	var repos []Repository
+	err = sess.Collection("starred_repos").
+		Find(db.Cond{"source": sourceStarred, "unstarred_at IS": nil, "private": false}).
+		Select("full_name", "language", "stargazers_count").
+		All(&repos)
+	if err != nil {
+		return nil, err
+	}
+
+	stars := make([]comparedStar, 0, len(repos))
+	for _, r := range repos {
+		stars = append(stars, comparedStar{FullName: r.FullName, Language: r.Language, Stars: r.StargazersCount})
+	}
+
+	return stars, nil
+}
+
+// compareStars splits the stars of the two users in the ones they share and
+// the ones only each of them starred, most popular first. Repositories are
+// matched by full name, case insensitively.
+func compareStars(names [2]string, sets [2][]comparedStar) comparison {
+	c := comparison{Users: names}
+	in := [2]map[string]bool{{}, {}}
+	for i, set := range sets {
+		for _, s := range set {
+			in[i][strings.ToLower(s.FullName)] = true
+		}
+	}
+
+	for i, set := range sets {
+		other := in[1-i]
+		for _, s := range set {
+			switch {
+			case !other[strings.ToLower(s.FullName)]:
+				c.Only[i] = append(c.Only[i], s)
+			case i == 0:
+				c.Both = append(c.Both, s)
+			}
+		}
+	}
+
+	for _, list := range [][]comparedStar{c.Both, c.Only[0], c.Only[1]} {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Stars != list[j].Stars {
+				return list[i].Stars > list[j].Stars
+			}
+			return list[i].FullName < list[j].FullName
+		})
+	}
+
+	return c
+}
+
+func writeComparison(out io.Writer, c comparison, limit int) error {
+	total := len(c.Both) + len(c.Only[0]) + len(c.Only[1])
+	overlap := 0.0
+	if total > 0 {
+		overlap = float64(len(c.Both)) / float64(total) * 100
+	}
+	fmt.Fprintf(out, "%s: %d stars, %s: %d stars, %d in common (%.1f%% overlap)\n",
+		c.Users[0], len(c.Both)+len(c.Only[0]), c.Users[1], len(c.Both)+len(c.Only[1]), len(c.Both), overlap)
+
+	sections := []struct {
+		title string
+		stars []comparedStar
+	}{
+		{"Starred by both", c.Both},
+		{"Only starred by " + c.Users[0], c.Only[0]},
+		{"Only starred by " + c.Users[1], c.Only[1]},
+	}
+	for _, section := range sections {
+		fmt.Fprintf(out, "\n%s (%d)\n", section.title, len(section.stars))
+		if len(section.stars) > 0 {
+			fmt.Fprintln(out)
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		for i, s := range section.stars {
+			if i == limit {
+				fmt.Fprintf(w, "... %d more\n", len(section.stars)-limit)
+				break
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\n", s.FullName, s.Language, s.Stars)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rubiojr/gh-stars-exporter/pkg/stars"
+)
+
+// Config is the optional TOML configuration file, see --config.
+type Config struct {
+	Private   stars.PrivateConfig `toml:"private"`
+	Daemon    DaemonConfig        `toml:"daemon"`
+	Limits    stars.LimitsConfig  `toml:"limits"`
+	SQLite    SQLiteConfig        `toml:"sqlite"`
+	Topics    TopicsConfig        `toml:"topics"`
+	Translate TranslateConfig     `toml:"translate"`
+	GitHub    GitHubConfig        `toml:"github"`
+	Auth      AuthConfig          `toml:"auth"`
+	Events    EventsConfig        `toml:"events"`
+	Digest    DigestConfig        `toml:"digest"`
+	Banner    BannerConfig        `toml:"banner"`
+	Backup    BackupConfig        `toml:"backup"`
+	Push      PushConfig          `toml:"push"`
+	Grab      GrabConfig          `toml:"grab"`
+	// RateLimits throttles the requests to the services other than GitHub.
+	RateLimits RateLimitsConfig `toml:"rate_limits"`
+	// Export holds the named export profiles, used with export --profile.
+	Export map[string]ExportProfile `toml:"export"`
+	// Searches holds the saved searches, run with search --saved.
+	Searches map[string]SavedSearch `toml:"searches"`
+}
+
+// SavedSearch is a named search, e.g. [searches.weekend-projects], run with
+// search --saved NAME and served by /api/searches/NAME.
+type SavedSearch struct {
+	// Query is a full text search, empty matches every star.
+	Query string `toml:"query"`
+	// Only are owner:, topic:, language: and readme-language: filters,
+	// a star matches when any of them does.
+	Only []string `toml:"only"`
+	// MaxAge leaves out the stars not refreshed within it, e.g. 90d.
+	MaxAge string `toml:"max_age"`
+	// Limit caps the number of results, 0 lists all.
+	Limit int `toml:"limit"`
+}
+
+// ExportProfile is a named set of export options, e.g. [export.backup].
+// Options given in the command line take precedence.
+type ExportProfile struct {
+	Format   string   `toml:"format"`
+	Output   string   `toml:"output"`
+	Only     []string `toml:"only"`
+	Query    string   `toml:"query"`
+	SSH      bool     `toml:"ssh"`
+	NoReadme bool     `toml:"no_readme"`
+	// ReadmeMaxBytes truncates the exported READMEs larger than this size,
+	// e.g. 64KB.
+	ReadmeMaxBytes string `toml:"readme_max_bytes"`
+	// GroupBy groups Markdown exports by topic or language.
+	GroupBy string `toml:"group_by"`
+	// Compress compresses the export with gzip or zstd.
+	Compress string `toml:"compress"`
+	// Feed metadata of atom and rss exports.
+	FeedTitle  string `toml:"feed_title"`
+	FeedLink   string `toml:"feed_link"`
+	FeedAuthor string `toml:"feed_author"`
+	FeedItems  int    `toml:"feed_items"`
+	// ICSYearly repeats the ics events on every anniversary.
+	ICSYearly bool `toml:"ics_yearly"`
+	// CSV delimiter, line endings and BOM, see export --excel.
+	Delimiter string `toml:"delimiter"`
+	CRLF      bool   `toml:"crlf"`
+	BOM       bool   `toml:"bom"`
+	Excel     bool   `toml:"excel"`
+	// MaxOutputSize trims the export to fit in this size, e.g. 50MB.
+	MaxOutputSize string `toml:"max_output_size"`
+	// To are FORMAT=FILE files written concurrently, see export --to.
+	To []string `toml:"to"`
+	// Vault is the directory of a Markdown note per star, see export --vault.
+	Vault string `toml:"vault"`
+	// Fields restricts json, ndjson, yaml and csv exports to these
+	// fields, see export --fields.
+	Fields []string `toml:"fields"`
+	// Template is the Go template file of template exports, see export
+	// --template.
+	Template string `toml:"template"`
+}
+
+// AuthConfig references the GitHub token stored by auth set-token, used
+// when neither GITHUB_TOKEN nor GITHUB_TOKEN_FILE are set.
+type AuthConfig struct {
+	// Token is keyring:USER for a token in the OS keyring, or age:PATH for
+	// a token file encrypted with age.
+	Token string `toml:"token,omitempty"`
+	// Identity is the age identity file decrypting age:PATH tokens, which
+	// are encrypted with a passphrase when empty.
+	Identity string `toml:"identity,omitempty"`
+}
+
+// GitHubConfig configures the GitHub API client.
+type GitHubConfig struct {
+	// BaseURL is the API endpoint, e.g. https://github.example.com/api/v3
+	// for GitHub Enterprise.
+	BaseURL string `toml:"base_url"`
+	// UserAgent replaces the default User-Agent.
+	UserAgent string `toml:"user_agent"`
+	// Headers are added to every request.
+	Headers map[string]string `toml:"headers"`
+	// CacheDir caches the API responses following RFC 9111, in a directory
+	// concurrent runs can share.
+	CacheDir string `toml:"cache_dir"`
+}
+
+// TranslateConfig configures the translation of descriptions, disabled
+// unless Endpoint is set.
+type TranslateConfig struct {
+	// Endpoint is the URL of a LibreTranslate compatible /translate endpoint.
+	Endpoint string `toml:"endpoint"`
+	APIKey   string `toml:"api_key"`
+	// Target is the language descriptions are translated to.
+	Target string `toml:"target"`
+}
+
+// TopicsConfig cleans up the inconsistent topic naming of upstream
+// repositories in exports and filters. Topics are stored unchanged.
+type TopicsConfig struct {
+	// Aliases maps topics to the topic they're merged into, e.g.
+	// golang = "go".
+	Aliases map[string]string `toml:"aliases"`
+}
+
+// EventsConfig publishes the star list changes seen by syncs to an MQTT
+// broker or a NATS server, disabled unless URL is set.
+type EventsConfig struct {
+	// URL is the broker or server URL: mqtt://, mqtts://, nats:// or tls://.
+	URL string `toml:"url"`
+	// Topic is the MQTT topic or NATS subject prefix.
+	Topic string `toml:"topic"`
+}
+
+// DigestConfig configures the emails of the daemon digest jobs, disabled
+// unless To is set.
+type DigestConfig struct {
+	// SMTP is the host:port of the mail server, which is sent the digests
+	// over STARTTLS when it supports it.
+	SMTP string `toml:"smtp"`
+	// Username and Password authenticate to the mail server, when set.
+	Username string   `toml:"username"`
+	Password string   `toml:"password"`
+	From     string   `toml:"from"`
+	To       []string `toml:"to"`
+	// Limit is the number of repositories listed per digest, 20 unless set.
+	Limit int `toml:"limit"`
+}
+
+// GrabConfig configures where the grab command clones repositories and
+// what opens them.
+type GrabConfig struct {
+	// Workspace is the directory repositories are cloned into, as
+	// OWNER/NAME.
+	Workspace string `toml:"workspace"`
+	// Editor is the command opening the clones, $VISUAL, $EDITOR or VS Code
+	// unless set.
+	Editor string `toml:"editor"`
+}
+
+// PushConfig configures the services the push command sends the stars to.
+type PushConfig struct {
+	Linkding LinkdingConfig `toml:"linkding"`
+	Shaarli  ShaarliConfig  `toml:"shaarli"`
+	Wallabag WallabagConfig `toml:"wallabag"`
+	Notion   NotionConfig   `toml:"notion"`
+	Airtable AirtableConfig `toml:"airtable"`
+}
+
+// LinkdingConfig is a linkding instance, disabled unless URL is set.
+type LinkdingConfig struct {
+	// URL is the base URL of the instance.
+	URL string `toml:"url"`
+	// Token is the REST API token, from the linkding settings.
+	Token string `toml:"token"`
+}
+
+// ShaarliConfig is a Shaarli instance, disabled unless URL is set.
+type ShaarliConfig struct {
+	// URL is the base URL of the instance.
+	URL string `toml:"url"`
+	// Secret is the REST API secret, from the Shaarli configuration page.
+	Secret string `toml:"secret"`
+}
+
+// NotionConfig is the Notion database push notion keeps, disabled unless
+// Token is set.
+type NotionConfig struct {
+	// Token is the secret of the Notion integration, which needs access to
+	// the database or page.
+	Token string `toml:"token"`
+	// DatabaseID is the database the stars are pushed to, which must have
+	// the properties of the one push notion creates.
+	DatabaseID string `toml:"database_id"`
+	// PageID is the page the database is created in when DatabaseID isn't
+	// set.
+	PageID string `toml:"page_id"`
+	// BaseURL is the API base URL, https://api.notion.com/v1 unless set.
+	BaseURL string `toml:"base_url"`
+}
+
+// AirtableConfig is the Airtable table push airtable upserts the stars
+// into, disabled unless Token is set.
+type AirtableConfig struct {
+	// Token is a personal access token with the data.records:write scope
+	// on the base.
+	Token string `toml:"token"`
+	// BaseID is the ID of the base, app....
+	BaseID string `toml:"base_id"`
+	// Table is the name or ID of the table.
+	Table string `toml:"table"`
+	// BaseURL is the API base URL, https://api.airtable.com/v0 unless set.
+	BaseURL string `toml:"base_url"`
+}
+
+// WallabagConfig is a wallabag instance, disabled unless URL is set. The
+// API client is created in the wallabag developer page.
+type WallabagConfig struct {
+	// URL is the base URL of the instance.
+	URL          string `toml:"url"`
+	ClientID     string `toml:"client_id"`
+	ClientSecret string `toml:"client_secret"`
+	Username     string `toml:"username"`
+	Password     string `toml:"password"`
+}
+
+// BannerConfig configures the banner printed on the first run of the week.
+type BannerConfig struct {
+	// Weekly prints what changed since the previous banner before the
+	// output of the first command run each week. Off by default.
+	Weekly bool `toml:"weekly"`
+}
+
+// BackupConfig configures the backups of the database file taken before
+// applying pending migrations.
+type BackupConfig struct {
+	// Keep is the number of backups kept, 0 disables them.
+	Keep int `toml:"keep"`
+	// Dir is the directory backups are written to, the database one unless
+	// set.
+	Dir string `toml:"dir"`
+}
+
+// SQLiteConfig configures the database connections.
+type SQLiteConfig struct {
+	// Extensions are the paths of the SQLite extensions loaded when opening
+	// the database, e.g. sqlite-vec or regexp.
+	Extensions []string `toml:"extensions"`
+}
+
+// DaemonConfig lists the jobs run by the daemon command.
+type DaemonConfig struct {
+	Jobs []JobConfig `toml:"jobs"`
+	// ReadmeBackfillPerHour is the number of missing READMEs fetched per hour
+	// in the background, 0 disables the backfill.
+	ReadmeBackfillPerHour int `toml:"readme_backfill_per_hour"`
+}
+
+// JobConfig is a job scheduled by the daemon command.
+type JobConfig struct {
+	Name string `toml:"name"`
+	// Schedule is a cron expression, a descriptor such as @daily or
+	// "@every <duration>".
+	Schedule string `toml:"schedule"`
+	// Command is the job to run, one of sync, export, push, digest or index.
+	Command string `toml:"command"`
+	// Service is the service push jobs push the new stars to.
+	Service string `toml:"service"`
+	// Output is the file written by export jobs, and by digest jobs when
+	// set.
+	Output string `toml:"output"`
+	// Format is the export format, json unless set.
+	Format string `toml:"format"`
+	// Shard makes sync jobs sync the next shard of the star list, see
+	// sync --shard, spending up to ShardBudget rate limit points.
+	Shard        bool `toml:"shard"`
+	ShardBudget  int  `toml:"shard_budget"`
+	ShardReserve int  `toml:"shard_reserve"`
+}
+
+var config = defaultConfig()
+
+func defaultConfig() Config {
+	return Config{
+		Private: stars.PrivateConfig{
+			Readme:      true,
+			Description: true,
+			Topics:      true,
+		},
+		Limits: stars.LimitsConfig{
+			MaxReadmeBytes: 1 << 20,
+			MaxTopics:      50,
+			MaxDescription: 1024,
+		},
+		Translate: TranslateConfig{
+			Target: "en",
+		},
+		Events: EventsConfig{
+			Topic: "gh-stars",
+		},
+		Backup: BackupConfig{
+			Keep: 3,
+		},
+		Grab: GrabConfig{
+			Workspace: "~/stars",
+		},
+		RateLimits: RateLimitsConfig{
+			Default: "5/s",
+		},
+	}
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/gh-stars-exporter/config.toml or
+// its platform equivalent.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "gh-stars-exporter", "config.toml")
+}
+
+// loadConfig reads the configuration file at path. A missing file is not an
+// error, the defaults are used instead.
+func loadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	_, err := toml.DecodeFile(path, &cfg)
+	if errors.Is(err, os.ErrNotExist) {
+		return defaultConfig(), nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	logger.Debugf("Loaded configuration from %s", path)
+
+	// Topics are matched case insensitively.
+	aliases := make(map[string]string, len(cfg.Topics.Aliases))
+	for from, to := range cfg.Topics.Aliases {
+		aliases[strings.ToLower(from)] = to
+	}
+	cfg.Topics.Aliases = aliases
+
+	if err := cfg.RateLimits.validate(); err != nil {
+		return cfg, fmt.Errorf("rate_limits: %w", err)
+	}
+
+	return cfg, nil
+}
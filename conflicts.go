@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Rules resolving the conflicts between the imported annotations and the
+// local ones.
+const (
+	preferNewest = "newest"
+	preferLocal  = "local"
+	preferRemote = "remote"
+)
+
+// annotationConflict is an annotation with different local and imported
+// values.
+type annotationConflict struct {
+	// What names the annotation, e.g. "reason of golang/go".
+	What          string
+	Local, Remote string
+	// LocalAt and RemoteAt are when the values were set, zero if unknown.
+	LocalAt, RemoteAt time.Time
+}
+
+// conflictResolver decides whether the imported annotations replace the
+// conflicting local ones, by rule or asking.
+type conflictResolver struct {
+	prefer string
+	// in reads the answers to the questions, nil unless interactive.
+	in *bufio.Reader
+	// all is the answer given for all the remaining conflicts, l or r.
+	all string
+}
+
+// newConflictResolver returns a resolver applying the prefer rule, asking
+// first on the terminal when interactive.
+func newConflictResolver(prefer string, interactive bool) (*conflictResolver, error) {
+	switch prefer {
+	case preferNewest, preferLocal, preferRemote:
+	default:
+		return nil, fmt.Errorf("unknown --prefer %q, expected newest, local or remote", prefer)
+	}
+	c := &conflictResolver{prefer: prefer}
+	if interactive {
+		if stat, err := os.Stdin.Stat(); err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+			return nil, fmt.Errorf("--interactive requires a terminal, use --prefer otherwise")
+		}
+		c.in = bufio.NewReader(os.Stdin)
+	}
+
+	return c, nil
+}
+
+// useRemote reports whether the imported value of the conflicting
+// annotation replaces the local one, logging the outcome.
+func (c *conflictResolver) useRemote(cf annotationConflict) (bool, error) {
+	remote := c.rule(cf)
+	if c.in != nil {
+		var err error
+		if remote, err = c.ask(cf, remote); err != nil {
+			return false, err
+		}
+	}
+
+	if remote {
+		logger.Infof("Replaced the local %s", cf.What)
+	} else {
+		logger.Infof("Kept the local %s", cf.What)
+	}
+	return remote, nil
+}
+
+// rule reports whether the prefer rule picks the imported value. Newest
+// picks the local one on ties, and when neither time is known.
+func (c *conflictResolver) rule(cf annotationConflict) bool {
+	switch c.prefer {
+	case preferLocal:
+		return false
+	case preferRemote:
+		return true
+	}
+	return cf.RemoteAt.After(cf.LocalAt)
+}
+
+// ask asks which value is kept, the one picked by the rule by default.
+func (c *conflictResolver) ask(cf annotationConflict, remote bool) (bool, error) {
+	if c.all != "" {
+		return c.all == "r", nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Conflicting %s:\n", cf.What)
+	fmt.Fprintf(os.Stderr, "  local  %s: %s\n", conflictTime(cf.LocalAt), cf.Local)
+	fmt.Fprintf(os.Stderr, "  remote %s: %s\n", conflictTime(cf.RemoteAt), cf.Remote)
+	def := "l"
+	if remote {
+		def = "r"
+	}
+	for {
+		fmt.Fprintf(os.Stderr, "Keep [l]ocal or use [r]emote, L or R for all the remaining conflicts [%s]: ", def)
+		answer, err := c.in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			answer = def
+		}
+
+		switch answer {
+		case "l", "r":
+			return answer == "r", nil
+		case "L", "R":
+			c.all = strings.ToLower(answer)
+			return c.all == "r", nil
+		}
+		if err == io.EOF {
+			return remote, nil
+		}
+	}
+}
+
+func conflictTime(t time.Time) string {
+	if t.IsZero() {
+		return "(unknown time)"
+	}
+	return "(" + displayTime(t).Format("2006-01-02 15:04") + ")"
+}
+
+// timeOr returns t, or def when nil.
+func timeOr(t *time.Time, def time.Time) time.Time {
+	if t == nil {
+		return def
+	}
+	return *t
+}
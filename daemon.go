@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/export"
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+	"github.com/upper/db/v4"
+)
+
+// daemonJob is a job from the configuration file along with its parsed
+// schedule.
+type daemonJob struct {
+	JobConfig
+	schedule schedule
+	next     time.Time
+}
+
+// daemonCmd runs the jobs configured in the [daemon] section of the
+// configuration file until ctx is cancelled. Jobs run one at a time, with
+// missing READMEs backfilled in the background at the configured pace.
+func daemonCmd(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("daemon", flag.ExitOnError)
+	addr := flags.String("addr", "", "Address serving the /healthz and /readyz probes (disabled when empty)")
+	syncSchedule := flags.String("sync-schedule", "", "Schedule of a sync job added to the configured ones")
+	flags.Parse(args)
+	if err := flagsFromEnv(flags); err != nil {
+		return err
+	}
+
+	jobConfigs := config.Daemon.Jobs
+	if *syncSchedule != "" {
+		jobConfigs = append(jobConfigs, JobConfig{Name: "sync", Schedule: *syncSchedule, Command: "sync"})
+	}
+	jobs, err := daemonJobs(jobConfigs)
+	if err != nil {
+		return err
+	}
+	backfill := config.Daemon.ReadmeBackfillPerHour
+	if len(jobs) == 0 && backfill <= 0 {
+		return fmt.Errorf("no jobs configured, add them to the [daemon] section of %s", configFile)
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	if *addr != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /healthz", handleHealthz)
+		mux.Handle("GET /readyz", readyzHandler(sess))
+		go func() {
+			if err := listenAndServe(ctx, *addr, mux); err != nil {
+				logger.Errorf("Probes endpoint: %s", err)
+			}
+		}()
+	}
+
+	// Serializes database writes between jobs and the README backfill.
+	var mu sync.Mutex
+	if backfill > 0 {
+		logger.Infof("Backfilling up to %d missing READMEs per hour", backfill)
+		gh, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		go backfillReadmes(ctx, sess, gh, backfill, &mu)
+	}
+
+	now := time.Now()
+	for _, job := range jobs {
+		job.next = job.schedule.Next(now)
+		logger.Infof("Job %s (%s) scheduled, next run at %s", job.Name, job.Command, job.next.Format(time.RFC3339))
+	}
+
+	for {
+		job := nextJob(jobs)
+		if job == nil {
+			if backfill > 0 {
+				<-ctx.Done()
+				logger.Info("Daemon stopped")
+				return nil
+			}
+			return fmt.Errorf("no job will ever run again")
+		}
+
+		timer := time.NewTimer(time.Until(job.next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logger.Info("Daemon stopped")
+			return nil
+		case <-timer.C:
+		}
+
+		logger.Infof("Running job %s", job.Name)
+		started := time.Now()
+		mu.Lock()
+		err := runJob(ctx, sess, job.JobConfig)
+		mu.Unlock()
+		if err != nil {
+			logger.Errorf("Job %s failed: %s", job.Name, err)
+		} else {
+			logger.Infof("Job %s finished in %s", job.Name, time.Since(started).Round(time.Millisecond))
+		}
+		job.next = job.schedule.Next(time.Now())
+	}
+}
+
+func daemonJobs(configs []JobConfig) ([]*daemonJob, error) {
+	var jobs []*daemonJob
+	for i, cfg := range configs {
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("%s-%d", cfg.Command, i+1)
+		}
+
+		sched, err := parseSchedule(cfg.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", cfg.Name, err)
+		}
+
+		switch cfg.Command {
+		case "sync", "index":
+		case "push":
+			if _, ok := pushers[cfg.Service]; !ok {
+				return nil, fmt.Errorf("job %s: unknown push service %q", cfg.Name, cfg.Service)
+			}
+		case "export":
+			if cfg.Output == "" {
+				return nil, fmt.Errorf("job %s: export jobs require an output file", cfg.Name)
+			}
+			if cfg.Format == "" {
+				cfg.Format = "json"
+			}
+			if !export.HasFormat(cfg.Format) {
+				return nil, fmt.Errorf("job %s: unknown export format %q", cfg.Name, cfg.Format)
+			}
+		case "digest":
+			if cfg.Output == "" && len(config.Digest.To) == 0 {
+				return nil, fmt.Errorf("job %s: digest jobs require an output file or [digest] recipients", cfg.Name)
+			}
+			if len(config.Digest.To) > 0 && (config.Digest.SMTP == "" || config.Digest.From == "") {
+				return nil, fmt.Errorf("job %s: emailing digests requires the [digest] smtp server and from address", cfg.Name)
+			}
+		default:
+			return nil, fmt.Errorf("job %s: unknown command %q", cfg.Name, cfg.Command)
+		}
+
+		jobs = append(jobs, &daemonJob{JobConfig: cfg, schedule: sched})
+	}
+
+	return jobs, nil
+}
+
+// nextJob returns the job due the soonest, nil if none will run again.
+func nextJob(jobs []*daemonJob) *daemonJob {
+	var next *daemonJob
+	for _, job := range jobs {
+		if job.next.IsZero() {
+			continue
+		}
+		if next == nil || job.next.Before(next.next) {
+			next = job
+		}
+	}
+
+	return next
+}
+
+func runJob(ctx context.Context, sess db.Session, job JobConfig) error {
+	switch job.Command {
+	case "sync":
+		initialized, err := hasStars(sess)
+		if err != nil {
+			return err
+		}
+		gh, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		syncer := newSyncer(gh, sess)
+		started := time.Now()
+		if job.Shard {
+			budget, reserve := job.ShardBudget, job.ShardReserve
+			if budget == 0 {
+				budget = defaultShardBudget
+			}
+			if reserve == 0 {
+				reserve = defaultShardReserve
+			}
+			_, err = syncShard(ctx, syncer, budget, reserve)
+		} else {
+			err = syncer.Sync(ctx)
+		}
+		if rerr := syncer.SaveReport(started, err); rerr != nil {
+			logger.Warnf("Saving the error report: %s", rerr)
+		}
+		if err != nil {
+			return err
+		}
+		stats := syncer.Stats()
+		logger.Infof("New stars: %d", stats.NewStars)
+		logger.Infof("Updated stars: %d", stats.UpdatedStars)
+		logger.Infof("Unstarred: %d", stats.Unstarred)
+		if initialized {
+			if err := publishEvents(stats.Events); err != nil {
+				logger.Warnf("Publishing events: %s", err)
+			}
+		}
+		if getFollowing {
+			return syncFollowing(ctx, gh, sess)
+		}
+		return nil
+	case "export":
+		return exportToFile(sess, job.Output, job.Format, exportOptions{})
+	case "push":
+		return pushers[job.Service](ctx, sess, pushOptions{Since: "last-run"})
+	case "digest":
+		return runDigest(sess, job.Output, time.Now())
+	case "index":
+		return rebuildIndex(sess)
+	}
+
+	return fmt.Errorf("unknown command %q", job.Command)
+}
+
+// readmeRetryAfter is how long the backfill waits before retrying a
+// repository whose README couldn't be fetched.
+const readmeRetryAfter = 7 * 24 * time.Hour
+
+// backfillReadmes fetches a missing README every hour/perHour until ctx is
+// cancelled, most recently starred repositories first.
+func backfillReadmes(ctx context.Context, sess db.Session, gh githubclient.Client, perHour int, mu *sync.Mutex) {
+	ticker := time.NewTicker(time.Hour / time.Duration(perHour))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		mu.Lock()
+		err := backfillNextReadme(ctx, sess, gh)
+		mu.Unlock()
+		if err != nil && !errors.Is(err, db.ErrNoMoreRows) {
+			logger.Errorf("Backfilling README: %s", err)
+		}
+	}
+}
+
+func backfillNextReadme(ctx context.Context, sess db.Session, gh githubclient.Client) error {
+	cond := db.And(
+		db.Cond{"readme IS": nil, "unstarred_at IS": nil},
+		db.Or(
+			db.Cond{"readme_fetched_at IS": nil},
+			db.Cond{"readme_fetched_at <": time.Now().UTC().Add(-readmeRetryAfter)},
+		),
+	)
+	// Hashed private repository names can't be resolved upstream.
+	if !config.Private.Readme || config.Private.HashNames {
+		cond = cond.And(db.Cond{"private": false})
+	}
+
+	res := sess.Collection("starred_repos").Find(cond)
+	var r Repository
+	if err := res.OrderBy("-starred_at").One(&r); err != nil {
+		return err
+	}
+
+	syncer := newSyncer(gh, sess)
+	if syncer.FetchMissingReadme(ctx, r.FullName, &r) {
+		logger.Infof("Backfilled README for %s", r.FullName)
+	}
+	for _, err := range syncer.Stats().Errors {
+		logger.Warn(err)
+	}
+
+	return sess.Collection("starred_repos").Find(r.ID).Update(r)
+}
+
+// exportToFile writes the export in the given format to path, replacing it
+// atomically. Paths ending in .gz or .zst are compressed unless
+// opts.Compress says otherwise.
+func exportToFile(sess db.Session, path, format string, opts exportOptions) error {
+	opts.Compress = compressionFor(path, opts.Compress)
+	f, err := os.CreateTemp(filepath.Dir(path), ".ghstars-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := exportStars(sess, f, format, opts); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
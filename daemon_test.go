@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+func TestDaemonJobs(t *testing.T) {
+	tests := []struct {
+		name string
+		job  JobConfig
+		err  string
+	}{
+		{name: "sync", job: JobConfig{Command: "sync", Schedule: "@hourly"}},
+		{name: "index", job: JobConfig{Command: "index", Schedule: "@daily"}},
+		{name: "export", job: JobConfig{Command: "export", Schedule: "@daily", Output: "stars.json"}},
+		{name: "push", job: JobConfig{Command: "push", Schedule: "@daily", Service: "linkding"}},
+		{name: "digest to a file", job: JobConfig{Command: "digest", Schedule: "@weekly", Output: "digest.md"}},
+		{name: "export without output", job: JobConfig{Command: "export", Schedule: "@daily"}, err: "require an output file"},
+		{name: "unknown export format", job: JobConfig{Command: "export", Schedule: "@daily", Output: "x", Format: "doc"}, err: "unknown export format"},
+		{name: "unknown push service", job: JobConfig{Command: "push", Schedule: "@daily", Service: "nope"}, err: "unknown push service"},
+		{name: "digest without output", job: JobConfig{Command: "digest", Schedule: "@weekly"}, err: "require an output file or [digest] recipients"},
+		{name: "unknown command", job: JobConfig{Command: "reindex", Schedule: "@daily"}, err: `unknown command "reindex"`},
+		{name: "invalid schedule", job: JobConfig{Command: "index", Schedule: "daily"}, err: "job index-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, err := daemonJobs([]JobConfig{tt.job})
+			if tt.err == "" {
+				if err != nil || len(jobs) != 1 {
+					t.Fatalf("daemonJobs(%+v) = %v, %v, want a job", tt.job, jobs, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("daemonJobs(%+v) error = %v, want %q", tt.job, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRunIndexJob(t *testing.T) {
+	sess, err := store.Open(filepath.Join(t.TempDir(), "stars.db"), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+
+	if err := runJob(context.Background(), sess, JobConfig{Name: "index", Command: "index"}); err != nil {
+		t.Errorf("index job: %s", err)
+	}
+}
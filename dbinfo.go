@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+const dbUsage = `Usage: gh-stars-exporter db <command>
+
+Commands:
+  info                 Print schema version, row counts, size, indexes and pending migrations
+  export-annotations   Export the pins, bookmarks and reasons, the data syncs can't rebuild
+  import-annotations   Restore the pins, bookmarks and reasons written by export-annotations
+  migrate-to           Copy every table into a Postgres database, postgres://...
+  export-dogsheep      Write the stars to a github-to-sqlite database, to explore with Datasette
+`
+
+// dbCmd groups the database maintenance commands.
+func dbCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, dbUsage)
+		return errUsage
+	}
+
+	switch args[0] {
+	case "info":
+		return dbInfo(os.Stdout)
+	case "export-annotations":
+		return exportAnnotationsCmd(args[1:])
+	case "import-annotations":
+		return importAnnotationsCmd(ctx, args[1:])
+	case "migrate-to":
+		return dbMigrateTo(ctx, args[1:])
+	case "export-dogsheep":
+		return dbExportDogsheep(ctx, args[1:])
+	default:
+		fmt.Fprint(os.Stderr, dbUsage)
+		return errUsage
+	}
+}
+
+// dbInfo prints a health overview of the database. The database is not
+// migrated, so pending migrations can be reported.
+func dbInfo(out io.Writer) error {
+	stat, err := os.Stat(dbFile)
+	if err != nil {
+		return err
+	}
+
+	m, src, err := store.NewMigrate(dbFile)
+	if err != nil {
+		return err
+	}
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		m.Close()
+		return err
+	}
+	pending, err := pendingMigrations(src, version)
+	m.Close()
+	if err != nil {
+		return err
+	}
+
+	sess, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Database:\t%s\n", dbFile)
+	fmt.Fprintf(w, "Size:\t%s\n", humanBytes(stat.Size()))
+	fmt.Fprintf(w, "Schema version:\t%d", version)
+	if dirty {
+		fmt.Fprint(w, " (dirty, a migration failed)")
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Pending migrations:\t%d\n", len(pending))
+	for _, v := range pending {
+		fmt.Fprintf(w, "\t%d\n", v)
+	}
+
+	fmt.Fprintln(w, "\nTable\tRows")
+	tables, err := sqliteObjects(sess, "table")
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		row, err := sess.SQL().QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, t.Name))
+		if err != nil {
+			return err
+		}
+		var count int
+		if err := row.Scan(&count); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%d\n", t.Name, count)
+	}
+
+	fmt.Fprintln(w, "\nIndex\tTable")
+	indexes, err := sqliteObjects(sess, "index")
+	if err != nil {
+		return err
+	}
+	for _, idx := range indexes {
+		fmt.Fprintf(w, "%s\t%s\n", idx.Name, idx.Table)
+	}
+
+	if err := printLargestReadmes(sess, w); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+type sqliteObject struct {
+	Name  string `db:"name"`
+	Table string `db:"tbl_name"`
+}
+
+func sqliteObjects(sess db.Session, kind string) ([]sqliteObject, error) {
+	var objects []sqliteObject
+	err := sess.SQL().
+		Select("name", "tbl_name").
+		From("sqlite_master").
+		Where("type = ? AND name NOT LIKE 'sqlite_%'", kind).
+		OrderBy("name").
+		All(&objects)
+
+	return objects, err
+}
+
+func printLargestReadmes(sess db.Session, w io.Writer) error {
+	var largest []struct {
+		FullName string `db:"full_name"`
+		Size     int64  `db:"size"`
+	}
+	err := sess.SQL().
+		Select("full_name", db.Raw("LENGTH(CAST(readme AS BLOB)) AS size")).
+		From("starred_repos").
+		Where("readme IS NOT NULL").
+		OrderBy("-size").
+		Limit(5).
+		All(&largest)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\nLargest READMEs\tSize")
+	for _, r := range largest {
+		fmt.Fprintf(w, "%s\t%s\n", r.FullName, humanBytes(r.Size))
+	}
+
+	return nil
+}
+
+// pendingMigrations returns the versions of the migrations not applied yet.
+func pendingMigrations(src source.Driver, current uint) ([]uint, error) {
+	var pending []uint
+	v, err := src.First()
+	for err == nil {
+		if v > current {
+			pending = append(pending, v)
+		}
+		v, err = src.Next(v)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// humanBytes formats a byte count using binary units.
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// defaultDigestLimit is the number of repositories listed per digest unless
+// [digest] limit says otherwise.
+const defaultDigestLimit = 20
+
+// runDigest writes the radar of the repositories pushed to since the
+// previous digest to output, when set, and emails it to the [digest]
+// recipients. The first digest covers the last week.
+func runDigest(sess db.Session, output string, now time.Time) error {
+	from, err := resolveSince(sess, "last-run", stateLastDigest)
+	if err != nil {
+		return err
+	}
+	if from.IsZero() {
+		from = now.Add(-radarFirstRun)
+	}
+	limit := config.Digest.Limit
+	if limit == 0 {
+		limit = defaultDigestLimit
+	}
+
+	var body bytes.Buffer
+	if err := writeRadar(sess, &body, from, limit); err != nil {
+		return err
+	}
+
+	if output != "" {
+		if err := writeDigest(output, body.Bytes()); err != nil {
+			return fmt.Errorf("writing the digest: %w", err)
+		}
+		logger.Infof("Wrote the digest to %s", output)
+	}
+	if len(config.Digest.To) > 0 {
+		subject := fmt.Sprintf("Starred repositories pushed to since %s", displayTime(from).Format("2006-01-02"))
+		if err := sendDigest(config.Digest, subject, body.Bytes(), now); err != nil {
+			return fmt.Errorf("emailing the digest: %w", err)
+		}
+		logger.Infof("Emailed the digest to %s", strings.Join(config.Digest.To, ", "))
+	}
+
+	return store.SetState(sess, stateLastDigest, now.UTC().Format(time.RFC3339))
+}
+
+// writeDigest replaces path with the digest atomically, like exports.
+func writeDigest(path string, body []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".ghstars-digest-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// sendDigest emails the Markdown digest body as plain text.
+func sendDigest(cfg DigestConfig, subject string, body []byte, now time.Time) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/markdown; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.Write(bytes.ReplaceAll(body, []byte("\n"), []byte("\r\n")))
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		host, _, err := net.SplitHostPort(cfg.SMTP)
+		if err != nil {
+			return fmt.Errorf("invalid smtp server %q: %w", cfg.SMTP, err)
+		}
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+
+	return smtp.SendMail(cfg.SMTP, auth, cfg.From, cfg.To, msg.Bytes())
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// dbExportDogsheep writes the stars to a database in the schema of
+// dogsheep's github-to-sqlite, to be explored with Datasette.
+func dbExportDogsheep(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export-dogsheep", flag.ExitOnError)
+	output := flags.String("output", "github.db", "Database written, replaced if it exists")
+	login := flags.String("user", "", "Login of the user the stars belong to, the authenticated user when not set")
+	userID := flags.Int("user-id", 0, "GitHub ID of --user, looked up with the API when not set")
+	flags.Parse(args)
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	user := store.DogsheepUser{ID: *userID, Login: *login}
+	if user.ID == 0 || user.Login == "" {
+		if *login != "" {
+			return fmt.Errorf("--user needs --user-id")
+		}
+		gh, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		authenticated, err := gh.User(ctx)
+		if err != nil {
+			return err
+		}
+		user = store.DogsheepUser{ID: authenticated.ID, Login: authenticated.Login, HTMLURL: authenticated.HTMLURL}
+	}
+	if user.HTMLURL == "" {
+		user.HTMLURL = "https://github.com/" + user.Login
+	}
+
+	// Written next to the output and renamed, so a failed export doesn't
+	// leave a partial database behind.
+	f, err := os.CreateTemp(filepath.Dir(*output), ".ghstars-dogsheep-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	n, err := store.WriteDogsheep(sess, f.Name(), user)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), *output); err != nil {
+		return err
+	}
+	logger.Infof("Wrote %d stars of %s to %s", n, user.Login, *output)
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// dumpCmd writes the database as SQL statements, to load it into another
+// SQLite or Postgres database without copying the database file.
+func dumpCmd(args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	dialect := flags.String("dialect", store.DialectSQLite, "SQL dialect: sqlite or postgres")
+	dataOnly := flags.Bool("data-only", false, "Write the INSERT statements only, leaving the schema out")
+	output := flags.String("output", "", "Write the dump to a file instead of stdout")
+	flags.Parse(args)
+
+	if *dialect != store.DialectSQLite && *dialect != store.DialectPostgres {
+		return fmt.Errorf("unknown dialect %q, expected sqlite or postgres", *dialect)
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	n, err := store.Dump(out, sess, store.DumpOptions{Dialect: *dialect, DataOnly: *dataOnly})
+	if err != nil {
+		return err
+	}
+	logger.Infof("Dumped %d rows", n)
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix is prepended to the upper cased flag names, with dashes replaced
+// by underscores, to get the environment variables setting them.
+const envPrefix = "GHSTARS_"
+
+// flagsFromEnv sets the flags not given in the command line from their
+// environment variables, e.g. --db from GHSTARS_DB, so containers can be
+// configured with environment variables only.
+func flagsFromEnv(flags *flag.FlagSet) error {
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] || err != nil {
+			return
+		}
+
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if v, ok := os.LookupEnv(name); ok {
+			if e := flags.Set(f.Name, v); e != nil {
+				err = fmt.Errorf("%s: %w", name, e)
+			}
+		}
+	})
+
+	return err
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/upper/db/v4"
+)
+
+// defaultEras splits the repository creation years in eras: before 2015,
+// 2015-2019, 2020-2024 and 2025 onwards.
+const defaultEras = "2015,2020,2025"
+
+// eraReport counts the starred repositories per year starred and era the
+// repositories were created in.
+type eraReport struct {
+	Eras []string `json:"eras"`
+	Rows []eraRow `json:"rows"`
+}
+
+type eraRow struct {
+	StarredYear int `json:"starred_year"`
+	// Counts are the stars of each era, in the order of eraReport.Eras.
+	Counts []int `json:"counts"`
+	Total  int   `json:"total"`
+}
+
+// reportEras buckets the starred repositories by the era they were created
+// in, crossed with the year they were starred.
+func reportEras(args []string) error {
+	flags := flag.NewFlagSet("eras", flag.ExitOnError)
+	format := flags.String("format", "json", "Output format: json or csv")
+	edges := flags.String("eras", defaultEras, "Comma separated years each era starts at")
+	flags.Parse(args)
+
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown format %q, expected json or csv", *format)
+	}
+	years, err := parseEraEdges(*edges)
+	if err != nil {
+		return err
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	var stars []Repository
+	err = sess.Collection("starred_repos").
+		Find(db.Cond{"source": sourceStarred, "unstarred_at IS": nil}).
+		Select("created_at", "starred_at").
+		All(&stars)
+	if err != nil {
+		return err
+	}
+
+	report := buildEraReport(stars, years)
+	if *format == "csv" {
+		return writeErasCSV(os.Stdout, report)
+	}
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(string(b))
+	return err
+}
+
+// parseEraEdges parses a comma separated list of increasing years.
+func parseEraEdges(s string) ([]int, error) {
+	var years []int
+	for _, v := range strings.Split(s, ",") {
+		year, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid era year %q", v)
+		}
+		if len(years) > 0 && year <= years[len(years)-1] {
+			return nil, fmt.Errorf("era years must be increasing, got %s", s)
+		}
+		years = append(years, year)
+	}
+
+	return years, nil
+}
+
+// eraNames returns the names of the eras delimited by years, e.g. pre-2015,
+// 2015-2019 and 2020+.
+func eraNames(years []int) []string {
+	names := []string{fmt.Sprintf("pre-%d", years[0])}
+	for i := 1; i < len(years); i++ {
+		names = append(names, fmt.Sprintf("%d-%d", years[i-1], years[i]-1))
+	}
+
+	return append(names, fmt.Sprintf("%d+", years[len(years)-1]))
+}
+
+func buildEraReport(stars []Repository, years []int) *eraReport {
+	report := &eraReport{Eras: eraNames(years), Rows: []eraRow{}}
+
+	rows := map[int]*eraRow{}
+	for _, r := range stars {
+		starred := displayTime(r.StarredAt).Year()
+		row, ok := rows[starred]
+		if !ok {
+			row = &eraRow{StarredYear: starred, Counts: make([]int, len(report.Eras))}
+			rows[starred] = row
+		}
+		// Index of the first era starting after the creation year.
+		era := sort.SearchInts(years, displayTime(r.CreatedAt).Year()+1)
+		row.Counts[era]++
+		row.Total++
+	}
+
+	for _, row := range rows {
+		report.Rows = append(report.Rows, *row)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		return report.Rows[i].StarredYear < report.Rows[j].StarredYear
+	})
+
+	return report
+}
+
+func writeErasCSV(out io.Writer, report *eraReport) error {
+	w := csv.NewWriter(out)
+	header := append([]string{"starred_year"}, report.Eras...)
+	if err := w.Write(append(header, "total")); err != nil {
+		return err
+	}
+
+	for _, row := range report.Rows {
+		record := []string{strconv.Itoa(row.StarredYear)}
+		for _, n := range row.Counts {
+			record = append(record, strconv.Itoa(n))
+		}
+		if err := w.Write(append(record, strconv.Itoa(row.Total))); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/events"
+	"github.com/rubiojr/gh-stars-exporter/pkg/stars"
+	"github.com/upper/db/v4"
+)
+
+// eventsTimeout bounds connecting to the events server and every publish.
+const eventsTimeout = 10 * time.Second
+
+// eventMessage is the JSON message published for a star list change, to
+// the star or unstar topic.
+type eventMessage struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Repo eventRepo `json:"repo"`
+}
+
+type eventRepo struct {
+	FullName        string    `json:"full_name"`
+	HTMLURL         string    `json:"html_url"`
+	Description     string    `json:"description"`
+	Language        string    `json:"language"`
+	Topics          []string  `json:"topics"`
+	StargazersCount int       `json:"stargazers_count"`
+	StarredAt       time.Time `json:"starred_at"`
+}
+
+// hasStars reports whether the database holds stars already. The first
+// sync sees every star as new, its events aren't published.
+func hasStars(sess db.Session) (bool, error) {
+	return sess.Collection("starred_repos").Find(db.Cond{"source": sourceStarred}).Exists()
+}
+
+// publishEvents publishes the star list changes of a sync to the [events]
+// server, when configured. Private repositories are never published.
+func publishEvents(evs []stars.Event) error {
+	cfg := config.Events
+	if cfg.URL == "" || len(evs) == 0 {
+		return nil
+	}
+
+	pub, err := events.Dial(cfg.URL, cfg.Topic, "gh-stars-exporter", eventsTimeout)
+	if err != nil {
+		return err
+	}
+
+	published := 0
+	for _, ev := range evs {
+		if ev.Repo.Private {
+			continue
+		}
+		if err := publishEvent(pub, ev); err != nil {
+			pub.Close()
+			return fmt.Errorf("publishing %s event for %s: %w", ev.Type, ev.Repo.FullName, err)
+		}
+		published++
+	}
+	logger.Infof("Published %d star events", published)
+
+	return pub.Close()
+}
+
+func publishEvent(pub events.Publisher, ev stars.Event) error {
+	r := ev.Repo
+	topics := []string{}
+	for _, t := range normalizeTopics(r.Topics, config.Topics.Aliases) {
+		if t != "" {
+			topics = append(topics, t)
+		}
+	}
+	b, err := json.Marshal(eventMessage{
+		Type: ev.Type,
+		Time: ev.Time,
+		Repo: eventRepo{
+			FullName:        r.FullName,
+			HTMLURL:         r.HTMLURL,
+			Description:     r.Description,
+			Language:        r.Language,
+			Topics:          topics,
+			StargazersCount: r.StargazersCount,
+			StarredAt:       r.StarredAt.UTC(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return pub.Publish(ev.Type, b)
+}
@@ -0,0 +1,489 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/export"
+	"github.com/upper/db/v4"
+)
+
+// exportOptions selects the exported stars and how they're written.
+type exportOptions struct {
+	export.Options
+	// Only restricts the export to the stars matching the filters.
+	Only repoFilters
+	// Query restricts the export to the stars matching a full text search.
+	Query string
+	// Limit caps the number of exported stars, 0 exports all of them.
+	Limit int
+	// MaxSize is the size budget of the export in bytes, trimming it to fit
+	// when larger. 0 means no budget.
+	MaxSize int64
+	// MaxAge leaves out the stars not refreshed within it, 0 keeps them
+	// all.
+	MaxAge time.Duration
+	// Public leaves out the private stars, the unstarred ones and the
+	// bookmarks.
+	Public bool
+}
+
+// cond is the SQL condition selecting the stars matching the filters of
+// opts, the ones of filteredStars.
+func (opts exportOptions) cond() db.LogicalExpr {
+	conds := []db.LogicalExpr{opts.Only.cond()}
+	if opts.MaxAge > 0 {
+		// Stars never refreshed have no refresh time, and are left out.
+		conds = append(conds, db.Raw("julianday(refreshed_at) >= julianday(?)", time.Now().UTC().Add(-opts.MaxAge)))
+	}
+	if opts.Public {
+		conds = append(conds, db.Cond{"source": sourceStarred, "unstarred_at IS": nil, "private": false})
+	}
+
+	return db.And(conds...)
+}
+
+func exportFormats() string {
+	return strings.Join(export.Formats(), ", ")
+}
+
+// exportCmd exports the stars stored in the database, without syncing.
+func exportCmd(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	profile := flags.String("profile", "", "Use the options of an [export.NAME] configuration profile, flags take precedence")
+	format := flags.String("format", "json", "Export format: "+exportFormats())
+	output := flags.String("output", "", "Write the export to a file instead of stdout, replacing it atomically")
+	vault := flags.String("vault", "", "Write a Markdown note per star to this directory, an Obsidian or Logseq vault")
+	var targets exportTargets
+	flags.Var(&targets, "to", "Write the export in FORMAT to FILE, FORMAT=FILE, concurrently with the other --to files (repeatable)")
+	var opts exportOptions
+	flags.Var(&opts.Only, "only", "Only export stars matching owner:NAME, topic:NAME, language:NAME or readme-language:CODE (repeatable)")
+	flags.BoolVar(&opts.SSH, "ssh", false, "Use SSH clone URLs (repos.txt and clone-script)")
+	flags.StringVar(&opts.Query, "query", "", "Only export stars matching a full text search")
+	flags.BoolVar(&opts.NoReadme, "no-readme", false, "Leave READMEs out of the export")
+	flags.Var(&readmeFlag{noReadme: &opts.NoReadme, with: true}, "with-readme", "Include the stored READMEs in the export")
+	flags.Var(&readmeFlag{noReadme: &opts.NoReadme}, "without-readme", "Leave READMEs out of the export, same as --no-readme")
+	readmeMaxBytes := flags.String("readme-max-bytes", "", "Truncate the exported READMEs larger than this size (e.g. 64KB)")
+	flags.StringVar(&opts.GroupBy, "group-by", "", "Group Markdown exports by topic or language")
+	flags.StringVar(&opts.Compress, "compress", "", "Compress the export with gzip or zstd")
+	gzip := flags.Bool("gzip", false, "Compress the export with gzip, same as --compress gzip")
+	flags.StringVar(&opts.Feed.Title, "feed-title", export.DefaultFeedTitle, "Title of atom and rss exports")
+	flags.StringVar(&opts.Feed.Link, "feed-link", "", "URL atom and rss exports are published at")
+	flags.StringVar(&opts.Feed.Author, "feed-author", "", "Author of atom and rss exports")
+	flags.IntVar(&opts.Feed.Items, "feed-items", export.DefaultFeedItems, "Number of most recent stars in atom and rss exports")
+	flags.BoolVar(&opts.Calendar.Yearly, "ics-yearly", false, "Repeat the ics events on every anniversary of the stars")
+	delimiter := flags.String("delimiter", "", "Field delimiter of csv exports, a single character or tab (default \",\")")
+	flags.BoolVar(&opts.CSV.CRLF, "crlf", false, "End the lines of csv exports with CRLF")
+	flags.BoolVar(&opts.CSV.BOM, "bom", false, "Start csv exports with a UTF-8 byte order mark")
+	excel := flags.Bool("excel", false, "Write csv exports Excel opens in any locale: semicolons, CRLF and a BOM")
+	maxSize := flags.String("max-output-size", "", "Trim the export to fit in this size (e.g. 50MB), READMEs first")
+	fields := flags.String("fields", "", "Comma separated fields json, ndjson, yaml and csv exports are restricted to, e.g. id,full_name,language,starred_at")
+	tmpl := flags.String("template", "", "Render the export through this Go text/template file, implies --format template")
+	flags.Parse(args)
+
+	if *profile != "" {
+		p, ok := config.Export[*profile]
+		if !ok {
+			return fmt.Errorf("unknown export profile %q", *profile)
+		}
+		if err := applyExportProfile(flags, p); err != nil {
+			return fmt.Errorf("export profile %s: %w", *profile, err)
+		}
+	}
+
+	if *tmpl != "" {
+		b, err := os.ReadFile(expandHome(*tmpl))
+		if err != nil {
+			return fmt.Errorf("--template: %w", err)
+		}
+		opts.Template = string(b)
+		formatSet := false
+		flags.Visit(func(f *flag.Flag) {
+			formatSet = formatSet || f.Name == "format"
+		})
+		if !formatSet {
+			*format = "template"
+		} else if *format != "template" && len(targets) == 0 {
+			return fmt.Errorf("--template can't be combined with --format %s", *format)
+		}
+	}
+
+	if !export.HasFormat(*format) {
+		return fmt.Errorf("unknown export format %q, expected one of %s", *format, exportFormats())
+	}
+	if err := applyGzip(&opts, *gzip); err != nil {
+		return err
+	}
+	switch opts.Compress {
+	case "", "gzip", "zstd":
+	default:
+		return fmt.Errorf("unknown compression %q, expected gzip or zstd", opts.Compress)
+	}
+	if *maxSize != "" {
+		n, err := parseBytes(*maxSize)
+		if err != nil {
+			return fmt.Errorf("--max-output-size: %w", err)
+		}
+		opts.MaxSize = n
+	}
+	if *readmeMaxBytes != "" {
+		n, err := parseBytes(*readmeMaxBytes)
+		if err != nil {
+			return fmt.Errorf("--readme-max-bytes: %w", err)
+		}
+		opts.ReadmeMaxBytes = int(n)
+	}
+	if *excel {
+		opts.CSV = export.ExcelCSV
+	}
+	if *delimiter != "" {
+		d, err := parseDelimiter(*delimiter)
+		if err != nil {
+			return fmt.Errorf("--delimiter: %w", err)
+		}
+		opts.CSV.Delimiter = d
+	}
+
+	if *fields != "" {
+		for _, f := range strings.Split(*fields, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				opts.Fields = append(opts.Fields, f)
+			}
+		}
+		formats := []string{*format}
+		if len(targets) > 0 {
+			formats = nil
+			for _, t := range targets {
+				formats = append(formats, t.Format)
+			}
+		}
+		for _, f := range formats {
+			if err := export.CheckFields(f, opts.Fields); err != nil {
+				return fmt.Errorf("--fields: %w", err)
+			}
+		}
+	}
+
+	if len(targets) > 0 && *output != "" {
+		return fmt.Errorf("--to and --output can't be combined")
+	}
+	if *vault != "" && (len(targets) > 0 || *output != "") {
+		return fmt.Errorf("--vault can't be combined with --to or --output")
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	if len(targets) > 0 {
+		_, err := exportToFiles(sess, targets, opts)
+		return err
+	}
+	if *vault != "" {
+		return exportVault(sess, *vault, opts)
+	}
+	if *output != "" {
+		path, err := siteDataPath(*output, *format)
+		if err != nil {
+			return err
+		}
+		return exportToFile(sess, path, *format, opts)
+	}
+
+	_, err = exportStars(sess, os.Stdout, *format, opts)
+	return err
+}
+
+// readmeFlag is a boolean flag setting whether READMEs are exported:
+// --with-readme when with is set, --without-readme otherwise.
+type readmeFlag struct {
+	noReadme *bool
+	with     bool
+}
+
+func (f *readmeFlag) String() string {
+	if f.noReadme == nil {
+		return "false"
+	}
+	return strconv.FormatBool(*f.noReadme != f.with)
+}
+
+func (f *readmeFlag) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*f.noReadme = v != f.with
+	return nil
+}
+
+func (f *readmeFlag) IsBoolFlag() bool { return true }
+
+// compressionFor returns the compression of the export written to path:
+// compression when set, otherwise the one of its extension, .gz or .zst.
+func compressionFor(path, compression string) string {
+	if compression != "" {
+		return compression
+	}
+	switch filepath.Ext(path) {
+	case ".gz":
+		return "gzip"
+	case ".zst":
+		return "zstd"
+	}
+	return ""
+}
+
+// applyGzip sets the gzip compression of opts when gzip is set.
+func applyGzip(opts *exportOptions, gzip bool) error {
+	if !gzip {
+		return nil
+	}
+	if opts.Compress != "" && opts.Compress != "gzip" {
+		return fmt.Errorf("--gzip can't be combined with --compress %s", opts.Compress)
+	}
+	opts.Compress = "gzip"
+	return nil
+}
+
+// parseDelimiter parses a csv delimiter: a single character, or tab.
+func parseDelimiter(s string) (rune, error) {
+	if s == "tab" || s == `\t` {
+		return '\t', nil
+	}
+	r := []rune(s)
+	if len(r) != 1 || r[0] == '"' || r[0] == '\r' || r[0] == '\n' || r[0] == utf8.RuneError {
+		return 0, fmt.Errorf("invalid delimiter %q, expected a single character or tab", s)
+	}
+
+	return r[0], nil
+}
+
+// applyExportProfile sets the flags not given in the command line from the
+// export profile p.
+func applyExportProfile(flags *flag.FlagSet, p ExportProfile) error {
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	// --with-readme and --without-readme override no_readme too.
+	if set["with-readme"] || set["without-readme"] {
+		set["no-readme"] = true
+	}
+
+	values := map[string][]string{
+		"format":           {p.Format},
+		"output":           {p.Output},
+		"vault":            {p.Vault},
+		"template":         {p.Template},
+		"fields":           {strings.Join(p.Fields, ",")},
+		"only":             p.Only,
+		"to":               p.To,
+		"query":            {p.Query},
+		"group-by":         {p.GroupBy},
+		"compress":         {p.Compress},
+		"ssh":              {strconv.FormatBool(p.SSH)},
+		"no-readme":        {strconv.FormatBool(p.NoReadme)},
+		"readme-max-bytes": {p.ReadmeMaxBytes},
+		"feed-title":       {p.FeedTitle},
+		"feed-link":        {p.FeedLink},
+		"feed-author":      {p.FeedAuthor},
+		"max-output-size":  {p.MaxOutputSize},
+		"ics-yearly":       {strconv.FormatBool(p.ICSYearly)},
+		"delimiter":        {p.Delimiter},
+		"crlf":             {strconv.FormatBool(p.CRLF)},
+		"bom":              {strconv.FormatBool(p.BOM)},
+		"excel":            {strconv.FormatBool(p.Excel)},
+	}
+	if p.FeedItems > 0 {
+		values["feed-items"] = []string{strconv.Itoa(p.FeedItems)}
+	}
+
+	for name, vv := range values {
+		if set[name] {
+			continue
+		}
+		for _, v := range vv {
+			if v == "" {
+				continue
+			}
+			if err := flags.Set(name, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// exportStars writes the stored stars to w in the given format, returning
+// the number of stars exported.
+func exportStars(sess db.Session, w io.Writer, format string, opts exportOptions) (int, error) {
+	if !export.HasFormat(format) {
+		return 0, fmt.Errorf("unknown export format %q", format)
+	}
+	if opts.Location == nil {
+		opts.Location = displayLocation
+	}
+	// Fitting a size budget needs all the stars at hand.
+	if export.CanStream(format) && opts.MaxSize == 0 {
+		return streamStars(sess, w, format, opts)
+	}
+
+	stars, err := filteredStars(sess, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	return writeStars(w, format, stars, opts)
+}
+
+// writeStars writes stars to w in the given format, trimmed to fit in
+// opts.MaxSize when set, returning the number of stars written.
+func writeStars(w io.Writer, format string, stars []*Repository, opts exportOptions) (int, error) {
+	if opts.MaxSize > 0 {
+		trimmed, err := export.WriteWithin(w, format, stars, opts.Options, opts.MaxSize)
+		if err != nil {
+			return 0, err
+		}
+		if trimmed.Any() {
+			logger.Warnf("Trimmed the export to fit in %d bytes: %d READMEs dropped, %d descriptions truncated, %d path bookmarks dropped, %d stars left out",
+				opts.MaxSize, trimmed.Readmes, trimmed.Descriptions, trimmed.PathBookmarks, trimmed.Stars)
+		}
+		return len(stars) - trimmed.Stars, nil
+	}
+
+	return len(stars), export.Write(w, format, stars, opts.Options)
+}
+
+// exportVault writes a note per exported star to the vault in dir.
+func exportVault(sess db.Session, dir string, opts exportOptions) error {
+	if opts.Location == nil {
+		opts.Location = displayLocation
+	}
+	stars, err := filteredStars(sess, opts)
+	if err != nil {
+		return err
+	}
+	for _, r := range stars {
+		export.LimitReadme(r, opts.Options)
+	}
+
+	n, err := export.WriteVault(dir, stars, opts.Options)
+	if err != nil {
+		return err
+	}
+	logger.Infof("%d notes written, %d unchanged", n, len(stars)-n)
+
+	return nil
+}
+
+// siteDataPath returns where to write the hugo and jekyll exports when
+// output is a site directory: its data file, creating the data directory.
+// Other outputs are returned as is.
+func siteDataPath(output, format string) (string, error) {
+	file := export.DataFile(format)
+	if fi, err := os.Stat(output); file == "" || err != nil || !fi.IsDir() {
+		return output, nil
+	}
+
+	path := filepath.Join(output, file)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// filteredStars returns the stored stars matching the query and filters
+// of opts, up to its limit, pinned ones first, along with their path
+// bookmarks and the [topics] aliases applied.
+func filteredStars(sess db.Session, opts exportOptions) ([]*Repository, error) {
+	res := starsResult(sess, opts.Query).And(opts.cond()).OrderBy(pinnedOrder...)
+	if opts.Limit > 0 {
+		res = res.Limit(opts.Limit)
+	}
+
+	stars := []*Repository{}
+	if err := res.All(&stars); err != nil {
+		return nil, err
+	}
+	if err := attachPathBookmarks(sess, stars); err != nil {
+		return nil, err
+	}
+	for _, r := range stars {
+		r.Topics = normalizeTopics(r.Topics, config.Topics.Aliases)
+	}
+
+	return stars, nil
+}
+
+// streamStars writes the stars a row at a time as they're read from the
+// database, keeping the memory usage flat on very large collections.
+func streamStars(sess db.Session, w io.Writer, format string, opts exportOptions) (int, error) {
+	next, closeRows, err := starRows(sess, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer closeRows()
+
+	n := 0
+	err = export.Stream(w, format, func() (*Repository, error) {
+		r, err := next()
+		if err == nil {
+			n++
+		}
+		return r, err
+	}, opts.Options)
+
+	return n, err
+}
+
+// starRows returns a function reading the stars matching the query and
+// filters of opts from the database a row at a time, up to its limit,
+// returning io.EOF after the last one. The returned close function releases
+// the result.
+func starRows(sess db.Session, opts exportOptions) (func() (*Repository, error), func() error, error) {
+	bookmarks, err := pathBookmarksByRepo(sess)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res := starsResult(sess, opts.Query).And(opts.cond()).OrderBy(pinnedOrder...)
+	if opts.Limit > 0 {
+		res = res.Limit(opts.Limit)
+	}
+	next := func() (*Repository, error) {
+		r := &Repository{}
+		if !res.Next(r) {
+			if err := res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		r.Topics = normalizeTopics(r.Topics, config.Topics.Aliases)
+		r.PathBookmarks = bookmarks[strings.ToLower(r.FullName)]
+		return r, nil
+	}
+
+	return next, res.Close, nil
+}
+
+// starsResult returns the stored stars matching the full text search query,
+// or all of them when empty.
+func starsResult(sess db.Session, query string) db.Result {
+	if query != "" {
+		return sess.Collection("starred_repos").Find(db.Raw("id IN (SELECT docid FROM starred_repos_fts WHERE starred_repos_fts MATCH ?)", query))
+	}
+
+	return sess.Collection("starred_repos").Find()
+}
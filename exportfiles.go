@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/export"
+	"github.com/upper/db/v4"
+)
+
+// exportQueueSize is the number of stars queued for each streamed file of a
+// multi-file export, bounding the memory used when the writers fall behind
+// the database.
+const exportQueueSize = 256
+
+// exportTarget is a file written by a multi-file export.
+type exportTarget struct {
+	Format string
+	Path   string
+}
+
+// exportTargets is a repeatable flag.Value of FORMAT=FILE export targets.
+type exportTargets []exportTarget
+
+func (t *exportTargets) String() string {
+	var s []string
+	for _, target := range *t {
+		s = append(s, target.Format+"="+target.Path)
+	}
+	return strings.Join(s, ",")
+}
+
+func (t *exportTargets) Set(s string) error {
+	format, path, ok := strings.Cut(s, "=")
+	if !ok || format == "" || path == "" {
+		return fmt.Errorf("expected FORMAT=FILE, got %q", s)
+	}
+	if !export.HasFormat(format) {
+		return fmt.Errorf("unknown export format %q, expected one of %s", format, exportFormats())
+	}
+	*t = append(*t, exportTarget{Format: format, Path: path})
+
+	return nil
+}
+
+// exportToFiles writes the export to every target concurrently, reading the
+// stars from the database once. Streamable formats get the stars through
+// bounded queues as they're read, unless another format needs all of them
+// in memory anyway. The files are replaced atomically once all of them were
+// written, none is replaced when any fails. Returns the number of stars
+// exported.
+func exportToFiles(sess db.Session, targets []exportTarget, opts exportOptions) (int, error) {
+	if opts.Location == nil {
+		opts.Location = displayLocation
+	}
+
+	// Fitting a size budget needs all the stars at hand.
+	streamed := func(t exportTarget) bool {
+		return export.CanStream(t.Format) && opts.MaxSize == 0
+	}
+
+	var stars []*Repository
+	loaded := false
+	for _, t := range targets {
+		if streamed(t) {
+			continue
+		}
+		var err error
+		if stars, err = filteredStars(sess, opts); err != nil {
+			return 0, err
+		}
+		loaded = true
+		break
+	}
+
+	files := make([]*os.File, len(targets))
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				f.Close()
+				os.Remove(f.Name())
+			}
+		}
+	}()
+	for i, t := range targets {
+		f, err := os.CreateTemp(filepath.Dir(t.Path), ".ghstars-export-*")
+		if err != nil {
+			return 0, err
+		}
+		files[i] = f
+	}
+
+	// The stars are shared by the writers, the READMEs are dropped or
+	// truncated once instead of by each of them.
+	for _, r := range stars {
+		export.LimitReadme(r, opts.Options)
+	}
+	writerOpts := opts
+	writerOpts.NoReadme = false
+	writerOpts.ReadmeMaxBytes = 0
+
+	var wg sync.WaitGroup
+	var queues []chan *Repository
+	errs := make([]error, len(targets))
+	for i, t := range targets {
+		f := files[i]
+		writerOpts := writerOpts
+		writerOpts.Compress = compressionFor(t.Path, opts.Compress)
+		if !streamed(t) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = writeStars(f, t.Format, copyStars(stars, opts.MaxSize > 0), writerOpts)
+			}()
+			continue
+		}
+
+		queue := make(chan *Repository, exportQueueSize)
+		queues = append(queues, queue)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = export.Stream(f, t.Format, func() (*Repository, error) {
+				r, ok := <-queue
+				if !ok {
+					return nil, io.EOF
+				}
+				return r, nil
+			}, writerOpts.Options)
+			// Keep the reader going when failing early.
+			for range queue {
+			}
+		}()
+	}
+
+	n, err := readStars(sess, opts, loaded, stars, func(r *Repository) {
+		if !loaded {
+			export.LimitReadme(r, opts.Options)
+		}
+		for _, queue := range queues {
+			queue <- r
+		}
+	})
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+
+	for i, t := range targets {
+		if errs[i] != nil {
+			errs[i] = fmt.Errorf("writing %s: %w", t.Path, errs[i])
+		}
+	}
+	if err := errors.Join(append(errs, err)...); err != nil {
+		return 0, err
+	}
+
+	for i, t := range targets {
+		if err := files[i].Chmod(0o644); err != nil {
+			return 0, err
+		}
+		if err := files[i].Close(); err != nil {
+			return 0, err
+		}
+		if err := os.Rename(files[i].Name(), t.Path); err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
+
+// readStars calls fn with every exported star, taken from stars when
+// loaded or read from the database otherwise, returning their number.
+func readStars(sess db.Session, opts exportOptions, loaded bool, stars []*Repository, fn func(*Repository)) (int, error) {
+	if loaded {
+		for _, r := range stars {
+			fn(r)
+		}
+		return len(stars), nil
+	}
+
+	next, closeRows, err := starRows(sess, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer closeRows()
+
+	n := 0
+	for {
+		r, err := next()
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		fn(r)
+		n++
+	}
+}
+
+// copyStars returns a copy of stars that can be modified without affecting
+// the other writers, when trimmed to a size budget. Otherwise stars is
+// returned as is, the writers don't modify them.
+func copyStars(stars []*Repository, trimmed bool) []*Repository {
+	if !trimmed {
+		return stars
+	}
+
+	copies := make([]*Repository, len(stars))
+	for i, r := range stars {
+		c := *r
+		copies[i] = &c
+	}
+
+	return copies
+}
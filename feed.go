@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/export"
+	"github.com/upper/db/v4"
+)
+
+// feedEntries is the number of most recent stars listed in a feed.
+const feedEntries = 50
+
+// feedHandler serves an Atom feed of the most recent public stars matching
+// a kind:value filter, the value coming from the {name}.atom path segment.
+func (s *server) feedHandler(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		value, ok := strings.CutSuffix(r.PathValue("name"), ".atom")
+		if !ok || value == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		entries, err := s.feedStars(repoFilters{{kind: kind, value: value}})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, repo := range entries {
+			repo.Topics = normalizeTopics(repo.Topics, config.Topics.Aliases)
+		}
+
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		err = export.Write(w, "atom", entries, export.Options{Feed: export.FeedOptions{
+			Title: fmt.Sprintf("Stars with %s %s", kind, value),
+			Link:  requestURL(r),
+			Items: feedEntries,
+		}})
+		if err != nil {
+			logger.Errorf("writing feed: %s", err)
+		}
+	}
+}
+
+// feedStars returns the most recent public stars matching the filters.
+// Private repositories are left out, feeds are meant to be shared.
+func (s *server) feedStars(filters repoFilters) ([]*Repository, error) {
+	var matches []*Repository
+	err := s.sess.Collection("starred_repos").
+		Find(db.Cond{"source": sourceStarred, "unstarred_at IS": nil, "private": false}).
+		And(filters.cond()).
+		Select("id", "full_name", "html_url", "description", "starred_at", "topics").
+		// Times are compared as such, older databases store them in other
+		// formats.
+		OrderBy(db.Raw("julianday(starred_at) DESC"), "-id").
+		Limit(feedEntries).
+		All(&matches)
+
+	return matches, err
+}
+
+// requestURL rebuilds the absolute URL of the request.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
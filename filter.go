@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/upper/db/v4"
+)
+
+// repoFilter matches repositories by owner, topic, language or README
+// language.
+type repoFilter struct {
+	kind  string
+	value string
+}
+
+// repoFilters is a repeatable flag.Value of kind:value filters. A repository
+// matches when any of the filters matches, or when there are no filters.
+type repoFilters []repoFilter
+
+func (f *repoFilters) String() string {
+	var s []string
+	for _, filter := range *f {
+		s = append(s, filter.kind+":"+filter.value)
+	}
+
+	return strings.Join(s, ",")
+}
+
+func (f *repoFilters) Set(s string) error {
+	kind, value, ok := strings.Cut(s, ":")
+	if !ok || value == "" {
+		return fmt.Errorf("invalid filter %q, expected owner:NAME, topic:NAME, language:NAME or readme-language:CODE", s)
+	}
+
+	switch kind {
+	case "owner", "topic", "language", "readme-language":
+	default:
+		return fmt.Errorf("unknown filter %q, expected owner, topic, language or readme-language", kind)
+	}
+	*f = append(*f, repoFilter{kind: kind, value: value})
+
+	return nil
+}
+
+func (f repoFilters) match(repo Repository) bool {
+	if len(f) == 0 {
+		return true
+	}
+
+	for _, filter := range f {
+		switch filter.kind {
+		case "owner":
+			owner, _, _ := strings.Cut(repo.FullName, "/")
+			if strings.EqualFold(owner, filter.value) {
+				return true
+			}
+		case "topic":
+			value := normalizeTopic(filter.value, config.Topics.Aliases)
+			for _, topic := range normalizeTopics(repo.Topics, config.Topics.Aliases) {
+				if strings.EqualFold(topic, value) {
+					return true
+				}
+			}
+		case "language":
+			if strings.EqualFold(repo.Language, filter.value) {
+				return true
+			}
+		case "readme-language":
+			if strings.EqualFold(repo.ReadmeLanguage, filter.value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// cond is the SQL condition of match, for queries of the starred_repos
+// table.
+func (f repoFilters) cond() db.LogicalExpr {
+	if len(f) == 0 {
+		return db.And()
+	}
+
+	var conds []db.LogicalExpr
+	for _, filter := range f {
+		switch filter.kind {
+		case "owner":
+			conds = append(conds, db.Raw(`full_name LIKE ? ESCAPE '\'`, likeEscape(filter.value)+"/%"))
+		case "topic":
+			// Topics are stored comma separated, any alias of the topic
+			// matches too.
+			value := normalizeTopic(filter.value, config.Topics.Aliases)
+			names := []string{value}
+			for alias, topic := range config.Topics.Aliases {
+				if strings.EqualFold(topic, value) {
+					names = append(names, alias)
+				}
+			}
+			for _, name := range names {
+				conds = append(conds, db.Raw(`',' || topics || ',' LIKE ? ESCAPE '\'`, "%,"+likeEscape(name)+",%"))
+			}
+		case "language":
+			conds = append(conds, db.Raw("language = ? COLLATE NOCASE", filter.value))
+		case "readme-language":
+			conds = append(conds, db.Raw("readme_language = ? COLLATE NOCASE", filter.value))
+		}
+	}
+
+	return db.Or(conds...)
+}
+
+// likeEscape escapes the LIKE wildcards in s, for patterns using \ as the
+// escape character.
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+	"github.com/upper/db/v4"
+)
+
+// FollowedUser is a user followed by the authenticated user.
+type FollowedUser struct {
+	ID        int       `json:"id" db:"id"`
+	Login     string    `json:"login" db:"login"`
+	HTMLURL   string    `json:"html_url" db:"html_url"`
+	FetchedAt time.Time `json:"fetched_at" db:"fetched_at"`
+}
+
+// syncFollowing replaces the stored followed users with the ones followed
+// upstream.
+func syncFollowing(ctx context.Context, gh githubclient.Client, sess db.Session) error {
+	logger.Info("Fetching followed users from github.com...")
+	users, err := gh.Following(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	err = sess.Tx(func(tx db.Session) error {
+		following := tx.Collection("following")
+		if err := following.Truncate(); err != nil {
+			return err
+		}
+		for _, u := range users {
+			_, err := following.Insert(FollowedUser{ID: u.ID, Login: u.Login, HTMLURL: u.HTMLURL, FetchedAt: now})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logger.Infof("Following: %d", len(users))
+
+	return nil
+}
+
+type ownerStars struct {
+	Owner string
+	Stars int
+}
+
+// reportFollowing correlates the followed users with the owners of the
+// starred repositories.
+func reportFollowing(args []string) error {
+	flags := flag.NewFlagSet("following", flag.ExitOnError)
+	limit := flags.Int("limit", 20, "Number of owners listed")
+	flags.Parse(args)
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	var users []FollowedUser
+	if err := sess.Collection("following").Find().OrderBy("login").All(&users); err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return fmt.Errorf("no followed users stored, sync with --get-following first")
+	}
+
+	var stars []Repository
+	err = sess.Collection("starred_repos").
+		Find(db.Cond{"source": sourceStarred, "unstarred_at IS": nil}).
+		Select("full_name").
+		All(&stars)
+	if err != nil {
+		return err
+	}
+
+	return writeFollowingReport(os.Stdout, users, stars, *limit)
+}
+
+func writeFollowingReport(out io.Writer, users []FollowedUser, stars []Repository, limit int) error {
+	followed := map[string]bool{}
+	for _, u := range users {
+		followed[strings.ToLower(u.Login)] = true
+	}
+
+	counts := map[string]int{}
+	for _, r := range stars {
+		owner, _, _ := strings.Cut(r.FullName, "/")
+		counts[owner]++
+	}
+
+	var notFollowed []ownerStars
+	fromFollowed := 0
+	for owner, n := range counts {
+		if followed[strings.ToLower(owner)] {
+			fromFollowed += n
+			delete(followed, strings.ToLower(owner))
+			continue
+		}
+		notFollowed = append(notFollowed, ownerStars{owner, n})
+	}
+	sort.Slice(notFollowed, func(i, j int) bool {
+		if notFollowed[i].Stars != notFollowed[j].Stars {
+			return notFollowed[i].Stars > notFollowed[j].Stars
+		}
+		return notFollowed[i].Owner < notFollowed[j].Owner
+	})
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Followed users:\t%d\n", len(users))
+	share := 0.0
+	if len(stars) > 0 {
+		share = float64(fromFollowed) * 100 / float64(len(stars))
+	}
+	fmt.Fprintf(w, "Stars from followed users:\t%d of %d (%.1f%%)\n", fromFollowed, len(stars), share)
+
+	fmt.Fprintln(w, "\nMost starred owners you don't follow\tStars")
+	for i, o := range notFollowed {
+		if i == limit {
+			break
+		}
+		fmt.Fprintf(w, "%s\t%d\n", o.Owner, o.Stars)
+	}
+
+	fmt.Fprintln(w, "\nFollowed users you never starred from")
+	for _, u := range users {
+		if followed[strings.ToLower(u.Login)] {
+			fmt.Fprintln(w, u.Login)
+		}
+	}
+
+	return w.Flush()
+}
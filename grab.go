@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// viewGrab is the kind of the views recorded by grab.
+const viewGrab = "grab"
+
+// grabCmd shallow clones a starred repository into the workspace directory,
+// OWNER/NAME, and opens it in the editor, recording the grab in the views
+// table. Repositories already cloned are only opened.
+func grabCmd(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("grab", flag.ExitOnError)
+	ssh := flags.Bool("ssh", false, "Clone using SSH instead of HTTPS")
+	noOpen := flags.Bool("no-open", false, "Clone without opening the editor")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: gh-stars-exporter grab [flags] OWNER/NAME")
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	res, err := findRepo(sess, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	var r Repository
+	if err := res.One(&r); err != nil {
+		return err
+	}
+	// Private repositories stored with hashed names (hash_names in
+	// [private]) keep neither their name nor their URL.
+	if r.HTMLURL == "" {
+		return fmt.Errorf("%s has no URL to clone, private repository names are hashed in the database", r.FullName)
+	}
+
+	dir := filepath.Join(expandHome(config.Grab.Workspace), filepath.FromSlash(r.FullName))
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		logger.Infof("%s is already in %s", r.FullName, dir)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+			return err
+		}
+		url := r.HTMLURL + ".git"
+		if *ssh {
+			url = "git@github.com:" + r.FullName + ".git"
+		}
+		logger.Infof("Cloning %s into %s", r.FullName, dir)
+		if err := runAttached(ctx, "git", "clone", "--depth", "1", url, dir); err != nil {
+			return fmt.Errorf("cloning %s: %w", r.FullName, err)
+		}
+	}
+
+	_, err = sess.Collection("views").Insert(map[string]interface{}{
+		"repo_id":   r.ID,
+		"full_name": r.FullName,
+		"kind":      viewGrab,
+		"viewed_at": time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("recording the grab: %w", err)
+	}
+
+	if *noOpen {
+		fmt.Println(dir)
+		return nil
+	}
+	editor := strings.Fields(grabEditor())
+	if err := runAttached(ctx, editor[0], append(editor[1:], dir)...); err != nil {
+		return fmt.Errorf("opening %s: %w", dir, err)
+	}
+
+	return nil
+}
+
+// grabEditor returns the editor command grabbed repositories are opened
+// with: the configured one, $VISUAL, $EDITOR, or VS Code.
+func grabEditor() string {
+	for _, e := range []string{config.Grab.Editor, os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+		if strings.TrimSpace(e) != "" {
+			return e
+		}
+	}
+	return "code"
+}
+
+// runAttached runs the command attached to the terminal.
+func runAttached(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err := cmd.Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("%s not found in PATH", name)
+	}
+
+	return err
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"time"
+
+	"github.com/upper/db/v4"
+)
+
+// heatmapColors are the fill colors of the SVG heatmap cells, by level.
+var heatmapColors = []string{"#ebedf0", "#9be9a8", "#40c463", "#30a14e", "#216e39"}
+
+const (
+	heatmapCell = 11
+	heatmapGap  = 3
+	// heatmapLeft and heatmapTop leave room for the weekday and month labels.
+	heatmapLeft = 28
+	heatmapTop  = 16
+)
+
+type heatmapDay struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+	// Level goes from 0 (no stars) to 4 (the busiest days).
+	Level int `json:"level"`
+}
+
+// heatmap is a GitHub style contribution grid of the stars per day, one
+// column per week starting on Sunday.
+type heatmap struct {
+	From  string         `json:"from"`
+	To    string         `json:"to"`
+	Total int            `json:"total"`
+	Max   int            `json:"max"`
+	Weeks [][]heatmapDay `json:"weeks"`
+}
+
+// heatmapCmd exports the starring activity per day as an SVG or a JSON
+// grid.
+func heatmapCmd(args []string) error {
+	flags := flag.NewFlagSet("heatmap", flag.ExitOnError)
+	format := flags.String("format", "svg", "Output format: svg or json")
+	weeks := flags.Int("weeks", 53, "Number of weeks shown, ending this week")
+	flags.Parse(args)
+
+	if *format != "svg" && *format != "json" {
+		return fmt.Errorf("unknown heatmap format %q, expected svg or json", *format)
+	}
+	if *weeks < 1 {
+		return fmt.Errorf("--weeks must be greater than zero")
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	hm, err := buildHeatmap(sess, displayTime(time.Now()), *weeks)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		b, err := json.MarshalIndent(hm, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(string(b))
+		return err
+	}
+
+	return writeHeatmapSVG(os.Stdout, hm)
+}
+
+// buildHeatmap counts the stars per day in the time zone of now.
+func buildHeatmap(sess db.Session, now time.Time, weeks int) (*heatmap, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// The grid starts on the Sunday weeks-1 weeks ago and ends today.
+	from := today.AddDate(0, 0, -int(today.Weekday())-7*(weeks-1))
+
+	var stars []Repository
+	err := sess.Collection("starred_repos").
+		Find(db.Cond{"source": sourceStarred, "starred_at >=": from.UTC()}).
+		Select("starred_at").
+		All(&stars)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, r := range stars {
+		counts[r.StarredAt.In(now.Location()).Format(time.DateOnly)]++
+	}
+
+	hm := &heatmap{From: from.Format(time.DateOnly), To: today.Format(time.DateOnly)}
+	for _, n := range counts {
+		hm.Total += n
+		hm.Max = max(hm.Max, n)
+	}
+
+	for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Sunday {
+			hm.Weeks = append(hm.Weeks, nil)
+		}
+		date := day.Format(time.DateOnly)
+		d := heatmapDay{Date: date, Count: counts[date]}
+		if d.Count > 0 {
+			// Quartiles of the busiest day.
+			d.Level = min(4, (d.Count*4+hm.Max-1)/hm.Max)
+		}
+		w := len(hm.Weeks) - 1
+		hm.Weeks[w] = append(hm.Weeks[w], d)
+	}
+
+	return hm, nil
+}
+
+func writeHeatmapSVG(w io.Writer, hm *heatmap) error {
+	step := heatmapCell + heatmapGap
+	width := heatmapLeft + len(hm.Weeks)*step
+	height := heatmapTop + 7*step
+
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="9" fill="#767676">`+"\n", width, height)
+	fmt.Fprintf(w, "<title>%d stars from %s to %s</title>\n", hm.Total, hm.From, hm.To)
+
+	for i, label := range []string{"Mon", "Wed", "Fri"} {
+		fmt.Fprintf(w, `<text x="0" y="%d">%s</text>`+"\n", heatmapTop+(2*i+1)*step+heatmapCell-2, label)
+	}
+
+	lastMonth := ""
+	for x, week := range hm.Weeks {
+		month := week[0].Date[:7]
+		if month != lastMonth && week[0].Date[8:] <= "07" {
+			t, _ := time.Parse(time.DateOnly, week[0].Date)
+			fmt.Fprintf(w, `<text x="%d" y="%d">%s</text>`+"\n", heatmapLeft+x*step, heatmapTop-5, t.Format("Jan"))
+			lastMonth = month
+		}
+
+		for _, d := range week {
+			t, _ := time.Parse(time.DateOnly, d.Date)
+			fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" rx="2" fill="%s"><title>%s</title></rect>`+"\n",
+				heatmapLeft+x*step, heatmapTop+int(t.Weekday())*step, heatmapCell, heatmapCell,
+				heatmapColors[d.Level], html.EscapeString(fmt.Sprintf("%d stars on %s", d.Count, d.Date)))
+		}
+	}
+
+	_, err := fmt.Fprintln(w, "</svg>")
+	return err
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/internal/langdetect"
+	"github.com/upper/db/v4"
+)
+
+const indexUsage = `Usage: gh-stars-exporter index <command>
+
+Commands:
+  build   Rebuild the full text search index and detect missing README
+          languages from the database
+`
+
+// indexCmd groups the search index maintenance commands.
+func indexCmd(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, indexUsage)
+		return errUsage
+	}
+
+	switch args[0] {
+	case "build":
+		return indexBuild()
+	default:
+		fmt.Fprint(os.Stderr, indexUsage)
+		return errUsage
+	}
+}
+
+// indexBuild rebuilds the full text search index and detects the missing
+// README languages from the data already in the database, without any
+// network access. The index is kept up to date while syncing, rebuilding it
+// is only needed after importing or merging data with other tools.
+func indexBuild() error {
+	sess, err := dbInit()
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	return rebuildIndex(sess)
+}
+
+// rebuildIndex runs index build on sess, also scheduled as a daemon job.
+func rebuildIndex(sess db.Session) error {
+	start := time.Now()
+	detected, err := detectReadmeLanguages(sess)
+	if err != nil {
+		return fmt.Errorf("detecting README languages: %w", err)
+	}
+	logger.Infof("Detected the language of %d READMEs", detected)
+
+	logger.Info("Rebuilding the full text search index")
+	if _, err := sess.SQL().Exec(`INSERT INTO starred_repos_fts(starred_repos_fts) VALUES ('rebuild')`); err != nil {
+		return fmt.Errorf("rebuilding the full text search index: %w", err)
+	}
+	if _, err := sess.SQL().Exec(`INSERT INTO starred_repos_fts(starred_repos_fts) VALUES ('optimize')`); err != nil {
+		return fmt.Errorf("optimizing the full text search index: %w", err)
+	}
+	logger.Infof("Full text search index rebuilt in %s", time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
+
+// detectReadmeLanguages detects the language of the stored READMEs that
+// don't have one, returning how many were detected.
+func detectReadmeLanguages(sess db.Session) (int, error) {
+	var repos []Repository
+	err := sess.Collection("starred_repos").
+		Find(db.Cond{"readme IS NOT": nil, "readme_language": ""}).
+		Select("id", "readme").
+		All(&repos)
+	if err != nil {
+		return 0, err
+	}
+
+	detected := 0
+	err = sess.Tx(func(tx db.Session) error {
+		for _, r := range repos {
+			lang := langdetect.Detect(r.Readme.String)
+			if lang == "" {
+				continue
+			}
+			_, err := tx.SQL().Update("starred_repos").Set("readme_language", lang).Where("id = ?", r.ID).Exec()
+			if err != nil {
+				return err
+			}
+			detected++
+		}
+		return nil
+	})
+
+	return detected, err
+}
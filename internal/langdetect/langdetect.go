@@ -0,0 +1,151 @@
+// Package langdetect guesses the natural language of README files.
+//
+// Languages with their own script are told apart by the script of their
+// letters, languages written in the Latin script by their most common words.
+// It's meant for whole documents, short texts are usually undetermined.
+package langdetect
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// minWords is the number of common words required to tell a Latin script
+// language.
+const minWords = 5
+
+// scriptShare is the minimum share of letters written in a script for its
+// language to be chosen over the Latin script languages. READMEs written in
+// other scripts usually include code, commands and English terms.
+const scriptShare = 0.2
+
+var scripts = []struct {
+	lang  string
+	table *unicode.RangeTable
+}{
+	{"ko", unicode.Hangul},
+	{"ru", unicode.Cyrillic},
+	{"ar", unicode.Arabic},
+	{"he", unicode.Hebrew},
+	{"th", unicode.Thai},
+	{"hi", unicode.Devanagari},
+	{"el", unicode.Greek},
+}
+
+var commonWords = map[string][]string{
+	"en": {"the", "and", "is", "to", "of", "for", "with", "this", "that", "you", "are", "it", "can", "from", "be", "on"},
+	"es": {"el", "los", "las", "que", "y", "para", "con", "una", "por", "del", "se", "es", "como", "más", "puede"},
+	"fr": {"le", "les", "et", "des", "est", "pour", "une", "dans", "du", "avec", "vous", "sur", "pas", "qui", "au"},
+	"de": {"der", "die", "und", "das", "ist", "mit", "für", "ein", "eine", "den", "zu", "nicht", "von", "auf", "wird", "sie"},
+	"pt": {"o", "os", "que", "e", "do", "da", "em", "para", "com", "um", "uma", "é", "não", "por", "como"},
+	"it": {"il", "di", "che", "per", "un", "una", "è", "con", "del", "della", "non", "sono", "gli", "alla", "questo"},
+	"nl": {"het", "een", "en", "van", "is", "dat", "op", "te", "voor", "met", "niet", "zijn", "je", "wordt", "kan"},
+}
+
+var (
+	fencedCode = regexp.MustCompile("(?s)```.*?```")
+	inlineCode = regexp.MustCompile("`[^`]*`")
+	links      = regexp.MustCompile(`https?://\S+|\]\([^)]*\)`)
+	htmlTags   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Detect returns the ISO 639-1 code of the primary language of the
+// Markdown text, or an empty string when undetermined.
+func Detect(text string) string {
+	text = fencedCode.ReplaceAllString(text, " ")
+	text = inlineCode.ReplaceAllString(text, " ")
+	text = links.ReplaceAllString(text, " ")
+	text = htmlTags.ReplaceAllString(text, " ")
+
+	if lang := detectScript(text); lang != "" {
+		return lang
+	}
+
+	return detectWords(text)
+}
+
+func detectScript(text string) string {
+	var letters, han, kana int
+	counts := make([]int, len(scripts))
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+			continue
+		case unicode.Is(unicode.Han, r):
+			han++
+			continue
+		}
+		for i, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// Japanese mixes kanji with kana, Chinese only uses Han characters.
+	if float64(han+kana)/float64(letters) >= scriptShare {
+		if kana*10 >= han+kana {
+			return "ja"
+		}
+		return "zh"
+	}
+
+	best, bestCount := "", 0
+	for i, s := range scripts {
+		if counts[i] > bestCount {
+			best, bestCount = s.lang, counts[i]
+		}
+	}
+	if float64(bestCount)/float64(letters) >= scriptShare {
+		return best
+	}
+
+	return ""
+}
+
+func detectWords(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	counts := map[string]int{}
+	for lang, common := range commonWords {
+		set := make(map[string]bool, len(common))
+		for _, w := range common {
+			set[w] = true
+		}
+		for _, w := range words {
+			if set[w] {
+				counts[lang]++
+			}
+		}
+	}
+
+	best, bestCount, second := "", 0, 0
+	for lang, n := range counts {
+		switch {
+		case n > bestCount || (n == bestCount && lang < best):
+			second = bestCount
+			best, bestCount = lang, n
+		case n > second:
+			second = n
+		}
+	}
+	// Require a clear winner, the common words of close languages overlap.
+	if bestCount < minWords || float64(bestCount) < 1.2*float64(second) {
+		return ""
+	}
+
+	return best
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+	"github.com/upper/db/v4"
+)
+
+// linkResult is the outcome of checking the homepage of a repository.
+type linkResult struct {
+	FullName string
+	URL      string
+	Status   string
+	Dead     bool
+}
+
+// reportLinkcheck requests the homepage URLs of the starred repositories,
+// listing the ones that fail or return an error status.
+func reportLinkcheck(args []string) error {
+	flags := flag.NewFlagSet("linkcheck", flag.ExitOnError)
+	concurrency := flags.Int("concurrency", 8, "Number of links checked in parallel")
+	timeout := flags.Duration("timeout", 10*time.Second, "Timeout for each link checked")
+	all := flags.Bool("all", false, "List every link checked, not only the dead ones")
+	flags.Parse(args)
+
+	if *concurrency < 1 {
+		return fmt.Errorf("invalid concurrency %d", *concurrency)
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	var repos []Repository
+	err = sess.Collection("starred_repos").
+		Find(db.Cond{"homepage !=": "", "unstarred_at IS": nil}).
+		Select("full_name", "homepage").
+		OrderBy("full_name").
+		All(&repos)
+	if err != nil {
+		return err
+	}
+
+	results := checkLinks(newHTTPClient(*timeout), repos, *concurrency)
+
+	dead := 0
+	for _, r := range results {
+		if r.Dead {
+			dead++
+		}
+	}
+	logger.Infof("Checked %d homepages, %d dead", len(results), dead)
+
+	return writeLinkcheck(os.Stdout, results, *all)
+}
+
+// checkLinks checks the homepages of repos using up to concurrency
+// requests in parallel, returning the results in the order of repos.
+func checkLinks(client *http.Client, repos []Repository, concurrency int) []linkResult {
+	results := make([]linkResult, len(repos))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, r := range repos {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = checkLink(client, r)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// checkLink sends a HEAD request to the homepage of r, falling back to GET
+// for the servers not supporting HEAD. Links failing to respond or
+// returning a 4xx or 5xx status are dead.
+func checkLink(client *http.Client, r Repository) linkResult {
+	res := linkResult{FullName: r.FullName, URL: r.Homepage}
+
+	resp, err := requestLink(client, http.MethodHead, r.Homepage)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = requestLink(client, http.MethodGet, r.Homepage)
+	}
+	if err != nil {
+		logger.Debugf("Checking %s: %s", r.Homepage, err)
+		res.Status = "error"
+		res.Dead = true
+		return res
+	}
+
+	res.Status = resp.Status
+	res.Dead = resp.StatusCode >= 400
+	return res
+}
+
+func requestLink(client *http.Client, method, url string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	ua := githubclient.DefaultUserAgent
+	if config.GitHub.UserAgent != "" {
+		ua = config.GitHub.UserAgent
+	}
+	req.Header.Set("User-Agent", ua)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// Only the status matters, don't download the page.
+	resp.Body.Close()
+
+	return resp, nil
+}
+
+func writeLinkcheck(out io.Writer, results []linkResult, all bool) error {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Dead && !results[j].Dead
+	})
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "Repository\tHomepage\tStatus")
+	for _, r := range results {
+		if !r.Dead && !all {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.FullName, r.URL, r.Status)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/upper/db/v4"
+)
+
+// Component loggers, their level can be set independently with --log-level.
+var (
+	httpLogger = logger.WithPrefix("http")
+	dbLogger   = logger.WithPrefix("db")
+)
+
+// setupLogging sets the log levels from a --log-level specification: a comma
+// separated list of levels, either global (debug) or per component
+// (http=debug). The http component follows the global level unless set, ORM
+// logs are limited to warnings unless db is set.
+func setupLogging(spec string, debug bool) error {
+	level := log.InfoLevel
+	if debug {
+		level = log.DebugLevel
+	}
+
+	components := map[string]log.Level{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			value = name
+		}
+		l, err := log.ParseLevel(value)
+		if err != nil {
+			return fmt.Errorf("invalid log level %q", item)
+		}
+
+		switch {
+		case !ok:
+			level = l
+		case name == "http" || name == "db":
+			components[name] = l
+		default:
+			return fmt.Errorf("unknown log component %q, expected http or db", name)
+		}
+	}
+
+	logger.SetLevel(level)
+
+	httpLevel, ok := components["http"]
+	if !ok {
+		httpLevel = level
+	}
+	httpLogger.SetLevel(httpLevel)
+
+	dbLevel, ok := components["db"]
+	if !ok {
+		dbLevel = log.WarnLevel
+	}
+	dbLogger.SetLevel(dbLevel)
+	db.LC().SetLogger(upperLogger{dbLogger})
+	db.LC().SetLevel(upperLevel(dbLevel))
+
+	return nil
+}
+
+func upperLevel(l log.Level) db.LogLevel {
+	switch l {
+	case log.DebugLevel:
+		return db.LogLevelDebug
+	case log.InfoLevel:
+		return db.LogLevelInfo
+	case log.WarnLevel:
+		return db.LogLevelWarn
+	case log.ErrorLevel:
+		return db.LogLevelError
+	default:
+		return db.LogLevelFatal
+	}
+}
+
+// upperLogger sends the upper/db logs, already filtered by level, to a
+// component logger.
+type upperLogger struct {
+	l *log.Logger
+}
+
+func (u upperLogger) Print(v ...interface{}) {
+	u.l.Print(strings.TrimSpace(fmt.Sprint(v...)))
+}
+
+func (u upperLogger) Printf(format string, v ...interface{}) {
+	u.l.Print(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (u upperLogger) Fatal(v ...interface{}) {
+	u.l.Fatal(fmt.Sprint(v...))
+}
+
+func (u upperLogger) Fatalf(format string, v ...interface{}) {
+	u.l.Fatalf(format, v...)
+}
+
+func (u upperLogger) Panic(v ...interface{}) {
+	panic(fmt.Sprint(v...))
+}
+
+func (u upperLogger) Panicf(format string, v ...interface{}) {
+	panic(fmt.Sprintf(format, v...))
+}
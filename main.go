@@ -1,59 +1,27 @@
 package main
 
 import (
-	"database/sql"
-	"database/sql/driver"
-	"embed"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
 	"os"
+	"os/signal"
+	runtimedebug "runtime/debug"
 	"strings"
+	"syscall"
 	"time"
 
 	"flag"
 
 	"github.com/charmbracelet/log"
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
-	"github.com/golang-migrate/migrate/v4/source/iofs"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+	"github.com/rubiojr/gh-stars-exporter/pkg/stars"
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
 	"github.com/upper/db/v4"
-	"github.com/upper/db/v4/adapter/sqlite"
 )
 
-//go:embed db/migrations/*.sql
-var fs embed.FS
-
-var readmeFiles = []string{
-	"README.md",
-	"README.rst",
-	"README.adoc",
-	"README.markdown",
-	"README.rdoc",
-	"README.txt",
-	"README.mkd",
-	"README.textile",
-	"README",
-	"readme.md",
-	"Readme.md",
-	"README.MD",
-	"readme.markdown",
-	"readme",
-	"Readme",
-	"readme.rst",
-	"Readme.rst",
-	"README.org",
-	"Readme.org",
-	"readme.org",
-	"docs/README.md",
-	".github/README.md",
-	"docs/Readme.md",
-	"docs/readme.md",
-}
-
 var logger = log.NewWithOptions(os.Stderr, log.Options{
 	ReportTimestamp: false,
 })
@@ -62,352 +30,440 @@ type ConnectionURL struct {
 	Database string
 }
 
-type StarredRepo struct {
-	Repo      Repository `json:"repo"`
-	StarredAt time.Time  `json:"starred_at"`
-}
-
-type Repository struct {
-	ID              int            `json:"id" db:"id"`
-	Name            string         `json:"name" db:"name"`
-	HTMLURL         string         `json:"html_url" db:"html_url"`
-	Description     string         `json:"description" db:"description"`
-	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time      `json:"updated_at" db:"updated_at"`
-	PushedAt        time.Time      `json:"pushed_at" db:"pushed_at"`
-	StargazersCount int            `json:"stargazers_count" db:"stargazers_count"`
-	Language        string         `json:"language" db:"language"`
-	FullName        string         `json:"full_name" db:"full_name"`
-	Topics          StringList     `json:"topics" db:"topics"`
-	IsTemplate      bool           `json:"is_template" db:"is_template"`
-	Private         bool           `json:"private" db:"private"`
-	StarredAt       time.Time      `json:"starred_at" db:"starred_at"`
-	Readme          sql.NullString `json:"readme" db:"readme"`
-}
+// Repository is the stored representation of a starred repository.
+type Repository = store.Repository
 
-type StringList []string
+// PathBookmark is a bookmarked sub-path of a repository.
+type PathBookmark = store.PathBookmark
 
-func (sl StringList) Value() (driver.Value, error) {
-	return strings.Join(sl, ","), nil
-}
+// Repository sources.
+const (
+	sourceStarred = store.SourceStarred
+	sourceManual  = store.SourceManual
+)
 
-func (sl *StringList) Scan(value interface{}) error {
-	if value == nil {
-		*sl = nil
-		return nil
+func dbInit() (db.Session, error) {
+	dest, err := store.BackupBeforeMigrating(dbFile, store.BackupOptions{Keep: config.Backup.Keep, Dir: config.Backup.Dir})
+	if err != nil {
+		return nil, fmt.Errorf("backing up the database before migrating it: %w", err)
 	}
-
-	if bv, err := driver.String.ConvertValue(value); err == nil {
-		if v, ok := bv.(string); ok {
-			*sl = strings.Split(v, ",")
-			return nil
-		}
+	if dest != "" {
+		logger.Infof("Backed up the database to %s before migrating it", dest)
 	}
 
-	return fmt.Errorf("failed to scan StringList")
+	logger.Debug("Migrating database...")
+	return store.Open(dbFile, storeOptions())
 }
 
-func jsonExport(sess db.Session) error {
-	stars := []*Repository{}
-	sess.Collection("starred_repos").Find().All(&stars)
-
-	b, err := json.MarshalIndent(stars, "", "  ")
-	if err != nil {
-		return err
-	}
-	fmt.Println(string(b))
-
-	return nil
+// openDB opens the database file without migrating it.
+func openDB() (db.Session, error) {
+	return store.Connect(dbFile, storeOptions())
 }
 
-func dbInit() (db.Session, error) {
-	if err := migrateDB(); err != nil {
-		return nil, err
-	}
-
-	settings := sqlite.ConnectionURL{
-		Database: dbFile,
-	}
-	sess, err := sqlite.Open(settings)
-
-	return sess, err
+func storeOptions() store.Options {
+	return store.Options{Extensions: config.SQLite.Extensions}
 }
 
-func token() string {
+// token returns the GitHub token from GITHUB_TOKEN, read from the file in
+// GITHUB_TOKEN_FILE (e.g. a container secret), or stored by auth set-token.
+func token() (string, error) {
 	token := os.Getenv("GITHUB_TOKEN")
+	if path := os.Getenv("GITHUB_TOKEN_FILE"); token == "" && path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading GITHUB_TOKEN_FILE: %w", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	if token == "" && config.Auth.Token != "" {
+		var err error
+		token, err = storedToken(config.Auth)
+		if err != nil {
+			return "", fmt.Errorf("reading the stored GitHub token: %w", err)
+		}
+	}
 	if token == "" {
-		logger.Fatal("GITHUB_TOKEN is required")
+		return "", errors.New("GITHUB_TOKEN is required, or a token stored with auth set-token")
 	}
-	return token
+	return token, nil
 }
 
-var newStars int
-var updatedStars int
-
 func main() {
 	flag.Parse()
+	if err := flagsFromEnv(flag.CommandLine); err != nil {
+		logger.Fatal(err)
+	}
 
-	if debug {
-		logger.SetLevel(log.DebugLevel)
+	err := setupLogging(logLevel, debug)
+	if err != nil {
+		logger.Fatal(err)
 	}
 
-	if getReadme {
-		logger.Info("Fetching READMEs enabled")
+	config, err = loadConfig(configFile)
+	if err != nil {
+		logger.Fatal("loading configuration", err)
+	}
+	if policyPath != "" {
+		syncPolicy, err = loadPolicy(policyPath)
+		if err != nil {
+			logger.Fatalf("loading the sync policy: %s", err)
+		}
 	}
 
-	if jsonFlag {
-		logger.Info("JSON export enabled")
+	if recordDir != "" && replayDir != "" {
+		logger.Fatal("--record and --replay can't be combined")
 	}
 
-	if skipUpdate && jsonFlag {
-		if _, err := os.Stat(dbFile); os.IsNotExist(err) {
-			logger.Fatal("Database file not found, use the exporter without --skip-update at least once.")
+	if tzName != "" {
+		displayLocation, err = time.LoadLocation(tzName)
+		if err != nil {
+			logger.Fatalf("invalid --tz: %s", err)
 		}
 	}
 
-	sess, err := dbInit()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stopProfiling, err := startProfiling()
+	if err != nil {
+		logger.Fatal("starting profiler", err)
+	}
+	// Exiting skips the deferred calls, the profiles are written first.
+	err = run(ctx)
+	stopProfiling()
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
 	if err != nil {
-		logger.Fatal("opening database", err)
+		logger.Fatal(err)
 	}
-	stars := sess.Collection("starred_repos")
+}
 
-	newStars := 0
-	if !skipUpdate {
-		logger.Info("Fetching stars from github.com...")
-		err = fetchAllStarredRepos(token(), func(repos []StarredRepo) error {
-			for _, sr := range repos {
-				repo := sr.Repo
-				repo.StarredAt = sr.StarredAt
-				if repo.Private && !storePrivate {
-					logger.Warnf("Skipping private repository %s", repo.FullName)
-					continue
-				}
-
-				res := stars.Find(db.Cond{"id": repo.ID})
-				var r Repository
-				err := res.One(&r)
-				if err == nil {
-					if getReadme {
-						updateRepoReadme(r, res)
-					}
-					continue
-				}
-
-				return addNewRepo(repo, sess)
-			}
+// errUsage is returned by the commands after printing their usage, to exit
+// with status 2 like flag parsing errors do.
+var errUsage = errors.New("invalid usage")
 
-			return nil
-		})
-		logger.Infof("New stars: %d", newStars)
-		logger.Infof("Updated stars: %d", updatedStars)
-	} else {
-		logger.Info("Skipping update (offline mode)")
+// run runs the command given in the arguments.
+func run(ctx context.Context) error {
+	if showBanner(flag.Arg(0)) {
+		if err := weeklyBanner(os.Stderr, time.Now()); err != nil {
+			logger.Warnf("Printing the weekly banner: %s", err)
+		}
 	}
 
-	if jsonFlag {
-		err = jsonExport(sess)
-		if err != nil {
-			logger.Fatal("exporting to JSON", err)
-		}
+	switch cmd := flag.Arg(0); cmd {
+	case "", "sync":
+		return syncAndExport(ctx, flag.Args())
+	case "serve":
+		return serveCmd(ctx, flag.Args()[1:])
+	case "daemon":
+		return daemonCmd(ctx, flag.Args()[1:])
+	case "bookmark":
+		return bookmarkCmd(flag.Args()[1:])
+	case "pin":
+		return pinCmd(flag.Args()[1:])
+	case "unpin":
+		return unpinCmd(flag.Args()[1:])
+	case "changelog":
+		return changelogCmd(flag.Args()[1:])
+	case "db":
+		return dbCmd(ctx, flag.Args()[1:])
+	case "export":
+		return exportCmd(flag.Args()[1:])
+	case "radar":
+		return radarCmd(flag.Args()[1:])
+	case "report":
+		return reportCmd(ctx, flag.Args()[1:])
+	case "stats":
+		return statsCmd(flag.Args()[1:])
+	case "prune":
+		return pruneCmd(flag.Args()[1:])
+	case "heatmap":
+		return heatmapCmd(flag.Args()[1:])
+	case "words":
+		return wordsCmd(flag.Args()[1:])
+	case "translate":
+		return translateCmd(ctx, flag.Args()[1:])
+	case "search":
+		return searchCmd(flag.Args()[1:])
+	case "fetch-readmes":
+		return fetchReadmesCmd(ctx, flag.Args()[1:])
+	case "index":
+		return indexCmd(flag.Args()[1:])
+	case "auth":
+		return authCmd(flag.Args()[1:])
+	case "compare":
+		return compareCmd(ctx, flag.Args()[1:])
+	case "star":
+		return starCmd(ctx, flag.Args()[1:])
+	case "reason":
+		return reasonCmd(flag.Args()[1:])
+	case "dump":
+		return dumpCmd(flag.Args()[1:])
+	case "suggest-topics":
+		return suggestTopicsCmd(ctx, flag.Args()[1:])
+	case "push":
+		return pushCmd(ctx, flag.Args()[1:])
+	case "grab":
+		return grabCmd(ctx, flag.Args()[1:])
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
 	}
 }
 
-func addNewRepo(repo Repository, sess db.Session) error {
-	if getReadme {
-		readme, err := getReadmeContent(repo)
-		if err != nil {
-			logger.Warnf("Failed to fetch README for %s: %s", repo.FullName, err)
-		} else {
-			repo.Readme = sql.NullString{String: readme, Valid: true}
-		}
+// syncAndExport syncs the stars from GitHub and exports them when requested.
+// It's the default command.
+func syncAndExport(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		flags := flag.NewFlagSet("sync", flag.ExitOnError)
+		flags.Var(&syncOnly, "only", "Only store new stars matching owner:NAME, topic:NAME or language:NAME (repeatable)")
+		flags.BoolVar(&retryFailedFlag, "retry-failed", false, "Only re-attempt the pages and READMEs the previous syncs failed to fetch")
+		flags.BoolVar(&shardFlag, "shard", false, "Only sync the next shard of the star list, for accounts with too many stars to sync at once")
+		flags.IntVar(&shardBudget, "shard-budget", defaultShardBudget, "GraphQL rate limit points a shard may spend")
+		flags.IntVar(&shardReserve, "shard-reserve", defaultShardReserve, "Stop the shard when fewer GraphQL rate limit points are left")
+		flags.Parse(args[1:])
 	}
 
-	_, err := sess.Collection("starred_repos").Insert(repo)
-	if err == nil {
-		newStars++
+	for _, f := range syncOnly {
+		if f.kind == "readme-language" {
+			return errors.New("readme-language filters can't be used when syncing, READMEs are fetched after filtering")
+		}
+	}
+	if len(syncOnly) > 0 {
+		logger.Infof("Only storing new stars matching %s", syncOnly.String())
 	}
-	return err
-}
 
-func updateRepoReadme(r Repository, res db.Result) error {
-	logger.Debugf("Repository %s already exists in the database", r.FullName)
+	if getReadme {
+		logger.Info("Fetching READMEs enabled")
+	}
 
-	if r.Readme.Valid {
-		logger.Debug("README already exists")
-		return nil
+	if jsonFlag {
+		logger.Info("JSON export enabled")
 	}
 
-	logger.Debugf("Updating README for %s", r.FullName)
-	readme, err := getReadmeContent(r)
-	if err != nil {
-		logger.Warnf("Failed to fetch README for %s, ignoring: %s", r.FullName, err)
-		return nil
+	if batchSize < 1 || commitEvery < 1 {
+		return errors.New("--batch-size and --commit-every must be greater than zero")
 	}
 
-	r.Readme = sql.NullString{String: readme, Valid: true}
-	err = res.Update(r)
-	if err == nil {
-		logger.Debugf("Updated README for %s", r.FullName)
-		updatedStars++
+	if summaryJSON && jsonFlag {
+		return errors.New("--summary-json can't be combined with --json, both are written to stdout")
 	}
-	return err
-}
 
-func fetchAllStarredRepos(githubToken string, iterator func([]StarredRepo) error) error {
-	nextPageURL := "https://api.github.com/user/starred?per_page=100"
+	if skipUpdate && jsonFlag {
+		if _, err := os.Stat(dbFile); os.IsNotExist(err) {
+			return errors.New("database file not found, use the exporter without --skip-update at least once")
+		}
+	}
 
-	client := &http.Client{
-		Timeout: time.Second * 10,
+	sess, err := dbInit()
+	if err != nil {
+		return failed("opening database", err)
 	}
 
-	currentPage := 1
-	for nextPageURL != "" {
-		logger.Debugf("Page URL %s", nextPageURL)
-		req, err := http.NewRequest("GET", nextPageURL, nil)
+	if !skipUpdate {
+		gh, err := newGitHubClient()
 		if err != nil {
 			return err
 		}
-		req.Header.Set("Authorization", "Bearer "+githubToken)
-		req.Header.Set("Accept", "application/vnd.github.star+json")
-		//req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-		resp, err := client.Do(req)
+		initialized, err := hasStars(sess)
 		if err != nil {
-			return err
+			return failed("opening database", err)
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			return err
+		logger.Info("Fetching stars from github.com...")
+		started := time.Now()
+		syncer := newSyncer(gh, sess)
+		var shard *stars.Shard
+		switch {
+		case retryFailedFlag:
+			err = syncer.RetryFailed(ctx)
+		case shardFlag:
+			var s stars.Shard
+			s, err = syncShard(ctx, syncer, shardBudget, shardReserve)
+			shard = &s
+		default:
+			err = syncer.Sync(ctx)
 		}
-
-		var repos []StarredRepo
-		if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
-			return err
+		if rerr := syncer.SaveReport(started, err); rerr != nil {
+			logger.Warnf("Saving the error report: %s", rerr)
 		}
-
-		pagerLink := resp.Header.Get("Link")
-		nextPageURL = getNextPageURL(pagerLink)
-		pageCount := getPageCount(pagerLink)
-		if pageCount == "" {
-			pageCount = fmt.Sprintf("%d", currentPage)
+		stats := syncer.Stats()
+		summary.Sync = &syncSummary{Stats: stats, Shard: shard, DurationMS: time.Since(started).Milliseconds()}
+		for _, err := range stats.Errors {
+			summary.addError(err)
 		}
-		logger.Infof("Fetching stars... (page %d/%s)", currentPage, pageCount)
-
-		err = iterator(repos)
 		if err != nil {
-			return err
+			return failed("syncing stars", err)
 		}
-
-		currentPage++
-	}
-
-	return nil
-}
-
-// getNextPageURL parses the Link header from GitHub API response and finds the URL for the next page.
-func getNextPageURL(linkHeader string) string {
-	if linkHeader == "" {
-		return ""
-	}
-
-	links := strings.Split(linkHeader, ",")
-	for _, link := range links {
-		parts := strings.Split(link, ";")
-		if len(parts) == 2 && strings.TrimSpace(parts[1]) == `rel="next"` {
-			nextPageURL := strings.TrimSpace(parts[0])
-			return strings.Trim(nextPageURL, "<>")
+		logger.Infof("New stars: %d", stats.NewStars)
+		logger.Infof("Updated stars: %d", stats.UpdatedStars)
+		logger.Infof("Unstarred: %d", stats.Unstarred)
+
+		if initialized {
+			if err := publishEvents(stats.Events); err != nil {
+				logger.Warnf("Publishing events: %s", err)
+				summary.addError(err)
+			}
 		}
-	}
-
-	return ""
-}
 
-// getNextPageURL parses the Link header from GitHub API response and finds the URL for the next page.
-func getPageCount(linkHeader string) string {
-	if linkHeader == "" {
-		return ""
-	}
-
-	links := strings.Split(linkHeader, ",")
-	for _, link := range links {
-		parts := strings.Split(link, ";")
-		if len(parts) == 2 && strings.TrimSpace(parts[1]) == `rel="last"` {
-			nextPageURL := strings.TrimSpace(parts[0])
-			u, err := url.Parse(strings.Trim(nextPageURL, "<>"))
-			if err != nil {
-				return ""
+		if getFollowing {
+			if err := syncFollowing(ctx, gh, sess); err != nil {
+				return failed("syncing followed users", err)
 			}
-			return u.Query().Get("page")
 		}
+	} else {
+		logger.Info("Skipping update (offline mode)")
 	}
 
-	return ""
-}
-
-func getReadmeContent(repo Repository) (string, error) {
-	baseURL := fmt.Sprintf("https://api.github.com/repos/%s/contents/", repo.FullName)
-	client := &http.Client{Timeout: time.Second * 10}
-
-	for _, file := range readmeFiles {
-		req, err := http.NewRequest("GET", baseURL+file, nil)
+	if jsonFlag {
+		started := time.Now()
+		count, err := exportStars(sess, os.Stdout, "json", exportOptions{})
 		if err != nil {
-			return "", err
+			return failed("exporting to JSON", err)
+		}
+		summary.Export = &exportSummary{
+			Format:     "json",
+			Repos:      count,
+			DurationMS: time.Since(started).Milliseconds(),
 		}
+	}
+
+	summary.print()
 
-		req.Header.Set("Authorization", "Bearer "+token())
-		req.Header.Set("Accept", "application/vnd.github.v3.raw")
+	return nil
+}
 
-		resp, err := client.Do(req)
+// newGitHubClient returns a GitHub API client configured from the command
+// line flags.
+func newGitHubClient() (*githubclient.HTTPClient, error) {
+	var gh *githubclient.HTTPClient
+	if replayDir != "" {
+		// Replayed responses don't need a token.
+		gh = githubclient.New("")
+		gh.HTTP = &http.Client{Transport: &githubclient.Replayer{Dir: replayDir}}
+	} else {
+		token, err := token()
 		if err != nil {
-			continue
+			return nil, err
 		}
-		defer resp.Body.Close()
+		gh = githubclient.New(token)
+	}
+	if recordDir != "" {
+		gh.HTTP = &http.Client{Transport: &githubclient.Recorder{Dir: recordDir}}
+	}
+	// Replayed responses are answered from the recording only.
+	if dir := httpCacheDir(); dir != "" && replayDir == "" {
+		gh.HTTP = &http.Client{Transport: &githubclient.Cache{Dir: dir, Transport: gh.HTTP.Transport}}
+	}
+	gh.Timeout = httpTimeout
+	gh.Logger = httpLogger
+	if v := buildVersion(); v != "" {
+		gh.UserAgent = "gh-stars-exporter/" + v + " (+https://github.com/rubiojr/gh-stars-exporter)"
+	}
 
-		if resp.StatusCode == http.StatusOK {
-			content, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return "", err
-			}
-			return string(content), nil
-		}
+	cfg := config.GitHub
+	if cfg.BaseURL != "" {
+		gh.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	if cfg.UserAgent != "" {
+		gh.UserAgent = cfg.UserAgent
+	}
+	gh.Headers = http.Header{}
+	for name, value := range cfg.Headers {
+		gh.Headers.Set(name, value)
 	}
 
-	return "", fmt.Errorf("no readme found for %s", repo.FullName)
+	return gh, nil
 }
 
-func migrateDB() error {
-	d, err := iofs.New(fs, "db/migrations")
-	if err != nil {
-		return err
+// httpCacheDir returns the directory the GitHub API responses are cached
+// in: the --http-cache one, or the cache_dir of the [github] configuration.
+// Empty disables the cache.
+func httpCacheDir() string {
+	if httpCache != "" {
+		return expandHome(httpCache)
 	}
-	m, err := migrate.NewWithSourceInstance("iofs", d, fmt.Sprintf("sqlite3://%s", dbFile))
-	if err != nil {
-		return err
-	}
-	defer m.Close()
-	logger.Debug("Migrating database...")
+	return expandHome(config.GitHub.CacheDir)
+}
 
-	err = m.Up()
-	if err != nil && err != migrate.ErrNoChange {
-		return err
+// newSyncer returns a star syncer configured from the command line flags
+// and the configuration file.
+func newSyncer(gh githubclient.Client, sess db.Session) *stars.Syncer {
+	return stars.New(gh, sess, stars.Options{
+		Readmes:      getReadme,
+		StorePrivate: storePrivate,
+		Force:        forceSync,
+		Filter:       syncOnly.match,
+		Private:      config.Private,
+		Limits:       config.Limits,
+		Policy:       syncPolicy,
+		BatchSize:    batchSize,
+		CommitEvery:  commitEvery,
+		Logger:       logger,
+	})
+}
+
+// buildVersion returns the module version the binary was built from, or an
+// empty string for development builds.
+func buildVersion() string {
+	info, ok := runtimedebug.ReadBuildInfo()
+	if !ok || info.Main.Version == "(devel)" {
+		return ""
 	}
 
-	return nil
+	return info.Main.Version
 }
 
 var dbFile string
+var configFile string
+var policyPath string
 var debug bool
 var jsonFlag bool
 var storePrivate bool
 var skipUpdate bool
 var getReadme bool
+var batchSize int
+var commitEvery int
+var httpTimeout time.Duration
+var forceSync bool
+var summaryJSON bool
+var logLevel string
+var tzName string
+var getFollowing bool
+var syncOnly repoFilters
+var retryFailedFlag bool
+var shardFlag bool
+var shardBudget int
+var shardReserve int
+var httpCache string
+var recordDir string
+var replayDir string
+var pprofAddr string
+var cpuProfile string
+var memProfile string
 
 func init() {
 	flag.StringVar(&dbFile, "db", "data.ghstars", "Database file")
+	flag.StringVar(&configFile, "config", defaultConfigPath(), "Configuration file")
+	flag.StringVar(&policyPath, "policy", "", "Sync policy file, declaring what syncs fetch and keep")
 	flag.BoolVar(&debug, "debug", false, "Enable debug mode")
+	flag.StringVar(&logLevel, "log-level", "", "Log levels, global and/or per component (http, db), e.g. warn,http=debug")
+	flag.StringVar(&tzName, "tz", "", "Time zone dates are displayed in, e.g. Europe/Madrid (default: the local time zone)")
 	flag.BoolVar(&skipUpdate, "skip-update", false, "Do not update the database (offline, use existing data)")
 	flag.BoolVar(&jsonFlag, "json", false, "JSON Export to stdout")
 	flag.BoolVar(&getReadme, "get-readme", false, "JSON Export to stdout")
+	flag.BoolVar(&getFollowing, "get-following", false, "Also fetch the users you follow")
 	flag.BoolVar(&storePrivate, "store-private", false, "Store private starred repositories")
+	flag.IntVar(&batchSize, "batch-size", 1, "Number of new stars written per INSERT statement")
+	flag.IntVar(&commitEvery, "commit-every", 1, "Commit the database transaction every N new stars")
+	flag.BoolVar(&summaryJSON, "summary-json", false, "Print a JSON summary of the run to stdout")
+	flag.BoolVar(&forceSync, "force", false, "Walk the full star list even if it didn't change since the last sync")
+	flag.DurationVar(&httpTimeout, "http-timeout", 30*time.Second, "Timeout for each individual HTTP request")
+	flag.StringVar(&httpCache, "http-cache", "", "Cache the GitHub API responses in this directory, which concurrent runs can share")
+	flag.StringVar(&recordDir, "record", "", "Save the GitHub API responses to this directory")
+	flag.StringVar(&replayDir, "replay", "", "Answer the GitHub API requests with the responses saved with --record in this directory, offline")
+	flag.StringVar(&pprofAddr, "pprof", "", "Expose the pprof debug endpoint on this address (e.g. :6060)")
+	flag.StringVar(&cpuProfile, "profile", "", "Write a CPU profile to this file")
+	flag.StringVar(&memProfile, "mem-profile", "", "Write a heap profile to this file before exiting")
 }
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/export"
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+const (
+	notionAPI     = "https://api.notion.com/v1"
+	notionVersion = "2022-06-28"
+	// notionTextLength is the maximum length of a Notion rich text object.
+	notionTextLength = 2000
+	// notionMaxBlocks is the maximum number of blocks of a page body,
+	// the number Notion accepts per request.
+	notionMaxBlocks = 100
+)
+
+// stateNotionDatabase is the sync_state key of the Notion database created
+// by push notion.
+const stateNotionDatabase = "notion_database_id"
+
+// pushNotion keeps a Notion database with a page per public star, its
+// properties the name, URL, description, language, topics, stargazers and
+// starred date of the repository, and its body the README. Pages are
+// created for the new stars and updated when the repository changed, the
+// body only when the README changed. The database is created in the
+// configured page unless database_id is set.
+func pushNotion(ctx context.Context, sess db.Session, _ pushOptions) error {
+	cfg := config.Push.Notion
+	if cfg.Token == "" || (cfg.DatabaseID == "" && cfg.PageID == "") {
+		return fmt.Errorf("no Notion database configured, set token and database_id or page_id in the [push.notion] section of %s", configFile)
+	}
+
+	nc := &notionClient{client: newHTTPClient(httpTimeout), token: cfg.Token, base: notionAPI}
+	if cfg.BaseURL != "" {
+		nc.base = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	databaseID, err := notionDatabase(ctx, sess, nc, cfg)
+	if err != nil {
+		return err
+	}
+
+	var repos []Repository
+	err = sess.Collection("starred_repos").
+		Find(db.Cond{"source": sourceStarred, "unstarred_at IS": nil, "private": false}).
+		OrderBy("starred_at").
+		All(&repos)
+	if err != nil {
+		return err
+	}
+	pushed, err := pushedItems(sess, "notion")
+	if err != nil {
+		return err
+	}
+
+	created, updated := 0, 0
+	for _, r := range repos {
+		r.Topics = normalizeTopics(r.Topics, config.Topics.Aliases)
+		props := notionProperties(r)
+		hash, readmeHash := pushHash(props), pushHash(r.Readme.String)
+		item, ok := pushed[r.ID]
+		if ok && item.Hash == hash && item.ReadmeHash == readmeHash {
+			continue
+		}
+
+		var page struct {
+			ID string `json:"id"`
+		}
+		if ok {
+			page.ID = item.RemoteID
+			err = nc.do(ctx, "PATCH", "/pages/"+page.ID, map[string]any{"properties": props}, nil)
+			var serr *pushStatusError
+			if errors.As(err, &serr) && serr.Code == http.StatusNotFound {
+				logger.Debugf("The Notion page of %s is gone, creating it again", r.FullName)
+				ok = false
+			} else if err == nil && item.ReadmeHash != readmeHash {
+				err = nc.replaceChildren(ctx, page.ID, notionBlocks(r.Readme.String))
+			}
+		}
+		if !ok {
+			err = nc.do(ctx, "POST", "/pages", map[string]any{
+				"parent":     map[string]any{"database_id": databaseID},
+				"properties": props,
+				"children":   notionBlocks(r.Readme.String),
+			}, &page)
+		}
+		if err != nil {
+			return fmt.Errorf("pushing %s: %w", r.FullName, err)
+		}
+
+		if err := savePushed(sess, "notion", r.ID, pushedItem{RemoteID: page.ID, Hash: hash, ReadmeHash: readmeHash}); err != nil {
+			return err
+		}
+		if ok {
+			updated++
+		} else {
+			created++
+		}
+		logger.Debugf("Pushed %s", r.FullName)
+	}
+	logger.Infof("Notion pages created: %d, updated: %d", created, updated)
+
+	return nil
+}
+
+// notionDatabase returns the ID of the database the pages are pushed to:
+// the configured one, or the one created in the configured page by a
+// previous push, created now otherwise.
+func notionDatabase(ctx context.Context, sess db.Session, nc *notionClient, cfg NotionConfig) (string, error) {
+	if cfg.DatabaseID != "" {
+		return cfg.DatabaseID, nil
+	}
+	id, err := store.GetState(sess, stateNotionDatabase)
+	if err != nil || id != "" {
+		return id, err
+	}
+
+	var database struct {
+		ID string `json:"id"`
+	}
+	err = nc.do(ctx, "POST", "/databases", map[string]any{
+		"parent": map[string]any{"type": "page_id", "page_id": cfg.PageID},
+		"title":  notionText(export.DefaultFeedTitle),
+		"properties": map[string]any{
+			"Name":        map[string]any{"title": map[string]any{}},
+			"URL":         map[string]any{"url": map[string]any{}},
+			"Description": map[string]any{"rich_text": map[string]any{}},
+			"Language":    map[string]any{"select": map[string]any{}},
+			"Topics":      map[string]any{"multi_select": map[string]any{}},
+			"Stars":       map[string]any{"number": map[string]any{}},
+			"Starred":     map[string]any{"date": map[string]any{}},
+		},
+	}, &database)
+	if err != nil {
+		return "", fmt.Errorf("creating the Notion database: %w", err)
+	}
+	logger.Infof("Created the Notion database %s", database.ID)
+
+	return database.ID, store.SetState(sess, stateNotionDatabase, database.ID)
+}
+
+// notionProperties returns the database properties of the page of r.
+func notionProperties(r Repository) map[string]any {
+	props := map[string]any{
+		"Name":        map[string]any{"title": notionText(r.FullName)},
+		"URL":         map[string]any{"url": r.HTMLURL},
+		"Description": map[string]any{"rich_text": notionText(strings.TrimSpace(r.Description))},
+		"Stars":       map[string]any{"number": r.StargazersCount},
+		"Starred":     map[string]any{"date": map[string]any{"start": r.StarredAt.UTC().Format(time.RFC3339)}},
+	}
+	if r.Language != "" {
+		props["Language"] = map[string]any{"select": map[string]any{"name": r.Language}}
+	} else {
+		props["Language"] = map[string]any{"select": nil}
+	}
+	topics := []map[string]any{}
+	for _, t := range r.Topics {
+		if t != "" {
+			topics = append(topics, map[string]any{"name": t})
+		}
+	}
+	props["Topics"] = map[string]any{"multi_select": topics}
+
+	return props
+}
+
+// notionText returns s as Notion rich text, split in objects of at most
+// notionTextLength characters.
+func notionText(s string) []map[string]any {
+	text := []map[string]any{}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		n := min(len(runes), notionTextLength)
+		text = append(text, map[string]any{"type": "text", "text": map[string]any{"content": string(runes[:n])}})
+		runes = runes[n:]
+	}
+	return text
+}
+
+// notionBlocks converts a Markdown README to Notion blocks: headings, list
+// items, code blocks and paragraphs, other Markdown is kept as text. Only
+// the first notionMaxBlocks blocks are kept.
+func notionBlocks(readme string) []map[string]any {
+	blocks := []map[string]any{}
+	block := func(kind string, content map[string]any) {
+		blocks = append(blocks, map[string]any{"object": "block", "type": kind, kind: content})
+	}
+	var paragraph, code []string
+	inCode := false
+	flush := func() {
+		if len(paragraph) > 0 {
+			block("paragraph", map[string]any{"rich_text": notionText(strings.Join(paragraph, " "))})
+			paragraph = nil
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(readme, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			if inCode {
+				block("code", map[string]any{"language": "plain text", "rich_text": notionText(strings.Join(code, "\n"))})
+				code = nil
+			} else {
+				flush()
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			code = append(code, line)
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			kind := "heading_" + strconv.Itoa(min(level, 3))
+			block(kind, map[string]any{"rich_text": notionText(strings.TrimSpace(trimmed[level:]))})
+		case strings.HasPrefix(trimmed, "- "), strings.HasPrefix(trimmed, "* "):
+			flush()
+			block("bulleted_list_item", map[string]any{"rich_text": notionText(trimmed[2:])})
+		case numberedItem(trimmed) != "":
+			flush()
+			block("numbered_list_item", map[string]any{"rich_text": notionText(numberedItem(trimmed))})
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	if inCode && len(code) > 0 {
+		block("code", map[string]any{"language": "plain text", "rich_text": notionText(strings.Join(code, "\n"))})
+	}
+	flush()
+
+	if len(blocks) > notionMaxBlocks {
+		blocks = blocks[:notionMaxBlocks]
+	}
+	return blocks
+}
+
+// numberedItem returns the text of the numbered list item line, 1. text,
+// empty if it isn't one.
+func numberedItem(line string) string {
+	n, text, ok := strings.Cut(line, ". ")
+	if !ok || n == "" {
+		return ""
+	}
+	if _, err := strconv.Atoi(n); err != nil {
+		return ""
+	}
+	return text
+}
+
+// notionClient calls the Notion API.
+type notionClient struct {
+	client *http.Client
+	token  string
+	base   string
+}
+
+// do calls the API, decoding the response into v unless nil. Rate limited
+// requests are retried after the time requested.
+func (c *notionClient) do(ctx context.Context, method, path string, body, v any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	return retryPush(ctx, "Notion", time.Second, func() error {
+		req, err := http.NewRequestWithContext(ctx, method, c.base+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Notion-Version", notionVersion)
+		req.Header.Set("Content-Type", "application/json")
+
+		return doPush(c.client, req, v)
+	})
+}
+
+// replaceChildren replaces the blocks of the page body.
+func (c *notionClient) replaceChildren(ctx context.Context, pageID string, blocks []map[string]any) error {
+	var ids []string
+	cursor := ""
+	for {
+		path := "/blocks/" + pageID + "/children?page_size=100"
+		if cursor != "" {
+			path += "&start_cursor=" + cursor
+		}
+		var children struct {
+			Results []struct {
+				ID string `json:"id"`
+			} `json:"results"`
+			HasMore    bool   `json:"has_more"`
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := c.do(ctx, "GET", path, nil, &children); err != nil {
+			return err
+		}
+		for _, b := range children.Results {
+			ids = append(ids, b.ID)
+		}
+		if !children.HasMore {
+			break
+		}
+		cursor = children.NextCursor
+	}
+
+	for _, id := range ids {
+		if err := c.do(ctx, "DELETE", "/blocks/"+id, nil, nil); err != nil {
+			return err
+		}
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	return c.do(ctx, "PATCH", "/blocks/"+pageID+"/children", map[string]any{"children": blocks}, nil)
+}
+
+// pushedItem is a star pushed to a service keeping a copy of every star,
+// identified there by RemoteID.
+type pushedItem struct {
+	RepoID     int       `db:"repo_id"`
+	RemoteID   string    `db:"remote_id"`
+	Hash       string    `db:"hash"`
+	ReadmeHash string    `db:"readme_hash"`
+	PushedAt   time.Time `db:"pushed_at"`
+}
+
+// pushedItems returns the stars pushed to service, by repository ID.
+func pushedItems(sess db.Session, service string) (map[int]pushedItem, error) {
+	var items []pushedItem
+	if err := sess.Collection("pushed").Find(db.Cond{"service": service}).All(&items); err != nil {
+		return nil, err
+	}
+	byRepo := make(map[int]pushedItem, len(items))
+	for _, item := range items {
+		byRepo[item.RepoID] = item
+	}
+
+	return byRepo, nil
+}
+
+// savePushed records that the star repoID was pushed to service.
+func savePushed(sess db.Session, service string, repoID int, item pushedItem) error {
+	_, err := sess.SQL().Exec(
+		`INSERT INTO pushed (service, repo_id, remote_id, hash, readme_hash, pushed_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(service, repo_id) DO UPDATE SET remote_id = excluded.remote_id, hash = excluded.hash,
+		readme_hash = excluded.readme_hash, pushed_at = excluded.pushed_at`,
+		service, repoID, item.RemoteID, item.Hash, item.ReadmeHash, time.Now().UTC(),
+	)
+	return err
+}
+
+// pushHash returns a hash of the JSON encoding of v, to tell whether what
+// was pushed changed.
+func pushHash(v any) string {
+	b, _ := json.Marshal(v)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
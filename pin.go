@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/upper/db/v4"
+)
+
+// pinnedOrder sorts pinned repositories first, highest priority first.
+var pinnedOrder = []interface{}{"-pinned", "-pin_priority", "id"}
+
+// pinCmd pins the repositories passed as arguments, or lists the pinned ones
+// when none is given.
+func pinCmd(args []string) error {
+	flags := flag.NewFlagSet("pin", flag.ExitOnError)
+	priority := flags.Int("priority", 0, "Pin priority, higher priorities are listed first")
+	flags.Parse(args)
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	if flags.NArg() == 0 {
+		var pinned []Repository
+		err := sess.Collection("starred_repos").Find(db.Cond{"pinned": true}).OrderBy(pinnedOrder...).All(&pinned)
+		if err != nil {
+			return err
+		}
+		for _, r := range pinned {
+			fmt.Printf("%d\t%s\t%s\n", r.PinPriority, r.FullName, r.HTMLURL)
+		}
+		return nil
+	}
+
+	return setPinned(sess, flags.Args(), true, *priority)
+}
+
+// unpinCmd unpins the repositories passed as arguments.
+func unpinCmd(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("unpin requires at least one repository")
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	return setPinned(sess, args, false, 0)
+}
+
+func setPinned(sess db.Session, names []string, pinned bool, priority int) error {
+	for _, name := range names {
+		res, err := findRepo(sess, name)
+		if err != nil {
+			return err
+		}
+		var pinnedAt *time.Time
+		if pinned {
+			now := time.Now().UTC()
+			pinnedAt = &now
+		}
+		if err := res.Update(map[string]interface{}{"pinned": pinned, "pin_priority": priority, "pinned_at": pinnedAt}); err != nil {
+			return err
+		}
+
+		if pinned {
+			logger.Infof("Pinned %s", name)
+		} else {
+			logger.Infof("Unpinned %s", name)
+		}
+	}
+
+	return nil
+}
+
+// findRepo returns the stored repository identified by name, either
+// owner/name or a GitHub URL.
+func findRepo(sess db.Session, name string) (db.Result, error) {
+	fullName, err := parseRepoURL(name)
+	if err != nil {
+		return nil, err
+	}
+
+	res := sess.Collection("starred_repos").Find(db.Raw("full_name = ? COLLATE NOCASE", fullName))
+	exists, err := res.Exists()
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New(fullName + " is not in the database")
+	}
+
+	return res, nil
+}
@@ -0,0 +1,120 @@
+// Package events publishes messages to MQTT brokers and NATS servers. Only
+// what publishing needs is implemented: MQTT 3.1.1 with QoS 1 and the NATS
+// client protocol, each delivery being confirmed by the server.
+package events
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Publisher publishes messages to the topics (MQTT) or subjects (NATS) under
+// a prefix.
+type Publisher interface {
+	// Publish sends payload to the name topic under the prefix, waiting for
+	// the server to acknowledge it.
+	Publish(name string, payload []byte) error
+	Close() error
+}
+
+// Dial connects to the MQTT broker or NATS server at rawURL, one of
+// mqtt://, mqtts://, nats:// or tls:// (NATS over TLS). Credentials are
+// taken from the URL user info, a NATS token being the user alone.
+//
+// Messages are published to prefix/name on MQTT and prefix.name on NATS.
+func Dial(rawURL, prefix, clientID string, timeout time.Duration) (Publisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var useTLS bool
+	var port string
+	switch u.Scheme {
+	case "mqtt":
+		port = "1883"
+	case "mqtts":
+		port, useTLS = "8883", true
+	case "nats":
+		port = "4222"
+	case "tls":
+		port, useTLS = "4222", true
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q, expected mqtt, mqtts, nats or tls", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if useTLS && u.Scheme == "mqtts" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: u.Hostname()})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{conn: conn, r: bufio.NewReader(conn), timeout: timeout}
+	user := u.User.Username()
+	pass, _ := u.User.Password()
+	if u.Scheme == "mqtt" || u.Scheme == "mqtts" {
+		err = c.mqttConnect(clientID, user, pass)
+		c.pub = c.mqttPublish
+		c.close = c.mqttDisconnect
+		c.sep = "/"
+	} else {
+		err = c.natsConnect(clientID, user, pass, useTLS, u.Hostname())
+		c.pub = c.natsPublish
+		c.close = func() error { return nil }
+		c.sep = "."
+	}
+	if err != nil {
+		c.conn.Close()
+		return nil, fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	c.prefix = prefix
+
+	return c, nil
+}
+
+// client is a connection to an MQTT broker or a NATS server.
+type client struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+	prefix  string
+	// sep separates the prefix from the message name.
+	sep   string
+	pub   func(topic string, payload []byte) error
+	close func() error
+	// packetID is the last MQTT packet identifier used.
+	packetID uint16
+}
+
+func (c *client) Publish(name string, payload []byte) error {
+	topic := name
+	if c.prefix != "" {
+		topic = c.prefix + c.sep + name
+	}
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+
+	return c.pub(topic, payload)
+}
+
+func (c *client) Close() error {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	err := c.close()
+	if cerr := c.conn.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
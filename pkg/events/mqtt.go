@@ -0,0 +1,160 @@
+package events
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// MQTT 3.1.1 control packet types, shifted to the high nibble of the fixed
+// header.
+const (
+	mqttConnect    = 1 << 4
+	mqttConnack    = 2 << 4
+	mqttPublish    = 3 << 4
+	mqttPuback     = 4 << 4
+	mqttDisconnect = 14 << 4
+
+	mqttQoS1 = 1 << 1
+)
+
+// mqttKeepAlive is the keep alive interval sent to the broker, in seconds.
+const mqttKeepAlive = 60
+
+// mqttConnackErrors are the CONNACK return codes.
+var mqttConnackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+func (c *client) mqttConnect(clientID, user, pass string) error {
+	var flags byte = 0x02 // Clean session.
+	body := mqttString(nil, "MQTT")
+	body = append(body, 4) // Protocol level, 3.1.1.
+	if user != "" {
+		flags |= 0x80
+	}
+	if pass != "" {
+		flags |= 0x40
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, mqttKeepAlive)
+	body = mqttString(body, clientID)
+	if user != "" {
+		body = mqttString(body, user)
+	}
+	if pass != "" {
+		body = mqttString(body, pass)
+	}
+	if err := c.mqttWrite(mqttConnect, body); err != nil {
+		return err
+	}
+
+	typ, resp, err := c.mqttRead()
+	if err != nil {
+		return err
+	}
+	if typ != mqttConnack || len(resp) != 2 {
+		return fmt.Errorf("unexpected MQTT packet %#x, expected CONNACK", typ)
+	}
+	if code := resp[1]; code != 0 {
+		if msg, ok := mqttConnackErrors[code]; ok {
+			return fmt.Errorf("connection refused: %s", msg)
+		}
+		return fmt.Errorf("connection refused with code %d", code)
+	}
+
+	return nil
+}
+
+// mqttPublish publishes with QoS 1, waiting for the PUBACK.
+func (c *client) mqttPublish(topic string, payload []byte) error {
+	c.packetID++
+	if c.packetID == 0 {
+		c.packetID = 1
+	}
+
+	body := mqttString(nil, topic)
+	body = binary.BigEndian.AppendUint16(body, c.packetID)
+	body = append(body, payload...)
+	if err := c.mqttWrite(mqttPublish|mqttQoS1, body); err != nil {
+		return err
+	}
+
+	typ, resp, err := c.mqttRead()
+	if err != nil {
+		return err
+	}
+	if typ != mqttPuback || len(resp) != 2 || binary.BigEndian.Uint16(resp) != c.packetID {
+		return fmt.Errorf("unexpected MQTT packet %#x, expected PUBACK", typ)
+	}
+
+	return nil
+}
+
+func (c *client) mqttDisconnect() error {
+	return c.mqttWrite(mqttDisconnect, nil)
+}
+
+// mqttWrite writes a control packet: the fixed header with its variable
+// length encoding of the remaining length, followed by body.
+func (c *client) mqttWrite(header byte, body []byte) error {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	packet = append(packet, body...)
+
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+// mqttRead reads a control packet, returning its type and the rest of the
+// packet after the fixed header.
+func (c *client) mqttRead() (byte, []byte, error) {
+	header, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, fmt.Errorf("malformed MQTT packet length")
+		}
+		multiplier *= 128
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, nil, err
+	}
+
+	return header & 0xf0, body, nil
+}
+
+// mqttString appends s prefixed by its length.
+func mqttString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// natsInfo is the INFO the server sends upon connection.
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+// natsConnect reads the server INFO, upgrades the connection to TLS when
+// required and authenticates. A user without password is sent as token.
+func (c *client) natsConnect(name, user, pass string, useTLS bool, host string) error {
+	line, err := c.natsReadLine()
+	if err != nil {
+		return err
+	}
+	infoJSON, ok := strings.CutPrefix(line, "INFO ")
+	if !ok {
+		return fmt.Errorf("unexpected NATS message %q, expected INFO", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(infoJSON), &info); err != nil {
+		return fmt.Errorf("decoding NATS INFO: %w", err)
+	}
+
+	if useTLS || info.TLSRequired {
+		conn := tls.Client(c.conn, &tls.Config{ServerName: host})
+		if err := conn.Handshake(); err != nil {
+			return err
+		}
+		c.conn = conn
+		c.r = bufio.NewReader(conn)
+	}
+
+	connect := natsConnect{Name: name, Lang: "go", Version: "1.0.0", User: user, Pass: pass}
+	if pass == "" {
+		connect.User, connect.Token = "", user
+	}
+	b, err := json.Marshal(connect)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.conn, "CONNECT %s\r\n", b); err != nil {
+		return err
+	}
+
+	return c.natsPing()
+}
+
+func (c *client) natsPublish(subject string, payload []byte) error {
+	if _, err := fmt.Fprintf(c.conn, "PUB %s %d\r\n%s\r\n", subject, len(payload), payload); err != nil {
+		return err
+	}
+
+	// Errors are reported before the PONG of the next PING.
+	return c.natsPing()
+}
+
+// natsPing sends a PING and waits for its PONG, failing on the errors the
+// server reports in between.
+func (c *client) natsPing() error {
+	if _, err := fmt.Fprint(c.conn, "PING\r\n"); err != nil {
+		return err
+	}
+
+	for {
+		line, err := c.natsReadLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := fmt.Fprint(c.conn, "PONG\r\n"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS error: %s", strings.Trim(strings.TrimPrefix(line, "-ERR"), " '"))
+		}
+	}
+}
+
+func (c *client) natsReadLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,319 @@
+package export
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// Arrow IPC format constants, from the Schema.fbs and Message.fbs
+// definitions of the Arrow columnar format.
+const (
+	arrowMetadataV5 = 4
+
+	arrowHeaderSchema      = 1
+	arrowHeaderRecordBatch = 3
+
+	arrowTypeInt       = 2
+	arrowTypeUtf8      = 5
+	arrowTypeBool      = 6
+	arrowTypeTimestamp = 10
+	arrowTypeList      = 12
+
+	arrowMillisecond = 1
+)
+
+// arrowBatchSize is the number of stars of each record batch.
+const arrowBatchSize = 1024
+
+// arrowNode is the length and null count of an array in a record batch.
+type arrowNode struct {
+	length    int
+	nullCount int
+}
+
+// arrowColumn is a column of the Arrow export.
+type arrowColumn struct {
+	field *fbTable
+	// encode returns the nodes and buffers of the column values of the
+	// stars in the batch, in the order the IPC format expects them.
+	encode func(batch []*store.Repository) ([]arrowNode, [][]byte, error)
+}
+
+// arrowColumns are the columns of the Arrow export, the fields of the JSON
+// export with timestamps in milliseconds since the epoch, UTC.
+var arrowColumns = []arrowColumn{
+	int64Column("id", func(r *store.Repository) int64 { return int64(r.ID) }),
+	stringColumn("name", func(r *store.Repository) (string, bool) { return r.Name, true }),
+	stringColumn("full_name", func(r *store.Repository) (string, bool) { return r.FullName, true }),
+	stringColumn("html_url", func(r *store.Repository) (string, bool) { return r.HTMLURL, true }),
+	stringColumn("description", func(r *store.Repository) (string, bool) { return r.Description, true }),
+	stringColumn("homepage", func(r *store.Repository) (string, bool) { return r.Homepage, true }),
+	stringColumn("language", func(r *store.Repository) (string, bool) { return r.Language, true }),
+	stringListColumn("topics", func(r *store.Repository) []string { return r.Topics }),
+	int64Column("stargazers_count", func(r *store.Repository) int64 { return int64(r.StargazersCount) }),
+	boolColumn("is_template", func(r *store.Repository) bool { return r.IsTemplate }),
+	boolColumn("private", func(r *store.Repository) bool { return r.Private }),
+	boolColumn("archived", func(r *store.Repository) bool { return r.Archived }),
+	boolColumn("pinned", func(r *store.Repository) bool { return r.Pinned }),
+	stringColumn("source", func(r *store.Repository) (string, bool) { return r.Source, true }),
+	stringColumn("reason", func(r *store.Repository) (string, bool) { return r.Reason, true }),
+	timestampColumn("created_at", func(r *store.Repository) time.Time { return r.CreatedAt }),
+	timestampColumn("updated_at", func(r *store.Repository) time.Time { return r.UpdatedAt }),
+	timestampColumn("pushed_at", func(r *store.Repository) time.Time { return r.PushedAt }),
+	timestampColumn("starred_at", func(r *store.Repository) time.Time { return r.StarredAt }),
+	timestampColumn("unstarred_at", func(r *store.Repository) time.Time {
+		if r.UnstarredAt == nil {
+			return time.Time{}
+		}
+		return *r.UnstarredAt
+	}),
+	stringColumn("readme", func(r *store.Repository) (string, bool) { return r.Readme.String, r.Readme.Valid }),
+}
+
+// exportArrow writes an Arrow IPC stream: the schema followed by record
+// batches of up to arrowBatchSize stars.
+func exportArrow(w io.Writer, stars []*store.Repository, _ Options) error {
+	fields := make(fbTables, len(arrowColumns))
+	for i, c := range arrowColumns {
+		fields[i] = c.field
+	}
+	schema := &fbTable{fields: []fbValue{nil, fields}}
+	if err := writeArrowMessage(w, arrowHeaderSchema, schema, nil); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(stars); start += arrowBatchSize {
+		batch := stars[start:min(start+arrowBatchSize, len(stars))]
+		var nodes []arrowNode
+		var buffers [][]byte
+		for _, c := range arrowColumns {
+			n, b, err := c.encode(batch)
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, n...)
+			buffers = append(buffers, b...)
+		}
+		if err := writeRecordBatch(w, len(batch), nodes, buffers); err != nil {
+			return err
+		}
+	}
+
+	// End of stream marker.
+	_, err := w.Write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0})
+	return err
+}
+
+func writeRecordBatch(w io.Writer, length int, nodes []arrowNode, buffers [][]byte) error {
+	var nodeStructs, bufferStructs []byte
+	for _, n := range nodes {
+		nodeStructs = binary.LittleEndian.AppendUint64(nodeStructs, uint64(n.length))
+		nodeStructs = binary.LittleEndian.AppendUint64(nodeStructs, uint64(n.nullCount))
+	}
+	offset := 0
+	for _, b := range buffers {
+		bufferStructs = binary.LittleEndian.AppendUint64(bufferStructs, uint64(offset))
+		bufferStructs = binary.LittleEndian.AppendUint64(bufferStructs, uint64(len(b)))
+		offset += padded(len(b))
+	}
+
+	batch := &fbTable{fields: []fbValue{fbInt64(int64(length)), fbStructs(nodeStructs), fbStructs(bufferStructs)}}
+	return writeArrowMessage(w, arrowHeaderRecordBatch, batch, buffers)
+}
+
+// writeArrowMessage writes an encapsulated IPC message: the continuation
+// marker, the metadata length, the Message flatbuffer and the body, all
+// padded to 8 bytes.
+func writeArrowMessage(w io.Writer, headerType uint8, header *fbTable, body [][]byte) error {
+	bodyLength := 0
+	for _, b := range body {
+		bodyLength += padded(len(b))
+	}
+
+	msg := &fbTable{fields: []fbValue{
+		fbInt16(arrowMetadataV5),
+		fbUint8(headerType),
+		header,
+		fbInt64(int64(bodyLength)),
+	}}
+	meta := new(fbBuilder).finish(msg)
+	meta = append(meta, make([]byte, padded(len(meta))-len(meta))...)
+
+	prefix := binary.LittleEndian.AppendUint32(nil, 0xffffffff)
+	prefix = binary.LittleEndian.AppendUint32(prefix, uint32(len(meta)))
+	if _, err := w.Write(prefix); err != nil {
+		return err
+	}
+	if _, err := w.Write(meta); err != nil {
+		return err
+	}
+	for _, b := range body {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		if _, err := w.Write(make([]byte, padded(len(b))-len(b))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// padded rounds n up to a multiple of 8.
+func padded(n int) int {
+	return (n + 7) &^ 7
+}
+
+func arrowField(name string, nullable bool, typ uint8, typeTable *fbTable, children ...*fbTable) *fbTable {
+	return &fbTable{fields: []fbValue{
+		fbString(name),
+		fbBool(nullable),
+		fbUint8(typ),
+		typeTable,
+		nil,
+		fbTables(children),
+	}}
+}
+
+// bitmap is an Arrow bitmap, least significant bit first.
+type bitmap []byte
+
+func newBitmap(n int) bitmap {
+	return make(bitmap, (n+7)/8)
+}
+
+func (b bitmap) set(i int) {
+	b[i/8] |= 1 << (i % 8)
+}
+
+// validity returns the validity bitmap of an array, nil when there are no
+// nulls as the format allows.
+func validity(b bitmap, nullCount int) []byte {
+	if nullCount == 0 {
+		return nil
+	}
+	return b
+}
+
+func int64Column(name string, value func(*store.Repository) int64) arrowColumn {
+	return arrowColumn{
+		field: arrowField(name, false, arrowTypeInt, &fbTable{fields: []fbValue{fbInt32(64), fbBool(true)}}),
+		encode: func(batch []*store.Repository) ([]arrowNode, [][]byte, error) {
+			data := make([]byte, 8*len(batch))
+			for i, r := range batch {
+				binary.LittleEndian.PutUint64(data[8*i:], uint64(value(r)))
+			}
+			return []arrowNode{{length: len(batch)}}, [][]byte{nil, data}, nil
+		},
+	}
+}
+
+func boolColumn(name string, value func(*store.Repository) bool) arrowColumn {
+	return arrowColumn{
+		field: arrowField(name, false, arrowTypeBool, &fbTable{}),
+		encode: func(batch []*store.Repository) ([]arrowNode, [][]byte, error) {
+			data := newBitmap(len(batch))
+			for i, r := range batch {
+				if value(r) {
+					data.set(i)
+				}
+			}
+			return []arrowNode{{length: len(batch)}}, [][]byte{nil, data}, nil
+		},
+	}
+}
+
+// timestampColumn is a column of UTC timestamps, null for zero times.
+func timestampColumn(name string, value func(*store.Repository) time.Time) arrowColumn {
+	return arrowColumn{
+		field: arrowField(name, true, arrowTypeTimestamp, &fbTable{fields: []fbValue{fbInt16(arrowMillisecond), fbString("UTC")}}),
+		encode: func(batch []*store.Repository) ([]arrowNode, [][]byte, error) {
+			valid := newBitmap(len(batch))
+			nulls := 0
+			data := make([]byte, 8*len(batch))
+			for i, r := range batch {
+				t := value(r)
+				if t.IsZero() {
+					nulls++
+					continue
+				}
+				valid.set(i)
+				binary.LittleEndian.PutUint64(data[8*i:], uint64(t.UnixMilli()))
+			}
+			return []arrowNode{{length: len(batch), nullCount: nulls}}, [][]byte{validity(valid, nulls), data}, nil
+		},
+	}
+}
+
+// stringColumn is a column of strings, null when value returns false.
+func stringColumn(name string, value func(*store.Repository) (string, bool)) arrowColumn {
+	return arrowColumn{
+		field: arrowField(name, true, arrowTypeUtf8, &fbTable{}),
+		encode: func(batch []*store.Repository) ([]arrowNode, [][]byte, error) {
+			valid := newBitmap(len(batch))
+			nulls := 0
+			values := make([]string, len(batch))
+			for i, r := range batch {
+				v, ok := value(r)
+				if !ok {
+					nulls++
+					continue
+				}
+				valid.set(i)
+				values[i] = v
+			}
+			offsets, data, err := utf8Buffers(name, values)
+			if err != nil {
+				return nil, nil, err
+			}
+			return []arrowNode{{length: len(batch), nullCount: nulls}}, [][]byte{validity(valid, nulls), offsets, data}, nil
+		},
+	}
+}
+
+// stringListColumn is a column of lists of strings. Empty strings are left
+// out of the lists.
+func stringListColumn(name string, value func(*store.Repository) []string) arrowColumn {
+	item := arrowField("item", true, arrowTypeUtf8, &fbTable{})
+	return arrowColumn{
+		field: arrowField(name, true, arrowTypeList, &fbTable{}, item),
+		encode: func(batch []*store.Repository) ([]arrowNode, [][]byte, error) {
+			offsets := binary.LittleEndian.AppendUint32(nil, 0)
+			var items []string
+			for _, r := range batch {
+				for _, v := range value(r) {
+					if v != "" {
+						items = append(items, v)
+					}
+				}
+				offsets = binary.LittleEndian.AppendUint32(offsets, uint32(len(items)))
+			}
+			itemOffsets, data, err := utf8Buffers(name, items)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes := []arrowNode{{length: len(batch)}, {length: len(items)}}
+			return nodes, [][]byte{nil, offsets, nil, itemOffsets, data}, nil
+		},
+	}
+}
+
+// utf8Buffers returns the offsets and data buffers of a string array.
+func utf8Buffers(name string, values []string) ([]byte, []byte, error) {
+	offsets := binary.LittleEndian.AppendUint32(nil, 0)
+	var data []byte
+	for _, v := range values {
+		data = append(data, v...)
+		if len(data) > math.MaxInt32 {
+			return nil, nil, fmt.Errorf("column %s too large for an Arrow batch", name)
+		}
+		offsets = binary.LittleEndian.AppendUint32(offsets, uint32(len(data)))
+	}
+
+	return offsets, data, nil
+}
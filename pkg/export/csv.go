@@ -0,0 +1,147 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// CSVOptions modifies the csv export.
+type CSVOptions struct {
+	// Delimiter separates the fields, a comma when zero.
+	Delimiter rune
+	// CRLF ends the lines with \r\n instead of \n.
+	CRLF bool
+	// BOM starts the file with a UTF-8 byte order mark, which Excel needs
+	// to detect the encoding.
+	BOM bool
+}
+
+// ExcelCSV are the csv options opening in Excel in the locales using the
+// comma as decimal separator, with the non-ASCII characters intact.
+var ExcelCSV = CSVOptions{Delimiter: ';', CRLF: true, BOM: true}
+
+// utf8BOM is the UTF-8 byte order mark.
+const utf8BOM = "\ufeff"
+
+var csvHeader = []string{
+	"full_name", "html_url", "description", "language", "topics", "stargazers_count",
+	"created_at", "pushed_at", "starred_at", "archived", "homepage", "reason",
+}
+
+// csvFields are the columns csv exports can be restricted to with
+// Options.Fields: the default ones and a few more.
+var csvFields = append(slices.Clone(csvHeader),
+	"id", "name", "updated_at", "private", "is_template", "source", "pinned", "pin_priority",
+	"archived_at", "unstarred_at", "readme_language", "description_translated",
+)
+
+// exportCSV writes a row per star, without READMEs, which don't fit in a
+// spreadsheet cell. Topics are separated by spaces.
+func exportCSV(w io.Writer, stars []*store.Repository, opts Options) error {
+	if opts.CSV.BOM {
+		if _, err := io.WriteString(w, utf8BOM); err != nil {
+			return err
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if opts.CSV.Delimiter != 0 {
+		cw.Comma = opts.CSV.Delimiter
+	}
+	cw.UseCRLF = opts.CSV.CRLF
+	header := csvHeader
+	if len(opts.Fields) > 0 {
+		header = opts.Fields
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range stars {
+		record := make([]string, len(header))
+		for i, column := range header {
+			record[i] = csvValue(r, column, opts)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// csvValue returns the value of the column of r.
+func csvValue(r *store.Repository, column string, opts Options) string {
+	switch column {
+	case "id":
+		return strconv.Itoa(r.ID)
+	case "name":
+		return r.Name
+	case "full_name":
+		return r.FullName
+	case "html_url":
+		return r.HTMLURL
+	case "description":
+		return strings.Join(strings.Fields(r.Description), " ")
+	case "description_translated":
+		return strings.Join(strings.Fields(r.Translation), " ")
+	case "language":
+		return r.Language
+	case "readme_language":
+		return r.ReadmeLanguage
+	case "topics":
+		return strings.Join(feedTopics(r), " ")
+	case "stargazers_count":
+		return strconv.Itoa(r.StargazersCount)
+	case "created_at":
+		return csvTime(r.CreatedAt, opts.Location)
+	case "updated_at":
+		return csvTime(r.UpdatedAt, opts.Location)
+	case "pushed_at":
+		return csvTime(r.PushedAt, opts.Location)
+	case "starred_at":
+		return csvTime(r.StarredAt, opts.Location)
+	case "archived_at":
+		return csvTimePtr(r.ArchivedAt, opts.Location)
+	case "unstarred_at":
+		return csvTimePtr(r.UnstarredAt, opts.Location)
+	case "archived":
+		return strconv.FormatBool(r.Archived)
+	case "private":
+		return strconv.FormatBool(r.Private)
+	case "is_template":
+		return strconv.FormatBool(r.IsTemplate)
+	case "pinned":
+		return strconv.FormatBool(r.Pinned)
+	case "pin_priority":
+		return strconv.Itoa(r.PinPriority)
+	case "source":
+		return r.Source
+	case "homepage":
+		return r.Homepage
+	case "reason":
+		return r.Reason
+	}
+	return ""
+}
+
+func csvTimePtr(t *time.Time, loc *time.Location) string {
+	if t == nil {
+		return ""
+	}
+	return csvTime(*t, loc)
+}
+
+func csvTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return inLocation(t, loc).Format(time.RFC3339)
+}
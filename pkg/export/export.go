@@ -0,0 +1,545 @@
+// Package export writes starred repositories in the supported export
+// formats.
+package export
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// Options modifies how the stars are written.
+type Options struct {
+	// SSH makes clone URLs use SSH instead of HTTPS.
+	SSH bool
+	// NoReadme leaves the READMEs out of the export.
+	NoReadme bool
+	// ReadmeMaxBytes truncates the exported READMEs larger than this, 0
+	// exports them whole.
+	ReadmeMaxBytes int
+	// GroupBy groups Markdown exports by topic or language.
+	GroupBy string
+	// Compress compresses the export with gzip or zstd.
+	Compress string
+	// Location is the time zone of the dates in the RIS and CSL-JSON
+	// exports, UTC when nil. JSON exports always use UTC.
+	Location *time.Location
+	// Feed sets the metadata of the atom and rss exports.
+	Feed FeedOptions
+	// Calendar modifies the ics export.
+	Calendar CalendarOptions
+	// CSV sets the delimiter, line endings and BOM of the csv export.
+	CSV CSVOptions
+	// Fields restricts the json, ndjson, yaml and csv exports to these
+	// fields, in this order. Empty exports them all.
+	Fields []string
+	// Template is the Go text/template source of template exports.
+	Template string
+	// Freshness appends to the Markdown entries how long ago the metadata
+	// and README of the repositories were refreshed.
+	Freshness bool
+}
+
+// writers maps the export formats to the functions writing them.
+var writers = map[string]func(w io.Writer, stars []*store.Repository, opts Options) error{
+	"json":         exportJSON,
+	"ris":          exportRIS,
+	"csl-json":     exportCSLJSON,
+	"repos.txt":    exportReposTxt,
+	"clone-script": exportCloneScript,
+	"markdown":     exportMarkdown,
+	"ndjson":       exportNDJSON,
+	"arrow":        exportArrow,
+	"html":         exportHTML,
+	"yaml":         exportYAML,
+	"atom":         exportAtom,
+	"rss":          exportRSS,
+	"opml":         exportOPML,
+	"org":          exportOrg,
+	"hugo":         exportHugo,
+	"jekyll":       exportJekyll,
+	"ics":          exportICS,
+	"dot":          exportDOT,
+	"graphml":      exportGraphML,
+	"csv":          exportCSV,
+	"pocket":       exportPocket,
+	"pocket-csv":   exportPocketCSV,
+	"raindrop":     exportRaindrop,
+	"raindrop-csv": exportRaindropCSV,
+	"graveyard":    exportGraveyard,
+	"template":     exportTemplate,
+}
+
+// streamers maps the formats that can be written a star at a time to the
+// functions writing a star.
+var streamers = map[string]func(w io.Writer, r *store.Repository, opts Options) error{
+	"ndjson": writeNDJSONStar,
+}
+
+// Formats returns the supported export formats, sorted by name.
+func Formats() []string {
+	var formats []string
+	for f := range writers {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+// HasFormat reports whether format is a supported export format.
+func HasFormat(format string) bool {
+	_, ok := writers[format]
+	return ok
+}
+
+// CanStream reports whether format can be written with Stream.
+func CanStream(format string) bool {
+	_, ok := streamers[format]
+	return ok
+}
+
+// Write writes stars to w in the given format. READMEs are cleared from the
+// stars when opts.NoReadme is set, and truncated to opts.ReadmeMaxBytes.
+func Write(w io.Writer, format string, stars []*store.Repository, opts Options) error {
+	fn, ok := writers[format]
+	if !ok {
+		return fmt.Errorf("unknown export format %q", format)
+	}
+
+	for _, r := range stars {
+		LimitReadme(r, opts)
+	}
+
+	cw, err := compressor(w, opts.Compress)
+	if err != nil {
+		return err
+	}
+	if cw == nil {
+		return fn(w, stars, opts)
+	}
+
+	if err := fn(cw, stars, opts); err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+// Stream writes the stars returned by next to w one at a time, until next
+// returns io.EOF, so exports of very large collections don't need to hold
+// all of them in memory. Only the formats CanStream reports are supported.
+func Stream(w io.Writer, format string, next func() (*store.Repository, error), opts Options) error {
+	fn, ok := streamers[format]
+	if !ok {
+		return fmt.Errorf("export format %q can't be streamed", format)
+	}
+
+	cw, err := compressor(w, opts.Compress)
+	if err != nil {
+		return err
+	}
+	out := w
+	if cw != nil {
+		out = cw
+	}
+
+	for {
+		r, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		LimitReadme(r, opts)
+		if err := fn(out, r, opts); err != nil {
+			return err
+		}
+	}
+
+	if cw == nil {
+		return nil
+	}
+	return cw.Close()
+}
+
+// LimitReadme clears the README of r when opts.NoReadme is set, or
+// truncates it to opts.ReadmeMaxBytes, without cutting a multi-byte
+// character in half.
+func LimitReadme(r *store.Repository, opts Options) {
+	if opts.NoReadme {
+		r.Readme = store.NullString{}
+		return
+	}
+	if opts.ReadmeMaxBytes <= 0 || len(r.Readme.String) <= opts.ReadmeMaxBytes {
+		return
+	}
+
+	end := opts.ReadmeMaxBytes
+	for end > 0 && !utf8.RuneStart(r.Readme.String[end]) {
+		end--
+	}
+	r.Readme.String = r.Readme.String[:end]
+}
+
+// compressor returns a writer compressing to w with the given algorithm,
+// nil when compression is empty.
+func compressor(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case "":
+		return nil, nil
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "zstd":
+		return zstd.NewWriter(w)
+	}
+
+	return nil, fmt.Errorf("unknown compression %q", compression)
+}
+
+// MarkdownEntry returns the Markdown list item of a repository, linking to
+// it along with its description, language and the reason it was starred.
+func MarkdownEntry(r store.Repository) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "- [%s](%s)", r.FullName, r.HTMLURL)
+	if r.Description != "" {
+		fmt.Fprintf(&b, ": %s", strings.TrimSpace(r.Description))
+	}
+	if r.Language != "" {
+		fmt.Fprintf(&b, " (%s)", r.Language)
+	}
+	if r.Reason != "" {
+		fmt.Fprintf(&b, " — why: %s", r.Reason)
+	}
+
+	return b.String()
+}
+
+func exportJSON(w io.Writer, stars []*store.Repository, opts Options) error {
+	v, err := jsonStars(stars, opts)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+
+	return err
+}
+
+// exportRIS writes the stars as RIS computer program (COMP) references,
+// importable by Zotero and most reference managers. The access date (Y2) is
+// the date the repository was starred.
+func exportRIS(w io.Writer, stars []*store.Repository, opts Options) error {
+	for _, r := range stars {
+		owner, _, _ := strings.Cut(r.FullName, "/")
+		fields := [][2]string{
+			{"TY", "COMP"},
+			{"TI", r.FullName},
+			{"AU", owner},
+			{"AB", r.Description},
+			{"UR", r.HTMLURL},
+			{"PY", risYear(r.CreatedAt, opts.Location)},
+			{"Y2", risDate(r.StarredAt, opts.Location)},
+			{"PB", "GitHub"},
+			{"LA", r.ReadmeLanguage},
+			{"N1", r.Reason},
+		}
+		for _, topic := range r.Topics {
+			fields = append(fields, [2]string{"KW", topic})
+		}
+		fields = append(fields, [2]string{"ER", ""})
+
+		for _, f := range fields {
+			if f[1] == "" && f[0] != "ER" {
+				continue
+			}
+			line := strings.TrimSpace(strings.Join(strings.Fields(f[1]), " "))
+			if _, err := fmt.Fprintf(w, "%s  - %s\r\n", f[0], line); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func risYear(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return inLocation(t, loc).Format("2006")
+}
+
+func risDate(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return inLocation(t, loc).Format("2006/01/02")
+}
+
+// inLocation returns t in loc, or in UTC when loc is nil.
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t.UTC()
+	}
+	return t.In(loc)
+}
+
+// cslItem is a CSL-JSON item of type software.
+type cslItem struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Author    []cslAuthor `json:"author,omitempty"`
+	Abstract  string      `json:"abstract,omitempty"`
+	URL       string      `json:"URL"`
+	Issued    *cslDate    `json:"issued,omitempty"`
+	Accessed  *cslDate    `json:"accessed,omitempty"`
+	Keyword   string      `json:"keyword,omitempty"`
+	Language  string      `json:"language,omitempty"`
+	Note      string      `json:"note,omitempty"`
+	Publisher string      `json:"publisher"`
+}
+
+type cslAuthor struct {
+	Literal string `json:"literal"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+func newCSLDate(t time.Time, loc *time.Location) *cslDate {
+	if t.IsZero() {
+		return nil
+	}
+	t = inLocation(t, loc)
+	return &cslDate{DateParts: [][]int{{t.Year(), int(t.Month()), t.Day()}}}
+}
+
+// exportCSLJSON writes the stars as CSL-JSON software items, importable by
+// Zotero. The access date is the date the repository was starred.
+func exportCSLJSON(w io.Writer, stars []*store.Repository, opts Options) error {
+	items := []cslItem{}
+	for _, r := range stars {
+		owner, _, _ := strings.Cut(r.FullName, "/")
+		item := cslItem{
+			ID:        r.FullName,
+			Type:      "software",
+			Title:     r.FullName,
+			Abstract:  r.Description,
+			URL:       r.HTMLURL,
+			Issued:    newCSLDate(r.CreatedAt, opts.Location),
+			Accessed:  newCSLDate(r.StarredAt, opts.Location),
+			Keyword:   strings.Join(r.Topics, ", "),
+			Language:  r.ReadmeLanguage,
+			Note:      r.Reason,
+			Publisher: "GitHub",
+		}
+		if owner != "" {
+			item.Author = []cslAuthor{{Literal: owner}}
+		}
+		items = append(items, item)
+	}
+
+	b, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+
+	return err
+}
+
+// cloneURL returns the HTTPS or SSH clone URL of r, or an empty string when
+// the repository name is hashed.
+func cloneURL(r *store.Repository, ssh bool) string {
+	if r.HTMLURL == "" {
+		return ""
+	}
+	if ssh {
+		return "git@github.com:" + r.FullName + ".git"
+	}
+
+	return r.HTMLURL + ".git"
+}
+
+// exportReposTxt writes a clone URL per line.
+func exportReposTxt(w io.Writer, stars []*store.Repository, opts Options) error {
+	for _, r := range stars {
+		u := cloneURL(r, opts.SSH)
+		if u == "" {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, u); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+const cloneScriptHeader = `#!/bin/sh
+# Clones the starred repositories into owner/name directories under the
+# current directory, skipping the ones already cloned.
+set -e
+
+clone() {
+	if [ -d "$2" ]; then
+		echo "$2 already cloned"
+		return
+	fi
+	git clone "$1" "$2"
+}
+
+`
+
+// exportCloneScript writes a shell script cloning the repositories.
+func exportCloneScript(w io.Writer, stars []*store.Repository, opts Options) error {
+	if _, err := io.WriteString(w, cloneScriptHeader); err != nil {
+		return err
+	}
+
+	for _, r := range stars {
+		u := cloneURL(r, opts.SSH)
+		if u == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "clone '%s' '%s'\n", u, r.FullName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exportMarkdown writes a Markdown list of the stars, grouped in sections by
+// topic or language when requested.
+func exportMarkdown(w io.Writer, stars []*store.Repository, opts Options) error {
+	entry := func(r *store.Repository) string {
+		if opts.Freshness {
+			return MarkdownEntry(*r) + freshness(*r, time.Now())
+		}
+		return MarkdownEntry(*r)
+	}
+
+	if opts.GroupBy == "" {
+		for _, r := range stars {
+			if _, err := fmt.Fprintln(w, entry(r)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	groups := map[string][]*store.Repository{}
+	for _, r := range stars {
+		var keys []string
+		switch opts.GroupBy {
+		case "topic":
+			for _, t := range r.Topics {
+				if t != "" {
+					keys = append(keys, t)
+				}
+			}
+		case "language":
+			if r.Language != "" {
+				keys = []string{r.Language}
+			}
+		default:
+			return fmt.Errorf("can't group by %q, expected topic or language", opts.GroupBy)
+		}
+		if len(keys) == 0 {
+			keys = []string{"Other"}
+		}
+		for _, k := range keys {
+			groups[k] = append(groups[k], r)
+		}
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		if name != "Other" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := groups["Other"]; ok {
+		names = append(names, "Other")
+	}
+
+	for i, name := range names {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "## %s\n\n", name)
+		for _, r := range groups[name] {
+			if _, err := fmt.Fprintln(w, entry(r)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// freshness describes how long before now the metadata and README of r
+// were refreshed, "never" for the ones not refreshed since refreshes are
+// tracked.
+func freshness(r store.Repository, now time.Time) string {
+	s := " · refreshed " + age(r.RefreshedAt, now)
+	if r.ReadmeFetchedAt != nil {
+		s += ", README " + age(r.ReadmeFetchedAt, now)
+	}
+	return s
+}
+
+// age returns how long before now t is, in days, or "never" when nil.
+func age(t *time.Time, now time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	switch days := int(now.Sub(*t).Hours() / 24); days {
+	case 0:
+		return "today"
+	case 1:
+		return "1 day ago"
+	default:
+		return fmt.Sprintf("%d days ago", days)
+	}
+}
+
+// exportNDJSON writes a JSON object per star and line.
+func exportNDJSON(w io.Writer, stars []*store.Repository, opts Options) error {
+	for _, r := range stars {
+		if err := writeNDJSONStar(w, r, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeNDJSONStar(w io.Writer, r *store.Repository, opts Options) error {
+	if len(opts.Fields) == 0 {
+		return json.NewEncoder(w).Encode(r)
+	}
+	o, err := selectFields(r, opts.Fields)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", o)
+	return err
+}
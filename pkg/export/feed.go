@@ -0,0 +1,214 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// DefaultFeedItems is the number of stars of the atom and rss exports
+// unless FeedOptions.Items is set.
+const DefaultFeedItems = 50
+
+// DefaultFeedTitle is the title of the atom and rss exports unless
+// FeedOptions.Title is set.
+const DefaultFeedTitle = "Starred repositories"
+
+// FeedOptions sets the metadata of the atom and rss exports.
+type FeedOptions struct {
+	Title string
+	// Link is the URL the feed is published at.
+	Link   string
+	Author string
+	// Items is the number of most recent stars listed.
+	Items int
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Link       atomLink       `xml:"link"`
+	Author     atomAuthor     `xml:"author"`
+	Summary    string         `xml:"summary,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	Description string   `xml:"description,omitempty"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+}
+
+// feedStars returns the most recently starred public repositories, still
+// starred, most recent first.
+func feedStars(stars []*store.Repository, items int) []*store.Repository {
+	if items <= 0 {
+		items = DefaultFeedItems
+	}
+
+	var recent []*store.Repository
+	for _, r := range stars {
+		if !r.Private && r.UnstarredAt == nil {
+			recent = append(recent, r)
+		}
+	}
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].StarredAt.After(recent[j].StarredAt)
+	})
+
+	return recent[:min(items, len(recent))]
+}
+
+func feedTitle(opts FeedOptions) string {
+	if opts.Title == "" {
+		return DefaultFeedTitle
+	}
+	return opts.Title
+}
+
+// feedTopics returns the non-empty topics of r.
+func feedTopics(r *store.Repository) []string {
+	var topics []string
+	for _, t := range r.Topics {
+		if t != "" {
+			topics = append(topics, t)
+		}
+	}
+	return topics
+}
+
+// exportAtom writes an Atom feed of the most recent stars, for publishing
+// what's being starred.
+func exportAtom(w io.Writer, stars []*store.Repository, opts Options) error {
+	recent := feedStars(stars, opts.Feed.Items)
+
+	feed := atomFeed{
+		ID:      opts.Feed.Link,
+		Title:   feedTitle(opts.Feed),
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: opts.Feed.Author},
+	}
+	if feed.ID == "" {
+		feed.ID = "urn:gh-stars-exporter:stars"
+	} else {
+		feed.Link = []atomLink{{Href: opts.Feed.Link, Rel: "self"}}
+	}
+	if feed.Author.Name == "" {
+		feed.Author.Name = "gh-stars-exporter"
+	}
+	if len(recent) > 0 {
+		feed.Updated = recent[0].StarredAt.UTC().Format(time.RFC3339)
+	}
+
+	for _, r := range recent {
+		owner, _, _ := strings.Cut(r.FullName, "/")
+		entry := atomEntry{
+			ID:      r.HTMLURL,
+			Title:   r.FullName,
+			Updated: r.StarredAt.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: r.HTMLURL},
+			Author:  atomAuthor{Name: owner},
+			Summary: r.Description,
+		}
+		for _, t := range feedTopics(r) {
+			entry.Categories = append(entry.Categories, atomCategory{Term: t})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return writeXML(w, feed)
+}
+
+// exportRSS writes an RSS 2.0 feed of the most recent stars.
+func exportRSS(w io.Writer, stars []*store.Repository, opts Options) error {
+	recent := feedStars(stars, opts.Feed.Items)
+
+	channel := rssChannel{
+		Title:         feedTitle(opts.Feed),
+		Link:          opts.Feed.Link,
+		Description:   "Recently starred repositories",
+		LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+	}
+	if opts.Feed.Author != "" {
+		channel.Description = fmt.Sprintf("Repositories recently starred by %s", opts.Feed.Author)
+	}
+	if channel.Link == "" {
+		channel.Link = "https://github.com"
+	}
+	if len(recent) > 0 {
+		channel.LastBuildDate = recent[0].StarredAt.UTC().Format(time.RFC1123Z)
+	}
+
+	for _, r := range recent {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       r.FullName,
+			Link:        r.HTMLURL,
+			GUID:        r.HTMLURL,
+			Description: r.Description,
+			PubDate:     r.StarredAt.UTC().Format(time.RFC1123Z),
+			Categories:  feedTopics(r),
+		})
+	}
+
+	return writeXML(w, rssFeed{Version: "2.0", Channel: channel})
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,114 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// fieldFormats are the formats supporting Options.Fields.
+var fieldFormats = map[string]bool{"json": true, "ndjson": true, "yaml": true, "csv": true}
+
+// Fields returns the names of the fields of the stars format can be
+// restricted to with Options.Fields, nil when format doesn't support it.
+func Fields(format string) []string {
+	if !fieldFormats[format] {
+		return nil
+	}
+	if format == "csv" {
+		return csvFields
+	}
+
+	var names []string
+	t := reflect.TypeOf(store.Repository{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// CheckFields returns an error unless format can be restricted to fields.
+func CheckFields(format string, fields []string) error {
+	known := Fields(format)
+	if known == nil {
+		return fmt.Errorf("%s exports can't be restricted to some fields", format)
+	}
+	for _, f := range fields {
+		if !slices.Contains(known, f) {
+			return fmt.Errorf("unknown %s field %q, expected %s", format, f, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// selectFields returns the JSON object of r with the fields given only, in
+// their order, the ones left out of the JSON encoding when empty included.
+func selectFields(r *store.Repository, fields []string) (json.RawMessage, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(f)
+		buf.Write(name)
+		buf.WriteByte(':')
+		v, ok := all[f]
+		if !ok {
+			// Left out by omitempty, the zero value of the field.
+			if v, err = json.Marshal(jsonZero(f)); err != nil {
+				return nil, err
+			}
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// jsonZero returns the zero value of the Repository field with the JSON
+// name.
+func jsonZero(name string) any {
+	t := reflect.TypeOf(store.Repository{})
+	for i := 0; i < t.NumField(); i++ {
+		if n, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); n == name {
+			return reflect.Zero(t.Field(i).Type).Interface()
+		}
+	}
+	return nil
+}
+
+// jsonStars returns the stars to encode as JSON, restricted to
+// opts.Fields when set.
+func jsonStars(stars []*store.Repository, opts Options) (any, error) {
+	if len(opts.Fields) == 0 {
+		return stars, nil
+	}
+	objects := make([]json.RawMessage, len(stars))
+	for i, r := range stars {
+		o, err := selectFields(r, opts.Fields)
+		if err != nil {
+			return nil, err
+		}
+		objects[i] = o
+	}
+	return objects, nil
+}
@@ -0,0 +1,160 @@
+package export
+
+import "encoding/binary"
+
+// A minimal FlatBuffers encoder, enough to write the Arrow IPC metadata
+// without depending on the Arrow and FlatBuffers libraries.
+//
+// Unlike the reference builders, the buffer is written front to back: every
+// table is preceded by its vtable and followed by the strings, vectors and
+// tables it references, so offsets always point forward as the format
+// requires.
+
+// fbTable is a table, its fields indexed by id. Absent fields are nil.
+type fbTable struct {
+	fields []fbValue
+}
+
+// fbValue is one of fbScalar, fbString, fbTables, fbStructs or *fbTable.
+type fbValue interface{}
+
+// fbScalar is a little endian scalar field, aligned to its size.
+type fbScalar []byte
+
+// fbString is a string field.
+type fbString string
+
+// fbTables is a vector of tables.
+type fbTables []*fbTable
+
+// fbStructs is a vector of already encoded structs made of two 64-bit
+// fields, such as the Arrow FieldNode and Buffer.
+type fbStructs []byte
+
+func fbBool(v bool) fbScalar {
+	if v {
+		return fbScalar{1}
+	}
+	return fbScalar{0}
+}
+
+func fbUint8(v uint8) fbScalar {
+	return fbScalar{v}
+}
+
+func fbInt16(v int16) fbScalar {
+	return binary.LittleEndian.AppendUint16(nil, uint16(v))
+}
+
+func fbInt32(v int32) fbScalar {
+	return binary.LittleEndian.AppendUint32(nil, uint32(v))
+}
+
+func fbInt64(v int64) fbScalar {
+	return binary.LittleEndian.AppendUint64(nil, uint64(v))
+}
+
+type fbBuilder struct {
+	buf []byte
+}
+
+// finish returns the encoded buffer with root as its root table.
+func (b *fbBuilder) finish(root *fbTable) []byte {
+	b.buf = make([]byte, 4)
+	pos := b.table(root)
+	binary.LittleEndian.PutUint32(b.buf, uint32(pos))
+
+	return b.buf
+}
+
+func (b *fbBuilder) pad(align int) {
+	for len(b.buf)%align != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (b *fbBuilder) putUint16(pos int, v int) {
+	binary.LittleEndian.PutUint16(b.buf[pos:], uint16(v))
+}
+
+func (b *fbBuilder) putUint32(pos int, v int) {
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(v))
+}
+
+// table writes the vtable and the table t followed by the values it
+// references, returning the position of the table.
+func (b *fbBuilder) table(t *fbTable) int {
+	b.pad(2)
+	vtable := len(b.buf)
+	b.buf = append(b.buf, make([]byte, 4+2*len(t.fields))...)
+
+	b.pad(4)
+	start := len(b.buf)
+	b.buf = append(b.buf, 0, 0, 0, 0)
+	b.putUint32(start, start-vtable)
+
+	type ref struct {
+		pos   int
+		value fbValue
+	}
+	var refs []ref
+	for i, v := range t.fields {
+		if v == nil {
+			continue
+		}
+		if s, ok := v.(fbScalar); ok {
+			b.pad(len(s))
+			b.putUint16(vtable+4+2*i, len(b.buf)-start)
+			b.buf = append(b.buf, s...)
+			continue
+		}
+		b.pad(4)
+		b.putUint16(vtable+4+2*i, len(b.buf)-start)
+		refs = append(refs, ref{pos: len(b.buf), value: v})
+		b.buf = append(b.buf, 0, 0, 0, 0)
+	}
+	b.putUint16(vtable, 4+2*len(t.fields))
+	b.putUint16(vtable+2, len(b.buf)-start)
+
+	for _, r := range refs {
+		b.putUint32(r.pos, b.value(r.value)-r.pos)
+	}
+
+	return start
+}
+
+// value writes a value referenced by a table, returning its position.
+func (b *fbBuilder) value(v fbValue) int {
+	switch v := v.(type) {
+	case *fbTable:
+		return b.table(v)
+	case fbString:
+		b.pad(4)
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(v)))
+		b.buf = append(b.buf, v...)
+		b.buf = append(b.buf, 0)
+		return pos
+	case fbTables:
+		b.pad(4)
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(v)))
+		b.buf = append(b.buf, make([]byte, 4*len(v))...)
+		for i, t := range v {
+			elem := pos + 4 + 4*i
+			b.putUint32(elem, b.table(t)-elem)
+		}
+		return pos
+	case fbStructs:
+		// The structs are 8 byte aligned, after the 4 byte length.
+		for (len(b.buf)+4)%8 != 0 {
+			b.buf = append(b.buf, 0)
+		}
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(v)/16))
+		b.buf = append(b.buf, v...)
+		return pos
+	}
+
+	panic("flatbuffers: unsupported value")
+}
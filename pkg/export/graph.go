@@ -0,0 +1,180 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// Kinds of the nodes of the graph exports.
+const (
+	nodeRepo     = "repo"
+	nodeTopic    = "topic"
+	nodeLanguage = "language"
+)
+
+// graphNode is a repository, topic or language of the graph exports.
+type graphNode struct {
+	ID    string
+	Kind  string
+	Label string
+	// Weight is the stargazers of repositories, the number of stars tagged
+	// with topics and languages.
+	Weight int
+	URL    string
+}
+
+type graphEdge struct {
+	Source, Target string
+}
+
+// starGraph connects the stars to their topics and languages, the nodes
+// and edges in the order of the stars.
+type starGraph struct {
+	Nodes []*graphNode
+	Edges []graphEdge
+}
+
+func buildGraph(stars []*store.Repository) starGraph {
+	var g starGraph
+	nodes := map[string]*graphNode{}
+	node := func(kind, label string) *graphNode {
+		id := kind + ":" + label
+		if n, ok := nodes[id]; ok {
+			return n
+		}
+		n := &graphNode{ID: id, Kind: kind, Label: label}
+		nodes[id] = n
+		g.Nodes = append(g.Nodes, n)
+		return n
+	}
+
+	for _, r := range stars {
+		repo := node(nodeRepo, r.FullName)
+		repo.Weight, repo.URL = r.StargazersCount, r.HTMLURL
+		var linked []*graphNode
+		for _, t := range feedTopics(r) {
+			linked = append(linked, node(nodeTopic, strings.ToLower(t)))
+		}
+		if r.Language != "" {
+			linked = append(linked, node(nodeLanguage, r.Language))
+		}
+		seen := map[*graphNode]bool{}
+		for _, n := range linked {
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			n.Weight++
+			g.Edges = append(g.Edges, graphEdge{Source: repo.ID, Target: n.ID})
+		}
+	}
+
+	return g
+}
+
+// graphShapes are the Graphviz node shapes of each kind of node.
+var graphShapes = map[string]string{
+	nodeRepo:     "box",
+	nodeTopic:    "ellipse",
+	nodeLanguage: "diamond",
+}
+
+// exportDOT writes a Graphviz graph connecting the stars to their topics
+// and languages.
+func exportDOT(w io.Writer, stars []*store.Repository, _ Options) error {
+	g := buildGraph(stars)
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "graph stars {")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(bw, "  %s [label=%s, kind=%s, shape=%s, weight=%d", dotID(n.ID), dotID(n.Label), n.Kind, graphShapes[n.Kind], n.Weight)
+		if n.URL != "" {
+			fmt.Fprintf(bw, ", URL=%s", dotID(n.URL))
+		}
+		fmt.Fprintln(bw, "];")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "  %s -- %s;\n", dotID(e.Source), dotID(e.Target))
+	}
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+// dotID quotes s as a DOT identifier.
+func dotID(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+type graphmlDocument struct {
+	XMLName xml.Name     `xml:"http://graphml.graphdrawing.org/xmlns graphml"`
+	Keys    []graphmlKey `xml:"key"`
+	Graph   graphmlGraph `xml:"graph"`
+}
+
+type graphmlKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphmlGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+
+type graphmlNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphmlData `xml:"data"`
+}
+
+type graphmlEdge struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// exportGraphML writes a GraphML graph connecting the stars to their
+// topics and languages, with the node label, kind, weight and URL as
+// attributes, for Gephi, Cytoscape or yEd.
+func exportGraphML(w io.Writer, stars []*store.Repository, _ Options) error {
+	g := buildGraph(stars)
+	doc := graphmlDocument{
+		Keys: []graphmlKey{
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "kind", For: "node", Name: "kind", Type: "string"},
+			{ID: "weight", For: "node", Name: "weight", Type: "int"},
+			{ID: "url", For: "node", Name: "url", Type: "string"},
+		},
+		Graph: graphmlGraph{ID: "stars", EdgeDefault: "undirected"},
+	}
+	for _, n := range g.Nodes {
+		node := graphmlNode{ID: n.ID, Data: []graphmlData{
+			{Key: "label", Value: n.Label},
+			{Key: "kind", Value: n.Kind},
+			{Key: "weight", Value: strconv.Itoa(n.Weight)},
+		}}
+		if n.URL != "" {
+			node.Data = append(node.Data, graphmlData{Key: "url", Value: n.URL})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+	for _, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphmlEdge(e))
+	}
+
+	return writeXML(w, doc)
+}
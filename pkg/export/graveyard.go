@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// exportGraveyard writes a standalone Markdown document recording the stars
+// that are gone: the unstarred ones, which include the repositories deleted
+// or made private upstream, and the archived ones. Every entry keeps its
+// last known metadata and README, which may exist nowhere else anymore.
+// Other stars are left out.
+func exportGraveyard(w io.Writer, stars []*store.Repository, opts Options) error {
+	var unstarred, archived []*store.Repository
+	for _, r := range stars {
+		switch {
+		case r.UnstarredAt != nil:
+			unstarred = append(unstarred, r)
+		case r.Archived:
+			archived = append(archived, r)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Graveyard")
+	fmt.Fprintf(bw, "\n%d unstarred or deleted, %d archived. Generated on %s.\n",
+		len(unstarred), len(archived), inLocation(time.Now(), opts.Location).Format("2006-01-02"))
+
+	for _, section := range []struct {
+		title string
+		stars []*store.Repository
+	}{{"Unstarred or deleted", unstarred}, {"Archived", archived}} {
+		if len(section.stars) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "\n## %s\n", section.title)
+		for _, r := range section.stars {
+			writeGraveyardEntry(bw, r, opts)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func writeGraveyardEntry(w *bufio.Writer, r *store.Repository, opts Options) {
+	fmt.Fprintf(w, "\n### [%s](%s)\n\n", r.FullName, r.HTMLURL)
+	if d := strings.Join(strings.Fields(r.Description), " "); d != "" {
+		fmt.Fprintf(w, "%s\n\n", d)
+	}
+
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return inLocation(t, opts.Location).Format("2006-01-02")
+	}
+	fields := [][2]string{
+		{"Language", r.Language},
+		{"Topics", strings.Join(feedTopics(r), ", ")},
+		{"Homepage", r.Homepage},
+		{"Stars", strconv.Itoa(r.StargazersCount)},
+		{"Created", date(r.CreatedAt)},
+		{"Last pushed", date(r.PushedAt)},
+		{"Starred", date(r.StarredAt)},
+	}
+	if r.UnstarredAt != nil {
+		fields = append(fields, [2]string{"Unstarred", date(*r.UnstarredAt)})
+	}
+	if r.ArchivedAt != nil {
+		fields = append(fields, [2]string{"Archived", date(*r.ArchivedAt)})
+	} else if r.Archived {
+		fields = append(fields, [2]string{"Archived", "yes"})
+	}
+	fields = append(fields, [2]string{"Why", r.Reason})
+	for _, f := range fields {
+		if f[1] != "" {
+			fmt.Fprintf(w, "- %s: %s\n", f[0], f[1])
+		}
+	}
+
+	if readme := strings.TrimRight(r.Readme.String, "\n"); readme != "" {
+		fence := markdownFence(readme)
+		fmt.Fprintf(w, "\n<details><summary>README</summary>\n\n%smarkdown\n%s\n%s\n\n</details>\n", fence, readme, fence)
+	}
+}
+
+// markdownFence returns a code fence longer than the backtick runs in s, so
+// that s can't close it.
+func markdownFence(s string) string {
+	longest, run := 0, 0
+	for _, c := range s {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+
+	return strings.Repeat("`", max(3, longest+1))
+}
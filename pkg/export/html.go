@@ -0,0 +1,143 @@
+package export
+
+import (
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// htmlStar is a star of the HTML export.
+type htmlStar struct {
+	FullName    string
+	URL         string
+	Description string
+	Reason      string
+	Language    string
+	Topics      []string
+	Stars       int
+	StarredAt   string
+	Archived    bool
+	Readme      string
+}
+
+type htmlPage struct {
+	Stars     []htmlStar
+	Languages []string
+}
+
+// exportHTML writes a self-contained HTML page listing the stars, with a
+// search box and a language selector filtering them in the browser. Works
+// offline, no external resources are loaded.
+func exportHTML(w io.Writer, stars []*store.Repository, opts Options) error {
+	page := htmlPage{}
+	seen := map[string]bool{}
+	for _, r := range stars {
+		var topics []string
+		for _, t := range r.Topics {
+			if t != "" {
+				topics = append(topics, t)
+			}
+		}
+		page.Stars = append(page.Stars, htmlStar{
+			FullName:    r.FullName,
+			URL:         r.HTMLURL,
+			Description: r.Description,
+			Reason:      r.Reason,
+			Language:    r.Language,
+			Topics:      topics,
+			Stars:       r.StargazersCount,
+			StarredAt:   inLocation(r.StarredAt, opts.Location).Format("2006-01-02"),
+			Archived:    r.Archived,
+			Readme:      r.Readme.String,
+		})
+
+		if r.Language != "" && !seen[r.Language] {
+			seen[r.Language] = true
+			page.Languages = append(page.Languages, r.Language)
+		}
+	}
+	sort.Slice(page.Languages, func(i, j int) bool {
+		return strings.ToLower(page.Languages[i]) < strings.ToLower(page.Languages[j])
+	})
+
+	return htmlTemplate.Execute(w, page)
+}
+
+var htmlTemplate = template.Must(template.New("stars").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Starred repositories</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 60em; margin: 0 auto; padding: 1em; color: #1f2328; }
+header { position: sticky; top: 0; background: #fff; padding: .5em 0; border-bottom: 1px solid #d0d7de; }
+input, select { font-size: 1em; padding: .3em; }
+input { width: 60%; }
+li { list-style: none; margin: 1em 0; }
+ul { padding: 0; }
+a { color: #0969da; text-decoration: none; font-weight: 600; }
+.meta { color: #59636e; font-size: .9em; }
+.topic { background: #ddf4ff; border-radius: 1em; padding: 0 .5em; margin-right: .3em; font-size: .8em; }
+.reason { font-style: italic; }
+pre { white-space: pre-wrap; background: #f6f8fa; padding: .5em; max-height: 30em; overflow: auto; }
+</style>
+</head>
+<body>
+<header>
+<input id="search" type="search" placeholder="Search names, descriptions, topics and READMEs" autofocus>
+<select id="language">
+<option value="">All languages</option>
+{{- range .Languages}}
+<option>{{.}}</option>
+{{- end}}
+</select>
+<span id="count" class="meta">{{len .Stars}} stars</span>
+</header>
+<ul id="stars">
+{{- range .Stars}}
+<li data-language="{{.Language}}">
+<a href="{{.URL}}">{{.FullName}}</a>{{if .Archived}} <span class="meta">(archived)</span>{{end}}
+<div>{{.Description}}</div>
+{{- if .Reason}}
+<div class="reason">Why: {{.Reason}}</div>
+{{- end}}
+<div class="meta">{{if .Language}}{{.Language}} · {{end}}★ {{.Stars}} · starred {{.StarredAt}}</div>
+{{- if .Topics}}
+<div>{{range .Topics}}<span class="topic">{{.}}</span>{{end}}</div>
+{{- end}}
+{{- if .Readme}}
+<details><summary class="meta">README</summary><pre>{{.Readme}}</pre></details>
+{{- end}}
+</li>
+{{- end}}
+</ul>
+<script>
+const search = document.getElementById("search");
+const language = document.getElementById("language");
+const count = document.getElementById("count");
+const stars = Array.from(document.querySelectorAll("#stars > li"));
+const texts = stars.map(li => li.textContent.toLowerCase());
+
+function filter() {
+  const words = search.value.toLowerCase().split(/\s+/).filter(w => w);
+  const lang = language.value;
+  let shown = 0;
+  stars.forEach((li, i) => {
+    const match = (!lang || li.dataset.language === lang) &&
+      words.every(w => texts[i].includes(w));
+    li.hidden = !match;
+    if (match) shown++;
+  });
+  count.textContent = shown + " stars";
+}
+
+search.addEventListener("input", filter);
+language.addEventListener("change", filter);
+</script>
+</body>
+</html>
+`))
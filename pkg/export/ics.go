@@ -0,0 +1,104 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// CalendarOptions modifies the ics export.
+type CalendarOptions struct {
+	// Yearly makes the events recur on every anniversary of the star, for
+	// "one year ago you starred" reminders.
+	Yearly bool
+}
+
+// icsLineLength is the maximum length of iCalendar content lines, in bytes,
+// longer ones are folded.
+const icsLineLength = 75
+
+// exportICS writes an iCalendar file with an all-day event per star, on
+// the day it was starred, named after the repository.
+func exportICS(w io.Writer, stars []*store.Repository, opts Options) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		icsLine(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//gh-stars-exporter//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("X-WR-CALNAME", icsEscape(DefaultFeedTitle))
+
+	for _, r := range stars {
+		if r.StarredAt.IsZero() {
+			continue
+		}
+		day := inLocation(r.StarredAt, opts.Location)
+		start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+		line("BEGIN", "VEVENT")
+		line("UID", fmt.Sprintf("%d-starred@gh-stars-exporter", r.ID))
+		line("DTSTAMP", r.StarredAt.UTC().Format("20060102T150405Z"))
+		line("DTSTART;VALUE=DATE", start.Format("20060102"))
+		line("DTEND;VALUE=DATE", start.AddDate(0, 0, 1).Format("20060102"))
+		if opts.Calendar.Yearly {
+			line("RRULE", "FREQ=YEARLY")
+		}
+		line("SUMMARY", icsEscape(r.FullName))
+		line("URL", r.HTMLURL)
+
+		var description []string
+		if d := strings.TrimSpace(r.Description); d != "" {
+			description = append(description, d)
+		}
+		if r.Reason != "" {
+			description = append(description, "Why: "+r.Reason)
+		}
+		description = append(description, r.HTMLURL)
+		line("DESCRIPTION", icsEscape(strings.Join(description, "\n")))
+
+		if topics := feedTopics(r); len(topics) > 0 {
+			escaped := make([]string, len(topics))
+			for i, t := range topics {
+				escaped[i] = icsEscape(t)
+			}
+			line("CATEGORIES", strings.Join(escaped, ","))
+		}
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+
+	return bw.Flush()
+}
+
+// icsEscape escapes the iCalendar TEXT value s.
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// icsLine writes the content line s, CRLF terminated and folded in lines
+// of at most icsLineLength bytes without splitting UTF-8 sequences.
+func icsLine(w *bufio.Writer, s string) {
+	limit := icsLineLength
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// The leading space of continuation lines counts.
+		limit = icsLineLength - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,58 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+type opmlDocument struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Head    opmlHead      `xml:"head"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated"`
+}
+
+type opmlOutline struct {
+	Type    string `xml:"type,attr"`
+	Text    string `xml:"text,attr"`
+	Title   string `xml:"title,attr"`
+	XMLURL  string `xml:"xmlUrl,attr"`
+	HTMLURL string `xml:"htmlUrl,attr"`
+}
+
+// exportOPML writes an OPML subscription list with the releases Atom feed
+// of every starred repository, to follow their releases from a feed reader,
+// titled with the feed title. Private repositories are left out, their feeds
+// need authentication, and so are the unstarred ones.
+func exportOPML(w io.Writer, stars []*store.Repository, opts Options) error {
+	doc := opmlDocument{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:       feedTitle(opts.Feed),
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	for _, r := range stars {
+		if r.Private || r.UnstarredAt != nil {
+			continue
+		}
+		doc.Body = append(doc.Body, opmlOutline{
+			Type:    "rss",
+			Text:    r.FullName,
+			Title:   r.FullName,
+			XMLURL:  r.HTMLURL + "/releases.atom",
+			HTMLURL: r.HTMLURL,
+		})
+	}
+
+	return writeXML(w, doc)
+}
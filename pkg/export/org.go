@@ -0,0 +1,116 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// exportOrg writes an Emacs Org file with a heading per star, tagged with
+// its topics, the metadata in its properties drawer and the README in a
+// Markdown source block as its body.
+func exportOrg(w io.Writer, stars []*store.Repository, opts Options) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "#+TITLE: %s\n", DefaultFeedTitle)
+
+	for _, r := range stars {
+		fmt.Fprintf(bw, "\n* [[%s][%s]]", r.HTMLURL, r.FullName)
+		if tags := orgTags(r.Topics); tags != "" {
+			fmt.Fprintf(bw, " %s", tags)
+		}
+		fmt.Fprintln(bw)
+
+		properties := [][2]string{
+			{"FULL_NAME", r.FullName},
+			{"URL", r.HTMLURL},
+			{"HOMEPAGE", r.Homepage},
+			{"LANGUAGE", r.Language},
+			{"STARS", strconv.Itoa(r.StargazersCount)},
+			{"CREATED", orgDate(r.CreatedAt, opts.Location)},
+			{"STARRED", orgDate(r.StarredAt, opts.Location)},
+			{"SOURCE", r.Source},
+			{"REASON", r.Reason},
+		}
+		if r.UnstarredAt != nil {
+			properties = append(properties, [2]string{"UNSTARRED", orgDate(*r.UnstarredAt, opts.Location)})
+		}
+		if r.Archived {
+			properties = append(properties, [2]string{"ARCHIVED", "t"})
+		}
+		if r.Pinned {
+			properties = append(properties, [2]string{"PINNED", strconv.Itoa(r.PinPriority)})
+		}
+		fmt.Fprintln(bw, ":PROPERTIES:")
+		for _, p := range properties {
+			if v := strings.Join(strings.Fields(p[1]), " "); v != "" {
+				fmt.Fprintf(bw, ":%s: %s\n", p[0], v)
+			}
+		}
+		fmt.Fprintln(bw, ":END:")
+
+		if description := strings.Join(strings.Fields(r.Description), " "); description != "" {
+			// A leading asterisk would start a heading.
+			if strings.HasPrefix(description, "*") {
+				description = " " + description
+			}
+			fmt.Fprintln(bw, description)
+		}
+
+		if r.Readme.String != "" {
+			fmt.Fprintln(bw, "#+BEGIN_SRC markdown")
+			for _, line := range strings.Split(strings.TrimRight(r.Readme.String, "\n"), "\n") {
+				fmt.Fprintln(bw, orgEscape(line))
+			}
+			fmt.Fprintln(bw, "#+END_SRC")
+		}
+	}
+
+	return bw.Flush()
+}
+
+// orgTags returns the topics as Org tags, :topic1:topic2:. Tags can only
+// contain letters, numbers, _ and @, other characters become _.
+func orgTags(topics []string) string {
+	var tags []string
+	for _, t := range topics {
+		if t == "" {
+			continue
+		}
+		tags = append(tags, strings.Map(func(r rune) rune {
+			if r == '_' || r == '@' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return '_'
+		}, t))
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+
+	return ":" + strings.Join(tags, ":") + ":"
+}
+
+// orgDate formats t as an inactive Org timestamp, [2024-08-12 Mon].
+func orgDate(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return inLocation(t, loc).Format("[2006-01-02 Mon]")
+}
+
+// orgEscape escapes a line of a source block the way Org does, prefixing a
+// comma to the lines that would be read as headings or keywords.
+func orgEscape(line string) string {
+	rest := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(rest)]
+	unescaped := strings.TrimLeft(rest, ",")
+	if indent == "" && strings.HasPrefix(unescaped, "*") || strings.HasPrefix(unescaped, "#+") {
+		return indent + "," + rest
+	}
+	return line
+}
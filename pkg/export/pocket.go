@@ -0,0 +1,91 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// pocketTitle is the title of a star in the Pocket exports, the repository
+// name followed by its description.
+func pocketTitle(r *store.Repository) string {
+	d := strings.Join(strings.Fields(r.Description), " ")
+	if d == "" {
+		return r.FullName
+	}
+	return r.FullName + ": " + d
+}
+
+// pocketArchived reports whether r belongs to the archive of the Pocket
+// exports rather than to the reading list: unstarred stars.
+func pocketArchived(r *store.Repository) bool {
+	return r.UnstarredAt != nil
+}
+
+// exportPocket writes the stars in the HTML format of Pocket exports, which
+// Pocket, Instapaper and most read-later services import: a link per star,
+// added when starred and tagged with its topics. Unstarred stars are listed
+// in the archive.
+func exportPocket(w io.Writer, stars []*store.Repository, _ Options) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "<!DOCTYPE html>")
+	fmt.Fprintln(bw, "<html>")
+	fmt.Fprintln(bw, `<head><meta http-equiv="Content-Type" content="text/html; charset=UTF-8" /><title>Pocket Export</title></head>`)
+	fmt.Fprintln(bw, "<body>")
+
+	for _, section := range []struct {
+		title    string
+		archived bool
+	}{{"Unread", false}, {"Read Archive", true}} {
+		fmt.Fprintf(bw, "<h1>%s</h1>\n<ul>\n", section.title)
+		for _, r := range stars {
+			if pocketArchived(r) != section.archived {
+				continue
+			}
+			fmt.Fprintf(bw, "<li><a href=\"%s\" time_added=\"%d\" tags=\"%s\">%s</a></li>\n",
+				html.EscapeString(r.HTMLURL), r.StarredAt.Unix(),
+				html.EscapeString(strings.Join(feedTopics(r), ",")),
+				html.EscapeString(pocketTitle(r)))
+		}
+		fmt.Fprintln(bw, "</ul>")
+	}
+	fmt.Fprintln(bw, "</body>")
+	fmt.Fprintln(bw, "</html>")
+
+	return bw.Flush()
+}
+
+// exportPocketCSV writes the stars in the CSV format of Pocket exports, the
+// topics as tags separated by |.
+func exportPocketCSV(w io.Writer, stars []*store.Repository, _ Options) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"title", "url", "time_added", "tags", "status"}); err != nil {
+		return err
+	}
+
+	for _, r := range stars {
+		status := "unread"
+		if pocketArchived(r) {
+			status = "archive"
+		}
+		record := []string{
+			pocketTitle(r),
+			r.HTMLURL,
+			strconv.FormatInt(r.StarredAt.Unix(), 10),
+			strings.Join(feedTopics(r), "|"),
+			status,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
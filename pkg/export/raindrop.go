@@ -0,0 +1,98 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// raindropOther is the collection of the stars without a language in the
+// Raindrop exports.
+const raindropOther = "Other"
+
+// raindropCollection is the Raindrop collection of r, named after its
+// language.
+func raindropCollection(r *store.Repository) string {
+	if r.Language == "" {
+		return raindropOther
+	}
+	return r.Language
+}
+
+// exportRaindropCSV writes the stars in the CSV format Raindrop.io imports:
+// a bookmark per star, in a collection per language, tagged with its topics
+// and with the reason it was starred as note.
+func exportRaindropCSV(w io.Writer, stars []*store.Repository, _ Options) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"url", "folder", "title", "description", "note", "tags", "created"}); err != nil {
+		return err
+	}
+
+	for _, r := range stars {
+		record := []string{
+			r.HTMLURL,
+			raindropCollection(r),
+			r.FullName,
+			strings.Join(strings.Fields(r.Description), " "),
+			r.Reason,
+			strings.Join(feedTopics(r), ","),
+			r.StarredAt.UTC().Format(time.RFC3339),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// exportRaindrop writes the stars as a Netscape bookmarks file, which
+// Raindrop.io imports with a collection per folder: a folder per language,
+// sorted by name with the stars without a language last, and the topics as
+// tags.
+func exportRaindrop(w io.Writer, stars []*store.Repository, _ Options) error {
+	collections := map[string][]*store.Repository{}
+	var names []string
+	for _, r := range stars {
+		c := raindropCollection(r)
+		if _, ok := collections[c]; !ok && c != raindropOther {
+			names = append(names, c)
+		}
+		collections[c] = append(collections[c], r)
+	}
+	sort.Strings(names)
+	if _, ok := collections[raindropOther]; ok {
+		names = append(names, raindropOther)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "<!DOCTYPE NETSCAPE-Bookmark-file-1>")
+	fmt.Fprintln(bw, `<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">`)
+	fmt.Fprintln(bw, "<TITLE>Bookmarks</TITLE>")
+	fmt.Fprintln(bw, "<H1>Bookmarks</H1>")
+	fmt.Fprintln(bw, "<DL><p>")
+	for _, name := range names {
+		fmt.Fprintf(bw, "    <DT><H3>%s</H3>\n    <DL><p>\n", html.EscapeString(name))
+		for _, r := range collections[name] {
+			fmt.Fprintf(bw, "        <DT><A HREF=\"%s\" ADD_DATE=\"%d\" TAGS=\"%s\">%s</A>\n",
+				html.EscapeString(r.HTMLURL), r.StarredAt.Unix(),
+				html.EscapeString(strings.Join(feedTopics(r), ",")),
+				html.EscapeString(r.FullName))
+			if d := strings.Join(strings.Fields(r.Description), " "); d != "" {
+				fmt.Fprintf(bw, "        <DD>%s\n", html.EscapeString(d))
+			}
+		}
+		fmt.Fprintln(bw, "    </DL><p>")
+	}
+	fmt.Fprintln(bw, "</DL><p>")
+
+	return bw.Flush()
+}
@@ -0,0 +1,196 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"gopkg.in/yaml.v3"
+)
+
+// siteData is the data file of the hugo and jekyll exports, shaped for
+// static site templates: the stars plus the languages and topics with their
+// slugs and counts, to render a page per language or topic.
+type siteData struct {
+	GeneratedAt time.Time  `json:"generated_at"`
+	Count       int        `json:"count"`
+	Languages   []siteTerm `json:"languages"`
+	Topics      []siteTerm `json:"topics"`
+	Stars       []siteStar `json:"stars"`
+}
+
+type siteTerm struct {
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Count int    `json:"count"`
+}
+
+type siteStar struct {
+	FullName     string   `json:"full_name"`
+	Owner        string   `json:"owner"`
+	Name         string   `json:"name"`
+	Slug         string   `json:"slug"`
+	URL          string   `json:"url"`
+	Homepage     string   `json:"homepage,omitempty"`
+	Description  string   `json:"description"`
+	Language     string   `json:"language"`
+	LanguageSlug string   `json:"language_slug"`
+	Topics       []string `json:"topics"`
+	TopicSlugs   []string `json:"topic_slugs"`
+	Stars        int      `json:"stars"`
+	CreatedAt    string   `json:"created_at"`
+	PushedAt     string   `json:"pushed_at"`
+	StarredAt    string   `json:"starred_at"`
+	Archived     bool     `json:"archived"`
+	Pinned       bool     `json:"pinned"`
+	Reason       string   `json:"reason,omitempty"`
+}
+
+// DataFile returns the conventional path of the data file of the static
+// site generator formats in the site directory, empty for other formats.
+func DataFile(format string) string {
+	switch format {
+	case "hugo":
+		return "data/stars.yaml"
+	case "jekyll":
+		return "_data/stars.json"
+	}
+	return ""
+}
+
+func buildSiteData(stars []*store.Repository, opts Options) siteData {
+	data := siteData{GeneratedAt: time.Now().UTC().Truncate(time.Second), Stars: []siteStar{}}
+	languages := map[string]*siteTerm{}
+	topics := map[string]*siteTerm{}
+	count := func(terms map[string]*siteTerm, name string) string {
+		slug := Slugify(name)
+		t, ok := terms[slug]
+		if !ok {
+			t = &siteTerm{Name: name, Slug: slug}
+			terms[slug] = t
+		}
+		t.Count++
+		return slug
+	}
+
+	for _, r := range stars {
+		owner, name, _ := strings.Cut(r.FullName, "/")
+		s := siteStar{
+			FullName:    r.FullName,
+			Owner:       owner,
+			Name:        name,
+			Slug:        Slugify(r.FullName),
+			URL:         r.HTMLURL,
+			Homepage:    r.Homepage,
+			Description: strings.TrimSpace(r.Description),
+			Language:    r.Language,
+			Topics:      []string{},
+			TopicSlugs:  []string{},
+			Stars:       r.StargazersCount,
+			CreatedAt:   siteTime(r.CreatedAt, opts.Location),
+			PushedAt:    siteTime(r.PushedAt, opts.Location),
+			StarredAt:   siteTime(r.StarredAt, opts.Location),
+			Archived:    r.Archived,
+			Pinned:      r.Pinned,
+			Reason:      r.Reason,
+		}
+		if r.Language != "" {
+			s.LanguageSlug = count(languages, r.Language)
+		}
+		for _, t := range feedTopics(r) {
+			s.Topics = append(s.Topics, t)
+			s.TopicSlugs = append(s.TopicSlugs, count(topics, t))
+		}
+		data.Stars = append(data.Stars, s)
+	}
+	data.Count = len(data.Stars)
+	data.Languages = sortedTerms(languages)
+	data.Topics = sortedTerms(topics)
+
+	return data
+}
+
+// sortedTerms returns the terms most used first, then by name.
+func sortedTerms(terms map[string]*siteTerm) []siteTerm {
+	sorted := []siteTerm{}
+	for _, t := range terms {
+		sorted = append(sorted, *t)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Slug < sorted[j].Slug
+	})
+
+	return sorted
+}
+
+func siteTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return inLocation(t, loc).Format(time.RFC3339)
+}
+
+// Slugify returns s lowercased with the runs of characters other than
+// letters and digits replaced by a dash, usable in URLs: "C++" becomes
+// "cplusplus" and "owner/repo.js" "owner-repo-js".
+func Slugify(s string) string {
+	s = strings.NewReplacer("+", "plus", "#", "sharp").Replace(strings.ToLower(s))
+
+	var b strings.Builder
+	dash := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+
+	return b.String()
+}
+
+// exportHugo writes the site data file as YAML, for Hugo's data directory.
+// READMEs are left out.
+func exportHugo(w io.Writer, stars []*store.Repository, opts Options) error {
+	b, err := json.Marshal(buildSiteData(stars, opts))
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// exportJekyll writes the site data file as JSON, for Jekyll's _data
+// directory. READMEs are left out.
+func exportJekyll(w io.Writer, stars []*store.Repository, opts Options) error {
+	b, err := json.MarshalIndent(buildSiteData(stars, opts), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+
+	return err
+}
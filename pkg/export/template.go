@@ -0,0 +1,86 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// templateData is the data the collection templates are executed with.
+type templateData struct {
+	Stars      []*store.Repository
+	Count      int
+	ExportedAt time.Time
+}
+
+// templateFuncs are the functions available to the export templates,
+// besides the text/template builtins.
+func templateFuncs(opts Options) template.FuncMap {
+	return template.FuncMap{
+		"join":    strings.Join,
+		"lower":   strings.ToLower,
+		"upper":   strings.ToUpper,
+		"trim":    strings.TrimSpace,
+		"replace": strings.ReplaceAll,
+		// truncate shortens s to n characters, ending it with an ellipsis.
+		"truncate": func(n int, s string) string {
+			runes := []rune(s)
+			if n < 1 || len(runes) <= n {
+				return s
+			}
+			return string(runes[:n-1]) + "…"
+		},
+		// date formats t with the Go layout, in the time zone of the export.
+		"date": func(layout string, t time.Time) string {
+			return inLocation(t, opts.Location).Format(layout)
+		},
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"markdown": func(r *store.Repository) string {
+			return MarkdownEntry(*r)
+		},
+	}
+}
+
+// exportTemplate renders the stars through the user provided Go template
+// in opts.Template. Templates defining a "star" template render it for
+// every star, between the optional "header" and "footer" ones, which get
+// the whole collection. Other templates are executed once with the whole
+// collection: .Stars, .Count and .ExportedAt.
+func exportTemplate(w io.Writer, stars []*store.Repository, opts Options) error {
+	if opts.Template == "" {
+		return errors.New("template exports need a template, see --template")
+	}
+	t, err := template.New("export").Funcs(templateFuncs(opts)).Parse(opts.Template)
+	if err != nil {
+		return fmt.Errorf("parsing the template: %w", err)
+	}
+	data := templateData{Stars: stars, Count: len(stars), ExportedAt: time.Now().UTC()}
+
+	if t.Lookup("star") == nil {
+		return t.Execute(w, data)
+	}
+	if t.Lookup("header") != nil {
+		if err := t.ExecuteTemplate(w, "header", data); err != nil {
+			return err
+		}
+	}
+	for _, r := range stars {
+		if err := t.ExecuteTemplate(w, "star", r); err != nil {
+			return err
+		}
+	}
+	if t.Lookup("footer") != nil {
+		return t.ExecuteTemplate(w, "footer", data)
+	}
+
+	return nil
+}
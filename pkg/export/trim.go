@@ -0,0 +1,174 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// trimmedDescriptionLen is the length descriptions are truncated to, in
+// runes, when dropping the READMEs isn't enough to fit an export.
+const trimmedDescriptionLen = 140
+
+// Trimmed reports what WriteWithin trimmed to fit an export in its budget.
+type Trimmed struct {
+	// Readmes is the number of READMEs dropped, largest first.
+	Readmes int
+	// Descriptions is the number of descriptions and translations
+	// truncated.
+	Descriptions int
+	// PathBookmarks is the number of path bookmarks dropped.
+	PathBookmarks int
+	// Stars is the number of stars left out, from the end of the list.
+	Stars int
+}
+
+// Any reports whether anything was trimmed.
+func (t Trimmed) Any() bool {
+	return t != Trimmed{}
+}
+
+// WriteWithin writes stars to w like Write, trimming the heaviest fields
+// until the output, compressed when requested, fits in maxSize bytes:
+// READMEs first, largest first, then long descriptions, then path bookmarks.
+// When that's not enough the last stars are left out. The stars are
+// modified in place.
+func WriteWithin(w io.Writer, format string, stars []*store.Repository, opts Options, maxSize int64) (Trimmed, error) {
+	var t Trimmed
+	if _, ok := writers[format]; !ok {
+		return t, fmt.Errorf("unknown export format %q", format)
+	}
+
+	fits := func(stars []*store.Repository) (bool, error) {
+		var c countingWriter
+		if err := Write(&c, format, stars, opts); err != nil {
+			return false, err
+		}
+		return c.n <= maxSize, nil
+	}
+
+	ok, err := fits(stars)
+	if err != nil {
+		return t, err
+	}
+
+	if !ok {
+		var readmes []*store.Repository
+		for _, r := range stars {
+			if r.Readme.String != "" {
+				readmes = append(readmes, r)
+			}
+		}
+		sort.SliceStable(readmes, func(i, j int) bool {
+			return len(readmes[i].Readme.String) > len(readmes[j].Readme.String)
+		})
+		original := make([]store.NullString, len(readmes))
+		for i, r := range readmes {
+			original[i] = r.Readme
+		}
+		dropReadmes := func(n int) {
+			for i, r := range readmes {
+				if i < n {
+					r.Readme = store.NullString{}
+				} else {
+					r.Readme = original[i]
+				}
+			}
+		}
+
+		t.Readmes, ok, err = fewest(len(readmes), func(n int) (bool, error) {
+			dropReadmes(n)
+			return fits(stars)
+		})
+		if err != nil {
+			return t, err
+		}
+		dropReadmes(t.Readmes)
+	}
+
+	if !ok {
+		for _, r := range stars {
+			description, cut := truncateRunes(r.Description, trimmedDescriptionLen)
+			translation, cutTranslation := truncateRunes(r.Translation, trimmedDescriptionLen)
+			if cut || cutTranslation {
+				r.Description, r.Translation = description, translation
+				t.Descriptions++
+			}
+		}
+		if ok, err = fits(stars); err != nil {
+			return t, err
+		}
+	}
+
+	if !ok {
+		for _, r := range stars {
+			t.PathBookmarks += len(r.PathBookmarks)
+			r.PathBookmarks = nil
+		}
+		if ok, err = fits(stars); err != nil {
+			return t, err
+		}
+	}
+
+	if !ok {
+		t.Stars, ok, err = fewest(len(stars), func(n int) (bool, error) {
+			return fits(stars[:len(stars)-n])
+		})
+		if err != nil {
+			return t, err
+		}
+		if !ok {
+			return t, fmt.Errorf("an empty %s export doesn't fit in %d bytes", format, maxSize)
+		}
+		stars = stars[:len(stars)-t.Stars]
+	}
+
+	return t, Write(w, format, stars, opts)
+}
+
+// fewest returns the smallest n in [0, max] for which fits returns true,
+// searching it with a bisection. fits must not become false as n grows.
+// ok is false when not even max fits.
+func fewest(max int, fits func(n int) (bool, error)) (n int, ok bool, err error) {
+	if ok, err := fits(max); err != nil || !ok {
+		return max, false, err
+	}
+
+	lo, hi := 0, max
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		ok, err := fits(mid)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	return lo, true, nil
+}
+
+// truncateRunes truncates s to n runes, ending it with an ellipsis,
+// reporting whether it was truncated.
+func truncateRunes(s string, n int) (string, bool) {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s, false
+	}
+
+	return string(runes[:n-1]) + "…", true
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
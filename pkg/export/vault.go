@@ -0,0 +1,126 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"gopkg.in/yaml.v3"
+)
+
+// vaultFrontmatter is the YAML frontmatter of a vault note, the properties
+// Obsidian and Logseq index.
+type vaultFrontmatter struct {
+	FullName    string   `yaml:"full_name"`
+	URL         string   `yaml:"url"`
+	Homepage    string   `yaml:"homepage,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Language    string   `yaml:"language,omitempty"`
+	Topics      []string `yaml:"topics,omitempty"`
+	// Tags are the topics, as tags.
+	Tags        []string `yaml:"tags,omitempty"`
+	Stars       int      `yaml:"stars"`
+	CreatedAt   noteDate `yaml:"created_at,omitempty"`
+	PushedAt    noteDate `yaml:"pushed_at,omitempty"`
+	StarredAt   noteDate `yaml:"starred_at"`
+	UnstarredAt noteDate `yaml:"unstarred_at,omitempty"`
+	Archived    bool     `yaml:"archived,omitempty"`
+	Reason      string   `yaml:"reason,omitempty"`
+}
+
+// WriteVault writes a Markdown note per star to dir, an Obsidian or Logseq
+// vault: dir/OWNER/NAME.md, with the repository metadata in the YAML
+// frontmatter and the README as the body. Notes are only rewritten when
+// their contents change, and notes of stars no longer exported are left
+// alone. Returns the number of notes written.
+func WriteVault(dir string, stars []*store.Repository, opts Options) (int, error) {
+	written := 0
+	for _, r := range stars {
+		owner, name, ok := strings.Cut(r.FullName, "/")
+		owner, name = filepath.Base(owner), filepath.Base(name)
+		if !ok || owner == "." || owner == ".." || name == "." || name == ".." {
+			return written, fmt.Errorf("invalid repository name %q", r.FullName)
+		}
+
+		note, err := vaultNote(r, opts)
+		if err != nil {
+			return written, err
+		}
+
+		path := filepath.Join(dir, owner, name+".md")
+		if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, note) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(path, note, 0o644); err != nil {
+			return written, err
+		}
+		written++
+	}
+
+	return written, nil
+}
+
+// vaultNote returns the note of r: the frontmatter, the repository as the
+// title and its README.
+func vaultNote(r *store.Repository, opts Options) ([]byte, error) {
+	fm := vaultFrontmatter{
+		FullName:    r.FullName,
+		URL:         r.HTMLURL,
+		Homepage:    r.Homepage,
+		Description: strings.TrimSpace(r.Description),
+		Language:    r.Language,
+		Topics:      feedTopics(r),
+		Tags:        feedTopics(r),
+		Stars:       r.StargazersCount,
+		CreatedAt:   vaultDate(r.CreatedAt, opts.Location),
+		PushedAt:    vaultDate(r.PushedAt, opts.Location),
+		StarredAt:   vaultDate(r.StarredAt, opts.Location),
+		Archived:    r.Archived,
+		Reason:      r.Reason,
+	}
+	if r.UnstarredAt != nil {
+		fm.UnstarredAt = vaultDate(*r.UnstarredAt, opts.Location)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(fm); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	b.WriteString("---\n\n")
+
+	fmt.Fprintf(&b, "# [%s](%s)\n", r.FullName, r.HTMLURL)
+	if r.Readme.String != "" {
+		fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(r.Readme.String))
+	}
+
+	return b.Bytes(), nil
+}
+
+// noteDate is a YYYY-MM-DD date property, written unquoted so it's read as
+// a date rather than as text.
+type noteDate string
+
+func (d noteDate) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: string(d)}, nil
+}
+
+// vaultDate formats t as a date property, empty when zero.
+func vaultDate(t time.Time, loc *time.Location) noteDate {
+	if t.IsZero() {
+		return ""
+	}
+	return noteDate(inLocation(t, loc).Format("2006-01-02"))
+}
@@ -0,0 +1,47 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"gopkg.in/yaml.v3"
+)
+
+// exportYAML writes the stars as a YAML sequence, with the same fields as
+// the JSON export.
+func exportYAML(w io.Writer, stars []*store.Repository, opts Options) error {
+	// JSON is valid YAML: decoding the JSON export keeps its field names and
+	// order, the flow style is then dropped for block style.
+	v, err := jsonStars(stars, opts)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// blockStyle resets the style of n and its children, letting the encoder
+// pick block collections, plain scalars when unambiguous and literal blocks
+// for multi-line strings.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
@@ -0,0 +1,148 @@
+package githubclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// advisoryBatch is the number of packages looked up per GraphQL query.
+const advisoryBatch = 25
+
+// Package is a package of a registry covered by the GitHub Advisory
+// Database.
+type Package struct {
+	// Ecosystem is the GraphQL SecurityAdvisoryEcosystem of the registry:
+	// GO, NPM, PIP, RUST, RUBYGEMS...
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+}
+
+// Vulnerability is a range of versions of a package affected by a security
+// advisory.
+type Vulnerability struct {
+	// Package is the package looked up.
+	Package Package `json:"package"`
+	// GHSAID is the GitHub Security Advisory ID, GHSA-xxxx-xxxx-xxxx.
+	GHSAID string `json:"ghsa_id"`
+	// CVEs are the CVE IDs of the advisory, if any.
+	CVEs        []string  `json:"cves,omitempty"`
+	Summary     string    `json:"summary"`
+	Permalink   string    `json:"permalink"`
+	Severity    string    `json:"severity"`
+	PublishedAt time.Time `json:"published_at"`
+	// VulnerableRange is the affected versions, e.g. "< 1.2.3".
+	VulnerableRange string `json:"vulnerable_range"`
+	// FirstPatched is the first version fixing it, empty when none does.
+	FirstPatched string `json:"first_patched,omitempty"`
+	// References are the URLs referenced by the advisory.
+	References []string `json:"references,omitempty"`
+}
+
+type graphqlVulnerabilities struct {
+	Nodes []struct {
+		Severity               string `json:"severity"`
+		VulnerableVersionRange string `json:"vulnerableVersionRange"`
+		FirstPatchedVersion    *struct {
+			Identifier string `json:"identifier"`
+		} `json:"firstPatchedVersion"`
+		Advisory struct {
+			GHSAID      string     `json:"ghsaId"`
+			Summary     string     `json:"summary"`
+			Permalink   string     `json:"permalink"`
+			PublishedAt time.Time  `json:"publishedAt"`
+			WithdrawnAt *time.Time `json:"withdrawnAt"`
+			Identifiers []struct {
+				Type  string `json:"type"`
+				Value string `json:"value"`
+			} `json:"identifiers"`
+			References []struct {
+				URL string `json:"url"`
+			} `json:"references"`
+		} `json:"advisory"`
+	} `json:"nodes"`
+}
+
+// Vulnerabilities returns the vulnerabilities of the packages recorded in
+// the GitHub Advisory Database with one of the severities (LOW, MODERATE,
+// HIGH or CRITICAL), all of them when none is given. Withdrawn advisories
+// are left out.
+func (c *HTTPClient) Vulnerabilities(ctx context.Context, packages []Package, severities []string) ([]Vulnerability, error) {
+	var vulns []Vulnerability
+	for start := 0; start < len(packages); start += advisoryBatch {
+		batch := packages[start:min(start+advisoryBatch, len(packages))]
+
+		var query strings.Builder
+		vars := map[string]interface{}{}
+		query.WriteString("query(")
+		if len(severities) > 0 {
+			vars["sev"] = severities
+			query.WriteString("$sev: [SecurityAdvisorySeverity!]")
+		}
+		for i, p := range batch {
+			vars[fmt.Sprintf("e%d", i)] = p.Ecosystem
+			vars[fmt.Sprintf("p%d", i)] = p.Name
+			if i > 0 || len(severities) > 0 {
+				query.WriteString(", ")
+			}
+			fmt.Fprintf(&query, "$e%d: SecurityAdvisoryEcosystem!, $p%d: String!", i, i)
+		}
+		query.WriteString(") {\n")
+		for i := range batch {
+			fmt.Fprintf(&query, "  v%d: securityVulnerabilities(first: 100, ecosystem: $e%d, package: $p%d", i, i, i)
+			if len(severities) > 0 {
+				query.WriteString(", severities: $sev")
+			}
+			query.WriteString(`) {
+    nodes {
+      severity vulnerableVersionRange
+      firstPatchedVersion { identifier }
+      advisory {
+        ghsaId summary permalink publishedAt withdrawnAt
+        identifiers { type value }
+        references { url }
+      }
+    }
+  }
+`)
+		}
+		query.WriteString("}")
+
+		var data map[string]graphqlVulnerabilities
+		if err := c.graphql(ctx, query.String(), vars, &data); err != nil {
+			return nil, err
+		}
+
+		for i, p := range batch {
+			for _, n := range data[fmt.Sprintf("v%d", i)].Nodes {
+				if n.Advisory.WithdrawnAt != nil {
+					continue
+				}
+				v := Vulnerability{
+					Package:         p,
+					GHSAID:          n.Advisory.GHSAID,
+					Summary:         n.Advisory.Summary,
+					Permalink:       n.Advisory.Permalink,
+					Severity:        n.Severity,
+					PublishedAt:     n.Advisory.PublishedAt,
+					VulnerableRange: n.VulnerableVersionRange,
+				}
+				if n.FirstPatchedVersion != nil {
+					v.FirstPatched = n.FirstPatchedVersion.Identifier
+				}
+				for _, id := range n.Advisory.Identifiers {
+					if id.Type == "CVE" {
+						v.CVEs = append(v.CVEs, id.Value)
+					}
+				}
+				for _, ref := range n.Advisory.References {
+					v.References = append(v.References, ref.URL)
+				}
+				vulns = append(vulns, v)
+			}
+		}
+	}
+
+	return vulns, nil
+}
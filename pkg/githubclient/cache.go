@@ -0,0 +1,370 @@
+package githubclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache is an http.RoundTripper caching the responses to GET requests in
+// Dir, following the HTTP caching rules of RFC 9111 for private caches:
+// fresh responses are answered from Dir, stale ones are revalidated with
+// their ETag or Last-Modified, and requests with unsafe methods invalidate
+// the cached responses of their URL.
+//
+// Dir can be shared by concurrent processes using Cache: entries are
+// written atomically, and keyed by URL and credentials so that tokens never
+// see the responses to each other. The entry format is Cache's own, other
+// HTTP caches, such as the gh CLI one, can't read it. Caching errors never
+// fail requests, the response is just not cached.
+type Cache struct {
+	Dir string
+	// Transport sends the requests, http.DefaultTransport when nil.
+	Transport http.RoundTripper
+}
+
+// cacheEntry is a cached response, stored as a JSON file in the cache
+// directory.
+type cacheEntry struct {
+	URL string `json:"url"`
+	// Vary holds the SHA-256 of the request headers named by the Vary
+	// response header, the responses being reused only for requests with
+	// the same ones. Their values, credentials among them, are not stored.
+	Vary map[string]string `json:"vary,omitempty"`
+	// RequestTime and ResponseTime are when the request that got the
+	// response was sent and when the response was received, or last
+	// revalidated.
+	RequestTime  time.Time   `json:"request_time"`
+	ResponseTime time.Time   `json:"response_time"`
+	Status       int         `json:"status"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+// heuristicStatuses are the status codes cacheable without explicit
+// freshness, RFC 9110 section 15.1.
+var heuristicStatuses = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true, 308: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *Cache) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	key := cacheKey(req)
+	if req.Method != http.MethodGet {
+		resp, err := transport.RoundTrip(req)
+		if err == nil && !safeMethod(req.Method) && resp.StatusCode < 400 {
+			os.Remove(c.path(key))
+		}
+		return resp, err
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	if reqCC.has("no-store") {
+		return transport.RoundTrip(req)
+	}
+
+	// Conditional requests of the caller are theirs to answer.
+	var entry *cacheEntry
+	if req.Header.Get("If-None-Match") == "" && req.Header.Get("If-Modified-Since") == "" {
+		entry = c.load(key, req)
+	}
+	if entry != nil && entry.fresh(time.Now(), reqCC) {
+		return entry.response(req, time.Now()), nil
+	}
+
+	out := req
+	if entry != nil {
+		etag, modified := entry.Header.Get("ETag"), entry.Header.Get("Last-Modified")
+		if etag != "" || modified != "" {
+			out = req.Clone(req.Context())
+			if etag != "" {
+				out.Header.Set("If-None-Match", etag)
+			}
+			if modified != "" {
+				out.Header.Set("If-Modified-Since", modified)
+			}
+		}
+	}
+
+	requestTime := time.Now()
+	resp, err := transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	responseTime := time.Now()
+
+	if out != req && resp.StatusCode == http.StatusNotModified {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		entry.revalidated(resp.Header, requestTime, responseTime)
+		c.save(key, entry)
+		return entry.response(req, responseTime), nil
+	}
+
+	if !storable(resp, reqCC) {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	c.save(key, &cacheEntry{
+		URL:          req.URL.String(),
+		Vary:         varyHashes(resp.Header, req.Header),
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+		Status:       resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+	})
+
+	return resp, nil
+}
+
+// cacheKey identifies the cached responses to req by URL and credentials.
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "GET %s\n", req.URL.String())
+	h.Write([]byte(req.Header.Get("Authorization")))
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// path returns the file of the entry key, in a subdirectory named after
+// its first two characters to keep directories small.
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key[:2], key+".json")
+}
+
+// load returns the entry cached for req, nil when there is none or it was
+// stored for requests with other Vary headers.
+func (c *Cache) load(key string, req *http.Request) *cacheEntry {
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil
+	}
+	vary := varyHashes(entry.Header, req.Header)
+	if len(vary) != len(entry.Vary) {
+		return nil
+	}
+	for name, h := range entry.Vary {
+		if vary[name] != h {
+			return nil
+		}
+	}
+
+	return &entry
+}
+
+// save writes entry atomically, so concurrent readers never see a partial
+// one.
+func (c *Cache) save(key string, entry *cacheEntry) {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+// varyHashes returns the SHA-256 of the request headers named by the Vary
+// response header.
+func varyHashes(respHeader, reqHeader http.Header) map[string]string {
+	hashes := map[string]string{}
+	for _, v := range respHeader.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+				hashes[name] = fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(reqHeader.Values(name), ", "))))
+			}
+		}
+	}
+
+	return hashes
+}
+
+// storable reports whether resp can be cached, RFC 9111 section 3. Partial
+// responses aren't.
+func storable(resp *http.Response, reqCC cacheControl) bool {
+	cc := parseCacheControl(resp.Header)
+	if cc.has("no-store") || reqCC.has("no-store") || strings.Contains(strings.Join(resp.Header.Values("Vary"), ","), "*") {
+		return false
+	}
+	switch {
+	case resp.StatusCode < 200, resp.StatusCode == http.StatusPartialContent, resp.StatusCode == http.StatusNotModified:
+		return false
+	case cc.has("max-age"), resp.Header.Get("Expires") != "":
+		return true
+	}
+
+	return heuristicStatuses[resp.StatusCode] &&
+		(resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "")
+}
+
+// freshnessLifetime returns how long the entry is fresh after being
+// generated by the server, RFC 9111 section 4.2.1.
+func (e *cacheEntry) freshnessLifetime() time.Duration {
+	cc := parseCacheControl(e.Header)
+	if v, ok := cc.seconds("max-age"); ok {
+		return v
+	}
+	date, dateErr := http.ParseTime(e.Header.Get("Date"))
+	if dateErr != nil {
+		date = e.ResponseTime
+	}
+	if v := e.Header.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0
+		}
+		return expires.Sub(date)
+	}
+	// Heuristic freshness, 10% of the time since the last modification.
+	if modified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && heuristicStatuses[e.Status] {
+		return date.Sub(modified) / 10
+	}
+
+	return 0
+}
+
+// age returns the current age of the entry, RFC 9111 section 4.2.3.
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	var apparent time.Duration
+	if date, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		apparent = max(0, e.ResponseTime.Sub(date))
+	}
+	ageValue, _ := strconv.Atoi(e.Header.Get("Age"))
+	corrected := time.Duration(ageValue)*time.Second + e.ResponseTime.Sub(e.RequestTime)
+
+	return max(apparent, corrected) + now.Sub(e.ResponseTime)
+}
+
+// fresh reports whether the entry can be used without revalidation to
+// answer a request with the reqCC Cache-Control directives.
+func (e *cacheEntry) fresh(now time.Time, reqCC cacheControl) bool {
+	if reqCC.has("no-cache") || parseCacheControl(e.Header).has("no-cache") {
+		return false
+	}
+	age := e.age(now)
+	if maxAge, ok := reqCC.seconds("max-age"); ok && age > maxAge {
+		return false
+	}
+	minFresh, _ := reqCC.seconds("min-fresh")
+
+	return age+minFresh < e.freshnessLifetime()
+}
+
+// revalidated updates the entry with the headers of the 304 response
+// revalidating it, RFC 9111 section 4.3.4.
+func (e *cacheEntry) revalidated(header http.Header, requestTime, responseTime time.Time) {
+	for name, values := range header {
+		if name != "Content-Length" {
+			e.Header[name] = values
+		}
+	}
+	e.RequestTime, e.ResponseTime = requestTime, responseTime
+}
+
+// response returns the cached response, with its Age at now.
+func (e *cacheEntry) response(req *http.Request, now time.Time) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(e.age(now).Seconds())))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// safeMethod reports whether method is safe, not invalidating the cached
+// responses, RFC 9110 section 9.2.1.
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// cacheControl holds the Cache-Control directives, lowercased, and their
+// arguments.
+type cacheControl map[string]string
+
+func parseCacheControl(h http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(d), "=")
+			if name != "" {
+				cc[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+	if _, ok := cc["no-cache"]; !ok && strings.Contains(strings.ToLower(h.Get("Pragma")), "no-cache") {
+		cc["no-cache"] = ""
+	}
+
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// seconds returns the delta-seconds argument of the directive name.
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	v, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+
+	return time.Duration(n) * time.Second, true
+}
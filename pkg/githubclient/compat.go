@@ -0,0 +1,102 @@
+package githubclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// APIVersion is the GitHub REST API version the client is written against,
+// sent in the X-GitHub-Api-Version header.
+const APIVersion = "2022-11-28"
+
+// do sends req, warning about deprecated or sunset endpoints. When the API
+// rejects the requested version, the request is retried once without the
+// X-GitHub-Api-Version header and the header is dropped from subsequent
+// requests, falling back to the API default version.
+func (c *HTTPClient) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusBadRequest && req.Header.Get("X-GitHub-Api-Version") != "" {
+		b, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if !bytes.Contains(b, []byte("X-GitHub-Api-Version")) {
+			resp.Body = io.NopCloser(bytes.NewReader(b))
+			return resp, nil
+		}
+
+		c.warnOnce("api-version", "GitHub API version %s is not supported anymore, falling back to the default version. Please upgrade gh-stars-exporter", req.Header.Get("X-GitHub-Api-Version"))
+		c.mu.Lock()
+		c.unsupportedVersion = true
+		c.mu.Unlock()
+
+		req = req.Clone(req.Context())
+		req.Header.Del("X-GitHub-Api-Version")
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		resp, err = c.HTTP.Do(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.checkDeprecation(req, resp)
+
+	return resp, nil
+}
+
+// checkDeprecation warns, once per endpoint, when the response flags the
+// endpoint as deprecated or scheduled for removal.
+func (c *HTTPClient) checkDeprecation(req *http.Request, resp *http.Response) {
+	deprecation := resp.Header.Get("Deprecation")
+	sunset := resp.Header.Get("Sunset")
+	if deprecation == "" && sunset == "" {
+		return
+	}
+
+	endpoint := req.URL.Path
+	msg := "GitHub API endpoint " + endpoint + " is deprecated"
+	if sunset != "" {
+		msg += ", it will be removed after " + sunset
+	}
+	if info := deprecationLink(resp.Header.Values("Link")); info != "" {
+		msg += " (see " + info + ")"
+	}
+	c.warnOnce("deprecation:"+endpoint, "%s", msg)
+}
+
+// deprecationLink returns the URL of the rel="deprecation" or rel="sunset"
+// Link, if any.
+func deprecationLink(headers []string) string {
+	for _, l := range parseLinks(headers...) {
+		if l.hasRel("deprecation") || l.hasRel("sunset") {
+			return l.URL
+		}
+	}
+
+	return ""
+}
+
+func (c *HTTPClient) warnOnce(key, format string, args ...interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.warned == nil {
+		c.warned = map[string]bool{}
+	}
+	if c.warned[key] {
+		return
+	}
+	c.warned[key] = true
+	c.Logger.Warnf(format, args...)
+}
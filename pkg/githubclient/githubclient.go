@@ -0,0 +1,531 @@
+// Package githubclient implements the GitHub API calls used by the exporter
+// behind the Client interface, so the sync pipeline can be exercised against
+// fakes and alternative forges.
+package githubclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// DefaultBaseURL is the GitHub REST API endpoint.
+const DefaultBaseURL = "https://api.github.com"
+
+// DefaultUserAgent identifies the client, as GitHub asks API clients to do.
+const DefaultUserAgent = "gh-stars-exporter (+https://github.com/rubiojr/gh-stars-exporter)"
+
+// ErrNotModified is returned by StarredRepos when the star list matches the
+// ETag passed in StarredOptions.IfNoneMatch.
+var ErrNotModified = fmt.Errorf("not modified")
+
+// ErrReadmeNotFound is returned by Readme when none of the known README
+// files exist in the repository.
+var ErrReadmeNotFound = fmt.Errorf("no readme found")
+
+// ReadmeFiles lists the README candidates tried, in order, when fetching a
+// repository README.
+var ReadmeFiles = []string{
+	"README.md",
+	"README.rst",
+	"README.adoc",
+	"README.markdown",
+	"README.rdoc",
+	"README.txt",
+	"README.mkd",
+	"README.textile",
+	"README",
+	"readme.md",
+	"Readme.md",
+	"README.MD",
+	"readme.markdown",
+	"readme",
+	"Readme",
+	"readme.rst",
+	"Readme.rst",
+	"README.org",
+	"Readme.org",
+	"readme.org",
+	"docs/README.md",
+	".github/README.md",
+	"docs/Readme.md",
+	"docs/readme.md",
+}
+
+// Client is implemented by the forges the exporter can sync stars from.
+type Client interface {
+	// StarredRepos calls fn with every page of repositories starred by the
+	// authenticated user, most recently starred first, stopping at the first
+	// error returned by fn.
+	StarredRepos(ctx context.Context, opts StarredOptions, fn func(Page) error) error
+	// Readme returns the raw README contents of the repository identified by
+	// fullName (owner/name).
+	Readme(ctx context.Context, fullName string) (string, error)
+	// Repo returns the repository identified by fullName (owner/name).
+	Repo(ctx context.Context, fullName string) (Repository, error)
+	// Following returns the users followed by the authenticated user.
+	Following(ctx context.Context) ([]User, error)
+}
+
+// StarredOptions modifies the starred repositories listing.
+type StarredOptions struct {
+	// IfNoneMatch is the ETag of the first page seen in a previous listing.
+	// When the star list did not change StarredRepos returns ErrNotModified
+	// without calling fn.
+	IfNoneMatch string
+	// LastPage and LastETag are the number and ETag of the last page seen
+	// in the same listing. Unstarring a repository past the first page
+	// leaves it unchanged but shifts the last one, so when LastPage is
+	// after the first the last page has to match too.
+	LastPage int
+	LastETag string
+	// StartPage resumes the listing at the given page, skipping the
+	// previous ones. IfNoneMatch is ignored when resuming.
+	StartPage int
+}
+
+// Page is a page of starred repositories.
+type Page struct {
+	// Number is the page number, starting at 1.
+	Number int
+	// Last is the number of the last page, or 0 when unknown.
+	Last int
+	// ETag identifies the contents of the page.
+	ETag  string
+	Repos []StarredRepo
+}
+
+// StarredRepo is a repository along with the time it was starred.
+type StarredRepo struct {
+	Repo      Repository `json:"repo"`
+	StarredAt time.Time  `json:"starred_at"`
+}
+
+// Repository is the GitHub representation of a repository.
+type Repository struct {
+	ID              int       `json:"id"`
+	Name            string    `json:"name"`
+	HTMLURL         string    `json:"html_url"`
+	Description     string    `json:"description"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	PushedAt        time.Time `json:"pushed_at"`
+	StargazersCount int       `json:"stargazers_count"`
+	Language        string    `json:"language"`
+	FullName        string    `json:"full_name"`
+	Topics          []string  `json:"topics"`
+	IsTemplate      bool      `json:"is_template"`
+	Private         bool      `json:"private"`
+	Archived        bool      `json:"archived"`
+	Homepage        string    `json:"homepage"`
+}
+
+// User is the GitHub representation of a user or organization.
+type User struct {
+	ID      int    `json:"id"`
+	Login   string `json:"login"`
+	HTMLURL string `json:"html_url"`
+}
+
+// HTTPClient is the Client talking to the GitHub REST API.
+type HTTPClient struct {
+	// BaseURL is the API endpoint, DefaultBaseURL unless overridden.
+	BaseURL string
+	Token   string
+	// Timeout applies to every individual request.
+	Timeout   time.Duration
+	UserAgent string
+	// Headers are added to every request, e.g. for proxies in front of
+	// GitHub Enterprise.
+	Headers http.Header
+	HTTP    *http.Client
+	Logger  *log.Logger
+
+	mu                 sync.Mutex
+	warned             map[string]bool
+	unsupportedVersion bool
+}
+
+// New returns a client for the GitHub REST API authenticated with token.
+func New(token string) *HTTPClient {
+	return &HTTPClient{
+		BaseURL:   DefaultBaseURL,
+		Token:     token,
+		Timeout:   30 * time.Second,
+		UserAgent: DefaultUserAgent,
+		HTTP:      &http.Client{},
+		Logger:    log.NewWithOptions(os.Stderr, log.Options{}),
+	}
+}
+
+// StarredRepos implements Client.
+func (c *HTTPClient) StarredRepos(ctx context.Context, opts StarredOptions, fn func(Page) error) error {
+	nextPageURL := c.BaseURL + "/user/starred?per_page=100"
+
+	currentPage := 1
+	if opts.StartPage > 1 {
+		currentPage = opts.StartPage
+		nextPageURL += fmt.Sprintf("&page=%d", currentPage)
+	}
+	for nextPageURL != "" {
+		c.Logger.Debugf("Page URL %s", nextPageURL)
+		etag := ""
+		if currentPage == 1 {
+			etag = opts.IfNoneMatch
+		}
+		page, pagerLink, err := c.fetchStarredPage(ctx, nextPageURL, etag)
+		if errors.Is(err, ErrNotModified) && opts.LastPage > 1 {
+			err = c.lastPageNotModified(ctx, opts)
+			if err == nil {
+				// The list changed past the first page, walk it again.
+				page, pagerLink, err = c.fetchStarredPage(ctx, nextPageURL, "")
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		nextPageURL = getNextPageURL(pagerLink)
+		page.Number = currentPage
+		page.Last = getPageCount(pagerLink)
+		err = fn(page)
+		if err != nil {
+			return err
+		}
+
+		currentPage++
+	}
+
+	return nil
+}
+
+// lastPageNotModified returns ErrNotModified when the last page of the
+// star list matches opts.LastETag, nil when it changed.
+func (c *HTTPClient) lastPageNotModified(ctx context.Context, opts StarredOptions) error {
+	pageURL := fmt.Sprintf("%s/user/starred?per_page=100&page=%d", c.BaseURL, opts.LastPage)
+	c.Logger.Debugf("Page URL %s", pageURL)
+	_, _, err := c.fetchStarredPage(ctx, pageURL, opts.LastETag)
+
+	return err
+}
+
+// UserStarred returns the public repositories starred by the user login,
+// most recently starred first.
+func (c *HTTPClient) UserStarred(ctx context.Context, login string) ([]StarredRepo, error) {
+	var stars []StarredRepo
+	nextPageURL := fmt.Sprintf("%s/users/%s/starred?per_page=100", c.BaseURL, url.PathEscape(login))
+	for nextPageURL != "" {
+		c.Logger.Debugf("Page URL %s", nextPageURL)
+		page, pagerLink, err := c.fetchStarredPage(ctx, nextPageURL, "")
+		if err != nil {
+			return nil, err
+		}
+		stars = append(stars, page.Repos...)
+		nextPageURL = getNextPageURL(pagerLink)
+	}
+
+	return stars, nil
+}
+
+// fetchStarredPage fetches a single page of starred repositories, returning
+// the page and the Link header used for pagination.
+func (c *HTTPClient) fetchStarredPage(ctx context.Context, pageURL, etag string) (Page, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, pageURL)
+	if err != nil {
+		return Page{}, "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github.star+json")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return Page{}, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return Page{}, "", ErrNotModified
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Page{}, "", fmt.Errorf("fetching %s: %s", pageURL, resp.Status)
+	}
+
+	page := Page{ETag: resp.Header.Get("ETag")}
+	if err := json.NewDecoder(resp.Body).Decode(&page.Repos); err != nil {
+		return Page{}, "", err
+	}
+
+	return page, strings.Join(resp.Header.Values("Link"), ", "), nil
+}
+
+// Repo implements Client.
+func (c *HTTPClient) Repo(ctx context.Context, fullName string) (Repository, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, fmt.Sprintf("%s/repos/%s", c.BaseURL, fullName))
+	if err != nil {
+		return Repository{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return Repository{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Repository{}, fmt.Errorf("fetching repository %s: %s", fullName, resp.Status)
+	}
+
+	var repo Repository
+	err = json.NewDecoder(resp.Body).Decode(&repo)
+
+	return repo, err
+}
+
+// Star stars the repository identified by fullName (owner/name) as the
+// authenticated user. Starring an already starred repository succeeds.
+func (c *HTTPClient) Star(ctx context.Context, fullName string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	req, err := c.newRequestWithBody(ctx, http.MethodPut, fmt.Sprintf("%s/user/starred/%s", c.BaseURL, fullName), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("starring %s: %s", fullName, resp.Status)
+	}
+
+	return nil
+}
+
+// User returns the authenticated user.
+func (c *HTTPClient) User(ctx context.Context) (User, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, c.BaseURL+"/user")
+	if err != nil {
+		return User{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return User{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return User{}, fmt.Errorf("fetching the authenticated user: %s", resp.Status)
+	}
+
+	var user User
+	err = json.NewDecoder(resp.Body).Decode(&user)
+
+	return user, err
+}
+
+// ReplaceTopics sets the topics of the repository identified by fullName
+// (owner/name), replacing all the current ones. It needs write access to
+// the repository.
+func (c *HTTPClient) ReplaceTopics(ctx context.Context, fullName string, topics []string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	body, err := json.Marshal(map[string][]string{"names": topics})
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequestWithBody(ctx, http.MethodPut, fmt.Sprintf("%s/repos/%s/topics", c.BaseURL, fullName), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("replacing the topics of %s: %s", fullName, resp.Status)
+	}
+
+	return nil
+}
+
+// Following implements Client.
+func (c *HTTPClient) Following(ctx context.Context) ([]User, error) {
+	var users []User
+	nextPageURL := c.BaseURL + "/user/following?per_page=100"
+	for nextPageURL != "" {
+		c.Logger.Debugf("Page URL %s", nextPageURL)
+		page, pagerLink, err := c.fetchFollowingPage(ctx, nextPageURL)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, page...)
+		nextPageURL = getNextPageURL(pagerLink)
+	}
+
+	return users, nil
+}
+
+func (c *HTTPClient) fetchFollowingPage(ctx context.Context, pageURL string) ([]User, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, pageURL)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("fetching %s: %s", pageURL, resp.Status)
+	}
+
+	var users []User
+	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+		return nil, "", err
+	}
+
+	return users, strings.Join(resp.Header.Values("Link"), ", "), nil
+}
+
+// Readme implements Client.
+func (c *HTTPClient) Readme(ctx context.Context, fullName string) (string, error) {
+	baseURL := fmt.Sprintf("%s/repos/%s/contents/", c.BaseURL, fullName)
+
+	for _, file := range ReadmeFiles {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		content, found, err := c.fetchReadmeFile(ctx, baseURL+file)
+		if err != nil {
+			c.Logger.Debugf("Fetching %s%s: %s", baseURL, file, err)
+			continue
+		}
+		if found {
+			return content, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w for %s", ErrReadmeNotFound, fullName)
+}
+
+// fetchReadmeFile downloads the raw contents of a single README candidate.
+// found is false when the file does not exist in the repository.
+func (c *HTTPClient) fetchReadmeFile(ctx context.Context, fileURL string) (content string, found bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, fileURL)
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3.raw")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", false, nil
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", false, err
+	}
+
+	return string(b), true, nil
+}
+
+func (c *HTTPClient) newRequest(ctx context.Context, u string) (*http.Request, error) {
+	return c.newRequestWithBody(ctx, http.MethodGet, u, nil)
+}
+
+func (c *HTTPClient) newRequestWithBody(ctx context.Context, method, u string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range c.Headers {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("User-Agent", c.UserAgent)
+	c.mu.Lock()
+	if !c.unsupportedVersion {
+		req.Header.Set("X-GitHub-Api-Version", APIVersion)
+	}
+	c.mu.Unlock()
+
+	return req, nil
+}
+
+// getNextPageURL returns the URL of the next page from the Link header of
+// a GitHub API response, empty on the last page.
+func getNextPageURL(linkHeader string) string {
+	return findLink("next", linkHeader)
+}
+
+// getPageCount returns the number of the last page from the Link header of
+// a GitHub API response, 0 when unknown.
+func getPageCount(linkHeader string) int {
+	last := findLink("last", linkHeader)
+	if last == "" {
+		return 0
+	}
+	u, err := url.Parse(last)
+	if err != nil {
+		return 0
+	}
+	page, _ := strconv.Atoi(u.Query().Get("page"))
+
+	return page
+}
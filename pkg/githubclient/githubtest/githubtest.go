@@ -0,0 +1,431 @@
+// Package githubtest provides an httptest-backed fake of the GitHub API
+// endpoints used by githubclient.
+package githubtest
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+)
+
+// Server is a fake GitHub API serving a fixed set of stars and READMEs.
+type Server struct {
+	*httptest.Server
+
+	// PerPage is the number of stars served per page.
+	PerPage int
+
+	mu        sync.Mutex
+	stars     []githubclient.StarredRepo
+	readmes   map[string]string
+	repos     []githubclient.Repository
+	following []githubclient.User
+	user      githubclient.User
+	// userStars are the stars of other users, keyed by login.
+	userStars map[string][]githubclient.StarredRepo
+	// points is the GraphQL rate limit left, decreasing by one per query.
+	points int
+}
+
+// NewServer starts a fake GitHub API serving stars. It must be closed
+// with Close.
+func NewServer(stars ...githubclient.StarredRepo) *Server {
+	s := &Server{
+		PerPage:   100,
+		stars:     stars,
+		readmes:   map[string]string{},
+		userStars: map[string][]githubclient.StarredRepo{},
+		points:    5000,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user/starred", s.handleStarred)
+	mux.HandleFunc("/user/starred/", s.handleStar)
+	mux.HandleFunc("/repos/", s.handleRepos)
+	mux.HandleFunc("/user/following", s.handleFollowing)
+	mux.HandleFunc("/user", s.handleUser)
+	mux.HandleFunc("/users/", s.handleUserStarred)
+	mux.HandleFunc("/graphql", s.handleGraphQL)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Client returns a githubclient.HTTPClient talking to the fake server.
+func (s *Server) Client() *githubclient.HTTPClient {
+	c := githubclient.New("test-token")
+	c.BaseURL = s.URL
+	c.HTTP = s.Server.Client()
+
+	return c
+}
+
+// SetStars replaces the starred repositories served.
+func (s *Server) SetStars(stars ...githubclient.StarredRepo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stars = stars
+}
+
+// AddRepo adds a repository that isn't starred, served by the
+// /repos/{owner}/{name} endpoint.
+func (s *Server) AddRepo(repo githubclient.Repository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repos = append(s.repos, repo)
+}
+
+// SetFollowing replaces the users followed by the authenticated user.
+func (s *Server) SetFollowing(users ...githubclient.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.following = users
+}
+
+// SetUser sets the authenticated user.
+func (s *Server) SetUser(user githubclient.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = user
+}
+
+// Topics returns the topics of the repository fullName, as last replaced.
+func (s *Server) Topics(fullName string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, repo := range s.allRepos() {
+		if strings.EqualFold(repo.FullName, fullName) {
+			return repo.Topics
+		}
+	}
+
+	return nil
+}
+
+// SetUserStars replaces the stars of the user login, served by the
+// /users/{login}/starred endpoint.
+func (s *Server) SetUserStars(login string, stars ...githubclient.StarredRepo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.userStars[login] = stars
+}
+
+// SetReadme sets the README served for the repository fullName.
+func (s *Server) SetReadme(fullName, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readmes[fullName] = content
+}
+
+func (s *Server) handleStarred(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	last := (len(s.stars) + s.PerPage - 1) / s.PerPage
+	start := min((page-1)*s.PerPage, len(s.stars))
+	end := min(start+s.PerPage, len(s.stars))
+
+	var links []string
+	if page < last {
+		links = append(links, fmt.Sprintf(`<%s/user/starred?per_page=%d&page=%d>; rel="next"`, s.URL, s.PerPage, page+1))
+	}
+	if last > 1 {
+		links = append(links, fmt.Sprintf(`<%s/user/starred?per_page=%d&page=%d>; rel="last"`, s.URL, s.PerPage, last))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	body, err := json.Marshal(s.stars[start:end])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	etag := fmt.Sprintf(`W/"%x"`, sha256.Sum256(body))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// handleStar stars a repository added with AddRepo, listing it first in
+// the stars served. Starring an already starred repository is a no-op.
+func (s *Server) handleStar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fullName := strings.TrimPrefix(r.URL.Path, "/user/starred/")
+	for _, sr := range s.stars {
+		if strings.EqualFold(sr.Repo.FullName, fullName) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	for _, repo := range s.repos {
+		if strings.EqualFold(repo.FullName, fullName) {
+			starred := githubclient.StarredRepo{Repo: repo, StarredAt: time.Now().UTC()}
+			s.stars = append([]githubclient.StarredRepo{starred}, s.stars...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
+
+// handleFollowing serves all the followed users in a single page.
+func (s *Server) handleFollowing(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(append([]githubclient.User{}, s.following...))
+}
+
+// handleUser serves the authenticated user set with SetUser.
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.user)
+}
+
+// handleUserStarred serves /users/{login}/starred, all the stars in a
+// single page. Unknown users are not found.
+func (s *Server) handleUserStarred(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	login, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/users/"), "/")
+	stars, ok := s.userStars[login]
+	if !ok || rest != "starred" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(append([]githubclient.StarredRepo{}, stars...))
+}
+
+// handleRepos serves /repos/{owner}/{name}, for the starred repositories and
+// the ones added with AddRepo, /repos/{owner}/{name}/contents/{file} and
+// replaces their topics on PUT /repos/{owner}/{name}/topics. Only the first
+// README candidate is served for a repository.
+func (s *Server) handleRepos(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/repos/"), "/", 4)
+	if len(parts) == 2 {
+		s.handleRepo(w, r, parts[0]+"/"+parts[1])
+		return
+	}
+	if len(parts) == 3 && parts[2] == "topics" && r.Method == http.MethodPut {
+		s.handleTopics(w, r, parts[0]+"/"+parts[1])
+		return
+	}
+
+	if len(parts) != 4 || parts[2] != "contents" {
+		http.NotFound(w, r)
+		return
+	}
+
+	readme, ok := s.readmes[parts[0]+"/"+parts[1]]
+	if !ok || parts[3] != githubclient.ReadmeFiles[0] {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Write([]byte(readme))
+}
+
+// graphqlRepo matches the repository fields of the README queries sent by
+// githubclient, along with the variables holding the owner and name.
+var graphqlRepo = regexp.MustCompile(`(r\d+): repository\(owner: \$(\w+), name: \$(\w+)\)`)
+
+// handleGraphQL answers the README batch queries and the starred
+// repositories listings. Like the REST endpoint, only the first README
+// candidate is served for a repository.
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var req struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.points--
+	if strings.Contains(req.Query, "starredRepositories") {
+		s.handleStarredQuery(w, req.Variables)
+		return
+	}
+
+	data := map[string]interface{}{}
+	for _, m := range graphqlRepo.FindAllStringSubmatch(req.Query, -1) {
+		fullName := fmt.Sprint(req.Variables[m[2]]) + "/" + fmt.Sprint(req.Variables[m[3]])
+		files := map[string]interface{}{}
+		if readme, ok := s.readmes[fullName]; ok {
+			files["f0"] = map[string]interface{}{"text": readme, "isTruncated": false}
+		}
+		data[m[1]] = files
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+// handleStarredQuery serves the stars oldest first, the cursors being the
+// positions in the listing.
+func (s *Server) handleStarredQuery(w http.ResponseWriter, vars map[string]interface{}) {
+	start := 0
+	if after, ok := vars["after"].(string); ok {
+		n, err := strconv.Atoi(after)
+		if err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		start = n
+	}
+	first := s.PerPage
+	if n, ok := vars["first"].(float64); ok {
+		first = int(n)
+	}
+	start = min(start, len(s.stars))
+	end := min(start+first, len(s.stars))
+
+	var edges []interface{}
+	for i := start; i < end; i++ {
+		// The REST listing is most recently starred first.
+		sr := s.stars[len(s.stars)-1-i]
+		topics := []interface{}{}
+		for _, t := range sr.Repo.Topics {
+			topics = append(topics, map[string]interface{}{"topic": map[string]string{"name": t}})
+		}
+		var language interface{}
+		if sr.Repo.Language != "" {
+			language = map[string]string{"name": sr.Repo.Language}
+		}
+		edges = append(edges, map[string]interface{}{
+			"starredAt": sr.StarredAt,
+			"node": map[string]interface{}{
+				"databaseId":       sr.Repo.ID,
+				"name":             sr.Repo.Name,
+				"nameWithOwner":    sr.Repo.FullName,
+				"url":              sr.Repo.HTMLURL,
+				"description":      sr.Repo.Description,
+				"homepageUrl":      sr.Repo.Homepage,
+				"createdAt":        sr.Repo.CreatedAt,
+				"updatedAt":        sr.Repo.UpdatedAt,
+				"pushedAt":         sr.Repo.PushedAt,
+				"stargazerCount":   sr.Repo.StargazersCount,
+				"isTemplate":       sr.Repo.IsTemplate,
+				"isPrivate":        sr.Repo.Private,
+				"isArchived":       sr.Repo.Archived,
+				"primaryLanguage":  language,
+				"repositoryTopics": map[string]interface{}{"nodes": topics},
+			},
+		})
+	}
+
+	var endCursor interface{}
+	if end > start {
+		endCursor = strconv.Itoa(end)
+	}
+	data := map[string]interface{}{
+		"viewer": map[string]interface{}{
+			"starredRepositories": map[string]interface{}{
+				"pageInfo": map[string]interface{}{"endCursor": endCursor, "hasNextPage": end < len(s.stars)},
+				"edges":    edges,
+			},
+		},
+		"rateLimit": map[string]interface{}{
+			"cost":      1,
+			"remaining": s.points,
+			"resetAt":   time.Now().Add(time.Hour).UTC(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func (s *Server) handleRepo(w http.ResponseWriter, r *http.Request, fullName string) {
+	for _, repo := range s.allRepos() {
+		if strings.EqualFold(repo.FullName, fullName) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(repo)
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
+
+func (s *Server) handleTopics(w http.ResponseWriter, r *http.Request, fullName string) {
+	var body struct {
+		Names []string `json:"names"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	found := false
+	for i := range s.repos {
+		if strings.EqualFold(s.repos[i].FullName, fullName) {
+			s.repos[i].Topics, found = body.Names, true
+		}
+	}
+	for i := range s.stars {
+		if strings.EqualFold(s.stars[i].Repo.FullName, fullName) {
+			s.stars[i].Repo.Topics, found = body.Names, true
+		}
+	}
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"names": body.Names})
+}
+
+// allRepos returns the repositories added with AddRepo and the starred ones.
+func (s *Server) allRepos() []githubclient.Repository {
+	repos := append([]githubclient.Repository{}, s.repos...)
+	for _, sr := range s.stars {
+		repos = append(repos, sr.Repo)
+	}
+
+	return repos
+}
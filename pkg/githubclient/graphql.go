@@ -0,0 +1,288 @@
+package githubclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ReadmeBatcher is implemented by the clients able to fetch the READMEs of
+// several repositories in a single request.
+type ReadmeBatcher interface {
+	// Readmes returns the raw README contents of the repositories
+	// identified by fullNames (owner/name), keyed by full name.
+	// Repositories without a README are missing from the map.
+	Readmes(ctx context.Context, fullNames []string) (map[string]string, error)
+}
+
+// CursorLister is implemented by the clients able to list the starred
+// repositories oldest first, resuming the listing from a cursor.
+type CursorLister interface {
+	// StarredAfter returns up to first repositories starred after cursor,
+	// the EndCursor of a previous page, or the first ones when empty.
+	StarredAfter(ctx context.Context, cursor string, first int) (CursorPage, error)
+}
+
+// CursorPage is a page of starred repositories returned by StarredAfter.
+type CursorPage struct {
+	Repos []StarredRepo
+	// EndCursor resumes the listing after the page, empty when the page
+	// has no repositories.
+	EndCursor   string
+	HasNextPage bool
+	// RateLimit is the GraphQL rate limit status after the request.
+	RateLimit RateLimit
+}
+
+// RateLimit is the status of the GraphQL API rate limit, in points.
+type RateLimit struct {
+	// Cost is the number of points the request consumed.
+	Cost      int       `json:"cost"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+// starredQuery lists the starred repositories oldest first, with the fields
+// of the REST API Repository.
+const starredQuery = `query($after: String, $first: Int!) {
+  viewer {
+    starredRepositories(first: $first, after: $after, orderBy: {field: STARRED_AT, direction: ASC}) {
+      pageInfo { endCursor hasNextPage }
+      edges {
+        starredAt
+        node {
+          databaseId name nameWithOwner url description homepageUrl
+          createdAt updatedAt pushedAt stargazerCount
+          isTemplate isPrivate isArchived
+          primaryLanguage { name }
+          repositoryTopics(first: 50) { nodes { topic { name } } }
+        }
+      }
+    }
+  }
+  rateLimit { cost remaining resetAt }
+}`
+
+type graphqlStarred struct {
+	Viewer struct {
+		StarredRepositories struct {
+			PageInfo struct {
+				EndCursor   *string `json:"endCursor"`
+				HasNextPage bool    `json:"hasNextPage"`
+			} `json:"pageInfo"`
+			Edges []struct {
+				StarredAt time.Time   `json:"starredAt"`
+				Node      graphqlRepo `json:"node"`
+			} `json:"edges"`
+		} `json:"starredRepositories"`
+	} `json:"viewer"`
+	RateLimit RateLimit `json:"rateLimit"`
+}
+
+type graphqlRepo struct {
+	DatabaseID      int       `json:"databaseId"`
+	Name            string    `json:"name"`
+	NameWithOwner   string    `json:"nameWithOwner"`
+	URL             string    `json:"url"`
+	Description     string    `json:"description"`
+	HomepageURL     string    `json:"homepageUrl"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+	PushedAt        time.Time `json:"pushedAt"`
+	StargazerCount  int       `json:"stargazerCount"`
+	IsTemplate      bool      `json:"isTemplate"`
+	IsPrivate       bool      `json:"isPrivate"`
+	IsArchived      bool      `json:"isArchived"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	RepositoryTopics struct {
+		Nodes []struct {
+			Topic struct {
+				Name string `json:"name"`
+			} `json:"topic"`
+		} `json:"nodes"`
+	} `json:"repositoryTopics"`
+}
+
+// repository converts the GraphQL repository to the REST representation.
+func (r graphqlRepo) repository() Repository {
+	repo := Repository{
+		ID:              r.DatabaseID,
+		Name:            r.Name,
+		HTMLURL:         r.URL,
+		Description:     r.Description,
+		CreatedAt:       r.CreatedAt,
+		UpdatedAt:       r.UpdatedAt,
+		PushedAt:        r.PushedAt,
+		StargazersCount: r.StargazerCount,
+		FullName:        r.NameWithOwner,
+		IsTemplate:      r.IsTemplate,
+		Private:         r.IsPrivate,
+		Archived:        r.IsArchived,
+		Homepage:        r.HomepageURL,
+		Topics:          []string{},
+	}
+	if r.PrimaryLanguage != nil {
+		repo.Language = r.PrimaryLanguage.Name
+	}
+	for _, n := range r.RepositoryTopics.Nodes {
+		repo.Topics = append(repo.Topics, n.Topic.Name)
+	}
+
+	return repo
+}
+
+// StarredAfter implements CursorLister using the GraphQL API.
+func (c *HTTPClient) StarredAfter(ctx context.Context, cursor string, first int) (CursorPage, error) {
+	vars := map[string]interface{}{"first": first}
+	if cursor != "" {
+		vars["after"] = cursor
+	}
+
+	var data graphqlStarred
+	if err := c.graphql(ctx, starredQuery, vars, &data); err != nil {
+		return CursorPage{}, err
+	}
+
+	starred := data.Viewer.StarredRepositories
+	page := CursorPage{
+		HasNextPage: starred.PageInfo.HasNextPage,
+		RateLimit:   data.RateLimit,
+	}
+	if starred.PageInfo.EndCursor != nil {
+		page.EndCursor = *starred.PageInfo.EndCursor
+	}
+	for _, e := range starred.Edges {
+		page.Repos = append(page.Repos, StarredRepo{Repo: e.Node.repository(), StarredAt: e.StarredAt})
+	}
+
+	return page, nil
+}
+
+// graphqlBlob is a file of the default branch, null when missing.
+type graphqlBlob struct {
+	Text        *string `json:"text"`
+	IsTruncated bool    `json:"isTruncated"`
+}
+
+type graphqlError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Readmes implements ReadmeBatcher, fetching the README candidates of all
+// the repositories with a single GraphQL query. READMEs too large to be
+// returned by the GraphQL API are fetched using the REST API.
+func (c *HTTPClient) Readmes(ctx context.Context, fullNames []string) (map[string]string, error) {
+	var query strings.Builder
+	vars := map[string]interface{}{}
+	query.WriteString("query(")
+	for i, fullName := range fullNames {
+		owner, name, ok := strings.Cut(fullName, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid repository name %q", fullName)
+		}
+		vars[fmt.Sprintf("o%d", i)] = owner
+		vars[fmt.Sprintf("n%d", i)] = name
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		fmt.Fprintf(&query, "$o%d: String!, $n%d: String!", i, i)
+	}
+	query.WriteString(") {\n")
+	for i := range fullNames {
+		fmt.Fprintf(&query, "  r%d: repository(owner: $o%d, name: $n%d) {\n", i, i, i)
+		for j, file := range ReadmeFiles {
+			fmt.Fprintf(&query, "    f%d: object(expression: %q) { ... on Blob { text isTruncated } }\n", j, "HEAD:"+file)
+		}
+		query.WriteString("  }\n")
+	}
+	query.WriteString("}")
+
+	var data map[string]map[string]*graphqlBlob
+	if err := c.graphql(ctx, query.String(), vars, &data); err != nil {
+		return nil, err
+	}
+
+	readmes := map[string]string{}
+	for i, fullName := range fullNames {
+		files := data[fmt.Sprintf("r%d", i)]
+		for j := range ReadmeFiles {
+			blob := files[fmt.Sprintf("f%d", j)]
+			if blob == nil || blob.Text == nil {
+				continue
+			}
+			if blob.IsTruncated {
+				readme, err := c.Readme(ctx, fullName)
+				if err != nil {
+					return nil, err
+				}
+				readmes[fullName] = readme
+				break
+			}
+			readmes[fullName] = *blob.Text
+			break
+		}
+	}
+
+	return readmes, nil
+}
+
+// graphql runs a GraphQL query, decoding the data returned into v.
+// Repositories that can't be resolved don't make the query fail, their
+// fields are null instead.
+func (c *HTTPClient) graphql(ctx context.Context, query string, vars map[string]interface{}, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequestWithBody(ctx, http.MethodPost, c.graphqlURL(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("querying %s: %s", c.graphqlURL(), resp.Status)
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphqlError  `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	for _, e := range result.Errors {
+		if e.Type != "NOT_FOUND" {
+			return fmt.Errorf("graphql: %s", e.Message)
+		}
+		c.Logger.Debugf("graphql: %s", e.Message)
+	}
+
+	return json.Unmarshal(result.Data, v)
+}
+
+// graphqlURL returns the GraphQL endpoint, /api/graphql for GitHub
+// Enterprise servers using /api/v3 for the REST API.
+func (c *HTTPClient) graphqlURL() string {
+	if base, ok := strings.CutSuffix(c.BaseURL, "/v3"); ok {
+		return base + "/graphql"
+	}
+
+	return c.BaseURL + "/graphql"
+}
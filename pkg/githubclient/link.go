@@ -0,0 +1,184 @@
+package githubclient
+
+import "strings"
+
+// webLink is a link of a Link header (RFC 8288).
+type webLink struct {
+	URL string
+	// Rels are the relation types, lowercased. rel can hold several,
+	// separated by spaces.
+	Rels []string
+	// Params are the other parameters, keyed by lowercased name.
+	Params map[string]string
+}
+
+// hasRel reports whether the link has the relation type rel.
+func (l webLink) hasRel(rel string) bool {
+	for _, r := range l.Rels {
+		if r == strings.ToLower(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLinks parses the values of Link headers. Commas and semicolons in the
+// URLs and quoted parameter values are kept, and the whitespace around the
+// separators is optional. Links without a URL are skipped, and so are the
+// malformed parameters, the rest of the link being kept.
+func parseLinks(headers ...string) []webLink {
+	var links []webLink
+	for _, h := range headers {
+		p := linkParser{s: h}
+		for {
+			l, ok := p.link()
+			if ok {
+				links = append(links, l)
+			}
+			if !p.skipPast(',') {
+				break
+			}
+		}
+	}
+
+	return links
+}
+
+// findLink returns the URL of the first link with the relation type rel in
+// the Link headers, empty if there's none.
+func findLink(rel string, headers ...string) string {
+	for _, l := range parseLinks(headers...) {
+		if l.hasRel(rel) {
+			return l.URL
+		}
+	}
+	return ""
+}
+
+// linkParser reads the links of a Link header value.
+type linkParser struct {
+	s   string
+	pos int
+}
+
+func (p *linkParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipPast moves past the next c, reporting whether there was one.
+func (p *linkParser) skipPast(c byte) bool {
+	i := strings.IndexByte(p.s[p.pos:], c)
+	if i < 0 {
+		p.pos = len(p.s)
+		return false
+	}
+	p.pos += i + 1
+	return true
+}
+
+// link reads a link up to the next link separator, leaving the position on
+// the separator.
+func (p *linkParser) link() (webLink, bool) {
+	p.skipSpace()
+	if p.pos >= len(p.s) || p.s[p.pos] != '<' {
+		p.skipTo(',')
+		return webLink{}, false
+	}
+	end := strings.IndexByte(p.s[p.pos:], '>')
+	if end < 0 {
+		p.pos = len(p.s)
+		return webLink{}, false
+	}
+	l := webLink{URL: strings.TrimSpace(p.s[p.pos+1 : p.pos+end]), Params: map[string]string{}}
+	p.pos += end + 1
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) || p.s[p.pos] == ',' {
+			break
+		}
+		if p.s[p.pos] != ';' {
+			// Garbage after the URL or a parameter, skip to the next one.
+			p.skipToAny(";,")
+			continue
+		}
+		p.pos++
+		name, value, ok := p.param()
+		if !ok {
+			continue
+		}
+		if name == "rel" {
+			// Only the first rel parameter counts.
+			if l.Rels == nil {
+				l.Rels = strings.Fields(strings.ToLower(value))
+			}
+			continue
+		}
+		if _, dup := l.Params[name]; !dup {
+			l.Params[name] = value
+		}
+	}
+
+	return l, l.URL != ""
+}
+
+// param reads a name=value parameter, the value a token or a quoted string.
+// Parameters without a value have an empty one.
+func (p *linkParser) param() (name, value string, ok bool) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune("=;, \t", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	name = strings.ToLower(p.s[start:p.pos])
+	if name == "" {
+		return "", "", false
+	}
+	p.skipSpace()
+	if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+		return name, "", true
+	}
+	p.pos++
+	p.skipSpace()
+
+	if p.pos < len(p.s) && p.s[p.pos] == '"' {
+		var b strings.Builder
+		for p.pos++; p.pos < len(p.s); p.pos++ {
+			switch c := p.s[p.pos]; c {
+			case '\\':
+				if p.pos+1 < len(p.s) {
+					p.pos++
+					b.WriteByte(p.s[p.pos])
+				}
+			case '"':
+				p.pos++
+				return name, b.String(), true
+			default:
+				b.WriteByte(c)
+			}
+		}
+		// Unterminated quoted string.
+		return "", "", false
+	}
+
+	start = p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(";, \t", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return name, p.s[start:p.pos], true
+}
+
+// skipTo moves to the next c, or to the end.
+func (p *linkParser) skipTo(c byte) {
+	p.skipToAny(string(c))
+}
+
+func (p *linkParser) skipToAny(chars string) {
+	if i := strings.IndexAny(p.s[p.pos:], chars); i >= 0 {
+		p.pos += i
+	} else {
+		p.pos = len(p.s)
+	}
+}
@@ -0,0 +1,158 @@
+package githubclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []webLink
+	}{
+		{
+			name:   "quoted rel",
+			header: `<https://api.github.com/user/starred?page=2>; rel="next"`,
+			want: []webLink{
+				{URL: "https://api.github.com/user/starred?page=2", Rels: []string{"next"}, Params: map[string]string{}},
+			},
+		},
+		{
+			name:   "unquoted rel",
+			header: `<https://api.github.com/user/starred?page=2>; rel=next`,
+			want: []webLink{
+				{URL: "https://api.github.com/user/starred?page=2", Rels: []string{"next"}, Params: map[string]string{}},
+			},
+		},
+		{
+			name:   "several rels",
+			header: `<https://example.com/5>; rel="last  Next"`,
+			want: []webLink{
+				{URL: "https://example.com/5", Rels: []string{"last", "next"}, Params: map[string]string{}},
+			},
+		},
+		{
+			name:   "commas and semicolons in the URL",
+			header: `<https://example.com/a,b;c?q=1,2>;rel="next", <https://example.com/last>; rel=last`,
+			want: []webLink{
+				{URL: "https://example.com/a,b;c?q=1,2", Rels: []string{"next"}, Params: map[string]string{}},
+				{URL: "https://example.com/last", Rels: []string{"last"}, Params: map[string]string{}},
+			},
+		},
+		{
+			name:   "quoted parameters",
+			header: `<https://example.com/1>; title="a, \"b\"; c"; rel="prev"`,
+			want: []webLink{
+				{URL: "https://example.com/1", Rels: []string{"prev"}, Params: map[string]string{"title": `a, "b"; c`}},
+			},
+		},
+		{
+			name:   "first rel wins",
+			header: `<https://example.com/1>; rel=prev; rel=next`,
+			want: []webLink{
+				{URL: "https://example.com/1", Rels: []string{"prev"}, Params: map[string]string{}},
+			},
+		},
+		{
+			name:   "malformed links skipped",
+			header: `garbage, <>; rel=next, <https://example.com/2>; ; rel=next`,
+			want: []webLink{
+				{URL: "https://example.com/2", Rels: []string{"next"}, Params: map[string]string{}},
+			},
+		},
+		{
+			name:   "empty",
+			header: "",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseLinks(tt.header)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLinks(%q) = %#v, want %#v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetNextPageURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{
+			name:   "next",
+			header: `<https://api.github.com/user/starred?page=2>; rel="next", <https://api.github.com/user/starred?page=5>; rel="last"`,
+			want:   "https://api.github.com/user/starred?page=2",
+		},
+		{
+			name:   "next among several rels",
+			header: `<https://api.github.com/user/starred?page=5>; rel="next last"`,
+			want:   "https://api.github.com/user/starred?page=5",
+		},
+		{
+			name:   "no next on the last page",
+			header: `<https://api.github.com/user/starred?page=1>; rel="first", <https://api.github.com/user/starred?page=4>; rel="prev"`,
+			want:   "",
+		},
+		{
+			name:   "nextish rels don't match",
+			header: `<https://example.com/2>; rel="next-archive"`,
+			want:   "",
+		},
+		{
+			name:   "no header",
+			header: "",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getNextPageURL(tt.header); got != tt.want {
+				t.Errorf("getNextPageURL(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPageCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{
+			name:   "last page",
+			header: `<https://api.github.com/user/starred?per_page=100&page=2>; rel="next", <https://api.github.com/user/starred?per_page=100&page=7>; rel="last"`,
+			want:   7,
+		},
+		{
+			name:   "last without a page parameter",
+			header: `<https://api.github.com/user/starred?per_page=100>; rel="last"`,
+			want:   0,
+		},
+		{
+			name:   "invalid page parameter",
+			header: `<https://api.github.com/user/starred?page=x>; rel="last"`,
+			want:   0,
+		},
+		{
+			name:   "no last link",
+			header: `<https://api.github.com/user/starred?page=2>; rel="next"`,
+			want:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getPageCount(tt.header); got != tt.want {
+				t.Errorf("getPageCount(%q) = %d, want %d", tt.header, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package githubclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrNotRecorded is returned by Replayer for the requests missing from the
+// recording.
+var ErrNotRecorded = errors.New("request not recorded")
+
+// exchange is a request and its response, as saved by Recorder.
+type exchange struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        string      `json:"body"`
+}
+
+// exchangeKey identifies a request by method, path, query and body. The
+// host is left out so recordings can be replayed against any base URL, and
+// so are the request headers, which hold the token.
+func exchangeKey(method, uri string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, uri)
+	h.Write(body)
+
+	return fmt.Sprintf("%x", h.Sum(nil))[:16]
+}
+
+// exchangeCounter numbers the occurrences of identical requests, so
+// retries replay the responses in the order they were recorded.
+type exchangeCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *exchangeCounter) next(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = map[string]int{}
+	}
+	c.counts[key]++
+
+	return c.counts[key]
+}
+
+func exchangePath(dir, key string, n int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s-%d.json", key, n))
+}
+
+// readRequestBody returns the body of req, restoring it so it can still be
+// sent.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(b))
+
+	return b, nil
+}
+
+// Recorder is an http.RoundTripper saving every request and response to
+// Dir as a JSON file, to be replayed offline by Replayer. Request headers
+// are not saved.
+type Recorder struct {
+	Dir string
+	// Transport sends the requests, http.DefaultTransport when nil.
+	Transport http.RoundTripper
+
+	counter exchangeCounter
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	key := exchangeKey(req.Method, req.URL.RequestURI(), reqBody)
+	ex := exchange{
+		Method:      req.Method,
+		URL:         req.URL.RequestURI(),
+		RequestBody: string(reqBody),
+		Status:      resp.StatusCode,
+		Header:      resp.Header,
+		Body:        string(body),
+	}
+	b, err := json.MarshalIndent(ex, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(r.Dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(exchangePath(r.Dir, key, r.counter.next(key)), b, 0o600); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Replayer is an http.RoundTripper answering requests with the responses
+// saved by Recorder in Dir, without network access. Identical requests get
+// the responses in the order they were recorded, the last one once they
+// run out. Requests missing from the recording fail with ErrNotRecorded.
+type Replayer struct {
+	Dir string
+
+	counter exchangeCounter
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	key := exchangeKey(req.Method, req.URL.RequestURI(), reqBody)
+	var path string
+	var b []byte
+	for n := r.counter.next(key); n > 0; n-- {
+		path = exchangePath(r.Dir, key, n)
+		b, err = os.ReadFile(path)
+		if !errors.Is(err, os.ErrNotExist) {
+			break
+		}
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.RequestURI(), ErrNotRecorded)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ex exchange
+	if err := json.Unmarshal(b, &ex); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", ex.Status, http.StatusText(ex.Status)),
+		StatusCode:    ex.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        ex.Header,
+		Body:          io.NopCloser(strings.NewReader(ex.Body)),
+		ContentLength: int64(len(ex.Body)),
+		Request:       req,
+	}, nil
+}
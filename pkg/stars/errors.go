@@ -0,0 +1,126 @@
+package stars
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// Kinds of the errors of a sync run.
+const (
+	// ErrorInsert is a new star the database rejected.
+	ErrorInsert = "insert"
+	// ErrorReadme is a README that couldn't be fetched.
+	ErrorReadme = "readme"
+	// ErrorDecode is an API response that couldn't be decoded.
+	ErrorDecode = "decode"
+	// ErrorSync is the error stopping the run.
+	ErrorSync = "sync"
+)
+
+// stateLastRun is when the last run whose errors were saved started.
+const stateLastRun = "last_run_at"
+
+// keptRuns is the number of runs whose errors are kept.
+const keptRuns = 20
+
+// SyncError is an error of a sync run, stored in the sync_errors table to be
+// reviewed with report errors.
+type SyncError struct {
+	ID    int       `db:"id,omitempty" json:"-"`
+	RunAt time.Time `db:"run_at" json:"run_at"`
+	Kind  string    `db:"kind" json:"kind"`
+	// Item is the full name of the repository, when the error is about one.
+	Item       string    `db:"item" json:"item,omitempty"`
+	RepoID     int       `db:"repo_id" json:"repo_id,omitempty"`
+	Error      string    `db:"error" json:"error"`
+	OccurredAt time.Time `db:"occurred_at" json:"occurred_at"`
+}
+
+// reportError records a non-fatal error about the repository item. Errors
+// decoding API responses are reported as such, whatever kind is given.
+func (s *Syncer) reportError(kind, item string, repoID int, err error) {
+	s.report = append(s.report, SyncError{
+		Kind:       errorKind(kind, err),
+		Item:       item,
+		RepoID:     repoID,
+		Error:      err.Error(),
+		OccurredAt: time.Now().UTC(),
+	})
+
+	switch kind {
+	case ErrorInsert:
+		err = fmt.Errorf("storing %s: %w", item, err)
+	case ErrorReadme:
+		err = fmt.Errorf("fetching README for %s: %w", item, err)
+	}
+	s.log.Debug(err)
+	s.stats.Errors = append(s.stats.Errors, err)
+}
+
+// errorKind returns ErrorDecode for the JSON decoding errors, kind
+// otherwise.
+func errorKind(kind string, err error) string {
+	var syntax *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+	if errors.As(err, &syntax) || errors.As(err, &typ) {
+		return ErrorDecode
+	}
+	return kind
+}
+
+// SaveReport stores the errors of the run started at runAt, along with
+// runErr, the error stopping it, if any, so they can be reviewed with
+// report errors once the logs scrolled away. The errors of the older runs
+// are deleted, keeping the last ones only.
+func (s *Syncer) SaveReport(runAt time.Time, runErr error) error {
+	runAt = runAt.UTC()
+	if runErr != nil {
+		s.report = append(s.report, SyncError{
+			Kind:       errorKind(ErrorSync, runErr),
+			Error:      runErr.Error(),
+			OccurredAt: time.Now().UTC(),
+		})
+	}
+
+	err := s.sess.Tx(func(tx db.Session) error {
+		errs := tx.Collection("sync_errors")
+		for _, e := range s.report {
+			e.RunAt = runAt
+			if _, err := errs.Insert(e); err != nil {
+				return err
+			}
+		}
+		if err := store.SetState(tx, stateLastRun, runAt.Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+
+		_, err := tx.SQL().Exec(`DELETE FROM sync_errors WHERE run_at NOT IN (
+			SELECT DISTINCT run_at FROM sync_errors ORDER BY run_at DESC LIMIT ?)`, keptRuns)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if n := len(s.report); n > 0 {
+		s.log.Warnf("%d errors during the run, see gh-stars-exporter report errors", n)
+	}
+	s.report = nil
+
+	return nil
+}
+
+// LastRun returns when the last run whose errors were saved started, the
+// zero time when none was.
+func LastRun(sess db.Session) (time.Time, error) {
+	v, err := store.GetState(sess, stateLastRun)
+	if err != nil || v == "" {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, v)
+}
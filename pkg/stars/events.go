@@ -0,0 +1,30 @@
+package stars
+
+import (
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// Event types.
+const (
+	// EventStar is a repository starred since the previous sync, starred
+	// again or a bookmark that became a star.
+	EventStar = "star"
+	// EventUnstar is a repository unstarred since the previous sync.
+	EventUnstar = "unstar"
+)
+
+// Event is a change of the star list seen by a sync.
+type Event struct {
+	Type string
+	// Repo is the repository as stored, without README.
+	Repo store.Repository
+	Time time.Time
+}
+
+func (s *Syncer) addEvent(typ string, repo store.Repository) {
+	repo.Readme.String = ""
+	repo.PathBookmarks = nil
+	s.stats.Events = append(s.stats.Events, Event{Type: typ, Repo: repo, Time: time.Now().UTC()})
+}
@@ -0,0 +1,164 @@
+package stars
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// Kinds of items a sync can fail to fetch.
+const (
+	FailurePage   = "page"
+	FailureReadme = "readme"
+)
+
+// Failure is an item a sync failed to fetch, stored in the sync_failures
+// table so it can be re-attempted with RetryFailed.
+type Failure struct {
+	Kind string `db:"kind"`
+	// Item is the page number or the upstream full name of the repository.
+	Item     string    `db:"item"`
+	RepoID   int       `db:"repo_id"`
+	Error    string    `db:"error"`
+	FailedAt time.Time `db:"failed_at"`
+}
+
+func (s *Syncer) recordFailure(kind, item string, repoID int, err error) {
+	s.failures = append(s.failures, Failure{
+		Kind:     kind,
+		Item:     item,
+		RepoID:   repoID,
+		Error:    err.Error(),
+		FailedAt: time.Now().UTC(),
+	})
+}
+
+// saveFailures stores the failures recorded since the last save, after
+// deleting the stored failures of the given kinds, which were re-attempted.
+func (s *Syncer) saveFailures(retried ...string) error {
+	err := s.sess.Tx(func(tx db.Session) error {
+		if len(retried) > 0 {
+			if err := tx.Collection("sync_failures").Find(db.Cond{"kind IN": retried}).Delete(); err != nil {
+				return err
+			}
+		}
+
+		for _, f := range s.failures {
+			_, err := tx.SQL().Exec(
+				`INSERT INTO sync_failures (kind, item, repo_id, error, failed_at) VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT(kind, item) DO UPDATE SET repo_id = excluded.repo_id, error = excluded.error, failed_at = excluded.failed_at`,
+				f.Kind, f.Item, f.RepoID, f.Error, f.FailedAt,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		if len(s.failures) > 0 {
+			s.log.Warnf("%d items failed, re-attempt them with sync --retry-failed", len(s.failures))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.failures = nil
+
+	return nil
+}
+
+// walkStarred calls fn with every page of starred repositories, recording
+// the page the listing failed at so it can be resumed later.
+func (s *Syncer) walkStarred(ctx context.Context, opts githubclient.StarredOptions, fn func(githubclient.Page) error) error {
+	next := max(opts.StartPage, 1)
+	var fnErr error
+	err := s.gh.StarredRepos(ctx, opts, func(page githubclient.Page) error {
+		next = page.Number + 1
+		fnErr = fn(page)
+		return fnErr
+	})
+	if err != nil && fnErr == nil && ctx.Err() == nil && !errors.Is(err, githubclient.ErrNotModified) {
+		s.recordFailure(FailurePage, strconv.Itoa(next), 0, err)
+	}
+
+	return err
+}
+
+// RetryFailed re-attempts the items stored in the sync_failures table:
+// listings are resumed at the page they failed at and the READMEs that
+// failed are fetched again.
+func (s *Syncer) RetryFailed(ctx context.Context) error {
+	var stored []Failure
+	if err := s.sess.Collection("sync_failures").Find().OrderBy("kind", "failed_at").All(&stored); err != nil {
+		return err
+	}
+	if len(stored) == 0 {
+		s.log.Info("No failed items to retry")
+		return nil
+	}
+
+	stars := s.sess.Collection("starred_repos")
+	startPage := 0
+	for _, f := range stored {
+		switch f.Kind {
+		case FailurePage:
+			n, err := strconv.Atoi(f.Item)
+			if err != nil {
+				s.log.Warnf("Ignoring invalid failed page %q", f.Item)
+				continue
+			}
+			if startPage == 0 || n < startPage {
+				startPage = n
+			}
+		case FailureReadme:
+			res := stars.Find(f.RepoID)
+			var r store.Repository
+			err := res.One(&r)
+			if errors.Is(err, db.ErrNoMoreRows) {
+				s.log.Debugf("Repository %s is gone, not retrying its README", f.Item)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			s.log.Infof("Retrying README for %s", f.Item)
+			if s.FetchMissingReadme(ctx, f.Item, &r) {
+				s.stats.UpdatedStars++
+			}
+			if err := res.Update(r); err != nil {
+				return err
+			}
+		}
+	}
+
+	if startPage > 0 {
+		s.log.Infof("Resuming the star listing at page %d", startPage)
+		writer := s.newWriter()
+		err := s.walkStarred(ctx, githubclient.StarredOptions{StartPage: startPage}, func(page githubclient.Page) error {
+			s.log.Infof("Fetching stars... (page %d)", page.Number)
+			for _, sr := range page.Repos {
+				if err := s.syncRepo(ctx, stars, writer, RepoFromGitHub(sr)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if ferr := writer.Flush(); ferr != nil && err == nil {
+			err = ferr
+		}
+		if rerr := s.saveRefreshed(); rerr != nil && err == nil {
+			err = rerr
+		}
+		if err != nil {
+			return errors.Join(err, s.saveFailures(FailurePage, FailureReadme))
+		}
+	}
+
+	return s.saveFailures(FailurePage, FailureReadme)
+}
@@ -0,0 +1,68 @@
+package stars
+
+import (
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// Snapshot is the stargazers count of a repository recorded by a sync,
+// stored in the stargazers_history table. Counts are only recorded when
+// they changed since the previous snapshot of the repository.
+type Snapshot struct {
+	RepoID          int       `db:"repo_id"`
+	StargazersCount int       `db:"stargazers_count"`
+	RecordedAt      time.Time `db:"recorded_at"`
+}
+
+// recordStargazers keeps the upstream stargazers count of repo for the
+// snapshot saved at the end of the sync.
+func (s *Syncer) recordStargazers(repo store.Repository) {
+	if s.stargazers == nil {
+		s.stargazers = map[int]int{}
+	}
+	s.stargazers[repo.ID] = repo.StargazersCount
+}
+
+// saveStargazers stores the stargazers counts recorded since the last save
+// that changed since the latest snapshot of each repository.
+func (s *Syncer) saveStargazers() error {
+	if len(s.stargazers) == 0 {
+		return nil
+	}
+
+	var latest []Snapshot
+	err := s.sess.SQL().
+		Select("repo_id", "stargazers_count").
+		From("stargazers_history AS h").
+		Where("recorded_at = (SELECT MAX(recorded_at) FROM stargazers_history WHERE repo_id = h.repo_id)").
+		All(&latest)
+	if err != nil {
+		return err
+	}
+	previous := map[int]int{}
+	for _, sn := range latest {
+		previous[sn.RepoID] = sn.StargazersCount
+	}
+
+	now := time.Now().UTC()
+	err = s.sess.Tx(func(tx db.Session) error {
+		for id, count := range s.stargazers {
+			if prev, ok := previous[id]; ok && prev == count {
+				continue
+			}
+			sn := Snapshot{RepoID: id, StargazersCount: count, RecordedAt: now}
+			if _, err := tx.Collection("stargazers_history").Insert(sn); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.stargazers = nil
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package stars
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// Policy declares what syncs fetch and keep beyond the Options, usually
+// loaded from a policy file. The zero value changes nothing.
+type Policy struct {
+	// ReadmeLanguages restricts the READMEs fetched to the repositories
+	// written in these languages, case insensitive. Empty fetches them all.
+	ReadmeLanguages []string
+	// PruneUnstarredAfter removes the stars unstarred longer ago than this
+	// after complete syncs, 0 keeps them.
+	PruneUnstarredAfter time.Duration
+	// RefreshEvery walks the full star list, refreshing the metadata of
+	// every star, when a star wasn't refreshed for this long, even if the
+	// list didn't change upstream. 0 refreshes only when it changed.
+	RefreshEvery time.Duration
+}
+
+// readmeLanguage reports whether the policy allows fetching the READMEs of
+// the repositories written in language.
+func (p Policy) readmeLanguage(language string) bool {
+	if len(p.ReadmeLanguages) == 0 {
+		return true
+	}
+	for _, l := range p.ReadmeLanguages {
+		if strings.EqualFold(l, language) {
+			return true
+		}
+	}
+	return false
+}
+
+// readmeWanted reports whether the README of r may be stored, by the
+// private repositories configuration and the policy.
+func (s *Syncer) readmeWanted(r store.Repository) bool {
+	if r.Private && !s.opts.Private.Readme {
+		return false
+	}
+	return s.opts.Policy.readmeLanguage(r.Language)
+}
+
+// refreshDue reports whether a stored star wasn't refreshed within the
+// policy RefreshEvery, stars never refreshed included.
+func (s *Syncer) refreshDue() (bool, error) {
+	if s.opts.Policy.RefreshEvery == 0 {
+		return false, nil
+	}
+	before := time.Now().UTC().Add(-s.opts.Policy.RefreshEvery)
+
+	return s.sess.Collection("starred_repos").
+		Find(db.Cond{"unstarred_at IS": nil}).
+		And(db.Raw("(refreshed_at IS NULL OR julianday(refreshed_at) < julianday(?))", before)).
+		Exists()
+}
+
+// pruneUnstarred removes the stars unstarred longer ago than the policy
+// PruneUnstarredAfter.
+func (s *Syncer) pruneUnstarred() error {
+	if s.opts.Policy.PruneUnstarredAfter == 0 {
+		return nil
+	}
+	before := time.Now().UTC().Add(-s.opts.Policy.PruneUnstarredAfter)
+
+	res := s.sess.Collection("starred_repos").
+		Find(db.Raw("unstarred_at IS NOT NULL AND julianday(unstarred_at) < julianday(?)", before))
+	n, err := res.Count()
+	if err != nil || n == 0 {
+		return err
+	}
+	if err := res.Delete(); err != nil {
+		return err
+	}
+	s.stats.Pruned = int(n)
+	s.log.Infof("Pruned %d stars unstarred more than %s ago", n, days(s.opts.Policy.PruneUnstarredAfter))
+
+	return nil
+}
+
+// days formats d in days when a whole number of them.
+func days(d time.Duration) string {
+	if d%(24*time.Hour) != 0 {
+		return d.String()
+	}
+	return fmt.Sprintf("%dd", d/(24*time.Hour))
+}
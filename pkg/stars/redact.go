@@ -0,0 +1,37 @@
+package stars
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// redactPrivate strips the fields of a private repository that cfg doesn't
+// allow to store. Public repositories are left untouched.
+func redactPrivate(repo *store.Repository, cfg PrivateConfig) {
+	if !repo.Private {
+		return
+	}
+
+	if !cfg.Readme {
+		repo.Readme = store.NullString{}
+		repo.ReadmeLanguage = ""
+	}
+
+	if !cfg.Description {
+		repo.Description = ""
+	}
+
+	if !cfg.Topics {
+		repo.Topics = nil
+	}
+
+	if cfg.HashNames {
+		hash := fmt.Sprintf("%x", sha256.Sum256([]byte(repo.FullName)))[:16]
+		repo.Name = hash
+		repo.FullName = "private/" + hash
+		repo.HTMLURL = ""
+		repo.Homepage = ""
+	}
+}
@@ -0,0 +1,73 @@
+package stars
+
+import (
+	"context"
+	"flag"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient/githubtest"
+)
+
+var update = flag.Bool("update", false, "Record the replayed GitHub API responses in testdata again")
+
+// replayDir holds the responses of a sync recorded from the fake server.
+var replayDir = filepath.Join("testdata", "replay")
+
+// recordSync records the responses of a sync of two pages of stars, with
+// READMEs, to replayDir.
+func recordSync(t *testing.T) {
+	t.Helper()
+	if err := os.RemoveAll(replayDir); err != nil {
+		t.Fatal(err)
+	}
+	srv := githubtest.NewServer(
+		starred(1, "owner/one", 1),
+		starred(2, "owner/two", 2),
+		starred(3, "owner/three", 3),
+	)
+	defer srv.Close()
+	srv.PerPage = 2
+	srv.SetReadme("owner/three", "# Three")
+
+	gh := srv.Client()
+	gh.HTTP = &http.Client{Transport: &githubclient.Recorder{Dir: replayDir, Transport: srv.Server.Client().Transport}}
+	s := New(gh, testDB(t), Options{Readmes: true, Logger: log.New(io.Discard)})
+	if err := s.Sync(context.Background()); err != nil {
+		t.Fatalf("recording sync: %s", err)
+	}
+}
+
+func TestSyncReplay(t *testing.T) {
+	if *update {
+		recordSync(t)
+	}
+
+	// The recording is replayed offline, whatever the base URL.
+	gh := githubclient.New("")
+	gh.HTTP = &http.Client{Transport: &githubclient.Replayer{Dir: replayDir}}
+	sess := testDB(t)
+	s := New(gh, sess, Options{Readmes: true, Logger: log.New(io.Discard)})
+	if err := s.Sync(context.Background()); err != nil {
+		t.Fatalf("replaying sync: %s", err)
+	}
+
+	if stats := s.Stats(); stats.NewStars != 3 {
+		t.Errorf("new stars = %d, want 3", stats.NewStars)
+	}
+	repos := stored(t, sess)
+	if len(repos) != 3 {
+		t.Fatalf("stored %d repositories, want 3", len(repos))
+	}
+	if r := repos["owner/three"]; r.Readme.String != "# Three" {
+		t.Errorf("owner/three README = %+v, want # Three", r.Readme)
+	}
+	if r := repos["owner/one"]; r.Readme.Valid {
+		t.Errorf("owner/one README = %+v, want none", r.Readme)
+	}
+}
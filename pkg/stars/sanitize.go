@@ -0,0 +1,87 @@
+package stars
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// Truncation counts the changes made to the upstream data before it's
+// stored.
+type Truncation struct {
+	Readmes      int   `json:"readmes"`
+	ReadmeBytes  int64 `json:"readme_bytes"`
+	Descriptions int   `json:"descriptions"`
+	Topics       int   `json:"topics"`
+	InvalidUTF8  int   `json:"invalid_utf8"`
+}
+
+// Any reports whether anything was changed.
+func (t Truncation) Any() bool {
+	return t != Truncation{}
+}
+
+// sanitizeRepo replaces invalid UTF-8 sequences and enforces the limits on
+// a repository before it's stored, so pathological upstream data doesn't end
+// up in the database and exports.
+func (t *Truncation) sanitizeRepo(repo *store.Repository, limits LimitsConfig) {
+	repo.Name = t.sanitizeString(repo.Name)
+	repo.FullName = t.sanitizeString(repo.FullName)
+	repo.HTMLURL = t.sanitizeString(repo.HTMLURL)
+	repo.Homepage = t.sanitizeString(repo.Homepage)
+	repo.Language = t.sanitizeString(repo.Language)
+	repo.Description = t.sanitizeString(repo.Description)
+	for i, topic := range repo.Topics {
+		repo.Topics[i] = t.sanitizeString(topic)
+	}
+
+	if limits.MaxDescription > 0 && utf8.RuneCountInString(repo.Description) > limits.MaxDescription {
+		repo.Description = string([]rune(repo.Description)[:limits.MaxDescription])
+		t.Descriptions++
+	}
+
+	if limits.MaxTopics > 0 && len(repo.Topics) > limits.MaxTopics {
+		repo.Topics = repo.Topics[:limits.MaxTopics]
+		t.Topics++
+	}
+
+	if repo.Readme.Valid {
+		repo.Readme.String = t.sanitizeReadme(repo.Readme.String, limits)
+	}
+}
+
+// SanitizeReadme applies the rules used when storing repositories to a
+// README.
+func SanitizeReadme(readme string, limits LimitsConfig) string {
+	var t Truncation
+	return t.sanitizeReadme(readme, limits)
+}
+
+func (t *Truncation) sanitizeReadme(readme string, limits LimitsConfig) string {
+	readme = t.sanitizeString(readme)
+	if limits.MaxReadmeBytes <= 0 || len(readme) <= limits.MaxReadmeBytes {
+		return readme
+	}
+
+	// Don't cut a multi-byte character in half.
+	end := limits.MaxReadmeBytes
+	for end > 0 && !utf8.RuneStart(readme[end]) {
+		end--
+	}
+	t.Readmes++
+	t.ReadmeBytes += int64(len(readme) - end)
+
+	return readme[:end]
+}
+
+// sanitizeString replaces invalid UTF-8 sequences with the Unicode
+// replacement character and removes NUL bytes.
+func (t *Truncation) sanitizeString(s string) string {
+	if utf8.ValidString(s) && !strings.ContainsRune(s, 0) {
+		return s
+	}
+
+	t.InvalidUTF8++
+	return strings.ReplaceAll(strings.ToValidUTF8(s, "�"), "\x00", "")
+}
@@ -0,0 +1,108 @@
+package stars
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+)
+
+// stateStarsCursor is the cursor the next shard resumes the listing at.
+const stateStarsCursor = "stars_cursor"
+
+// shardPageSize is the number of stars requested per page, the GraphQL
+// API maximum.
+const shardPageSize = 100
+
+// ShardOptions bounds the rate limit points a shard spends.
+type ShardOptions struct {
+	// Budget is the number of GraphQL rate limit points the shard may
+	// spend.
+	Budget int
+	// Reserve is the number of points left for other uses of the token:
+	// the shard stops once fewer remain, whatever its budget.
+	Reserve int
+}
+
+// Shard is the outcome of SyncShard.
+type Shard struct {
+	Pages int `json:"pages"`
+	// Spent is the number of rate limit points spent.
+	Spent int `json:"spent"`
+	// Remaining is the number of rate limit points left until ResetAt.
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+	// Done is set when the listing reached the most recent star.
+	Done bool `json:"done"`
+}
+
+// SyncShard syncs the next shard of the star list, for accounts with too
+// many stars to sync in a single run without exceeding the hourly rate
+// limits. Stars are listed oldest first, resuming after the cursor the
+// previous shard persisted, until the shard budget is spent or the listing
+// is complete. Once complete, shards only pick up the new stars.
+//
+// READMEs are not fetched, a README per request would exhaust the REST API
+// rate limit; backfill them with FetchMissingReadmes instead. Unstarred
+// repositories aren't detected either, that takes a full Sync.
+func (s *Syncer) SyncShard(ctx context.Context, opts ShardOptions) (Shard, error) {
+	var shard Shard
+	lister, ok := s.gh.(githubclient.CursorLister)
+	if !ok {
+		return shard, fmt.Errorf("the client can't resume star listings from a cursor")
+	}
+
+	cursor, err := store.GetState(s.sess, stateStarsCursor)
+	if err != nil {
+		return shard, err
+	}
+
+	readmes := s.opts.Readmes
+	s.opts.Readmes = false
+	defer func() { s.opts.Readmes = readmes }()
+
+	stars := s.sess.Collection("starred_repos")
+	writer := s.newWriter()
+	s.stargazers = nil
+	for shard.Spent < opts.Budget {
+		page, err := lister.StarredAfter(ctx, cursor, shardPageSize)
+		if err != nil {
+			return shard, errors.Join(err, writer.Flush(), s.saveRefreshed(), s.saveStargazers())
+		}
+		shard.Pages++
+		shard.Spent += page.RateLimit.Cost
+		shard.Remaining = page.RateLimit.Remaining
+		shard.ResetAt = page.RateLimit.ResetAt
+		s.log.Infof("Fetching stars... (shard page %d, %d rate limit points left)", shard.Pages, shard.Remaining)
+
+		for _, sr := range page.Repos {
+			if err := s.syncRepo(ctx, stars, writer, RepoFromGitHub(sr)); err != nil {
+				return shard, errors.Join(err, writer.Flush())
+			}
+		}
+		// The cursor only moves forward once the page is stored.
+		if err := writer.Flush(); err != nil {
+			return shard, err
+		}
+		if page.EndCursor != "" {
+			cursor = page.EndCursor
+			if err := store.SetState(s.sess, stateStarsCursor, cursor); err != nil {
+				return shard, err
+			}
+		}
+
+		if !page.HasNextPage {
+			shard.Done = true
+			break
+		}
+		if shard.Remaining < opts.Reserve {
+			s.log.Warnf("Only %d rate limit points left until %s, stopping the shard", shard.Remaining, shard.ResetAt.Format(time.RFC3339))
+			break
+		}
+	}
+
+	return shard, errors.Join(s.saveRefreshed(), s.saveStargazers())
+}
@@ -0,0 +1,629 @@
+// Package stars syncs the repositories starred on GitHub to the database
+// managed by the store package.
+package stars
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/rubiojr/gh-stars-exporter/internal/langdetect"
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// Keys stored in the sync_state table.
+const (
+	stateStarsETag     = "stars_etag"
+	stateStarsLastPage = "stars_last_page"
+	stateStarsLastETag = "stars_last_etag"
+	stateLastStarredAt = "last_starred_at"
+)
+
+// refreshChunk is the number of repositories whose refresh time is saved
+// per statement.
+const refreshChunk = 500
+
+// Options configures a Syncer. The zero value syncs public stars only,
+// without READMEs.
+type Options struct {
+	// Readmes fetches the READMEs of the repositories not having one yet.
+	Readmes bool
+	// StorePrivate stores private starred repositories.
+	StorePrivate bool
+	// Force walks the full star list even if it didn't change since the
+	// last sync.
+	Force bool
+	// Filter restricts the new stars stored, nil stores all of them.
+	// Repositories already stored keep being refreshed.
+	Filter func(store.Repository) bool
+	// Private controls what is stored about private repositories.
+	Private PrivateConfig
+	// Limits caps the size of the data stored for each repository.
+	Limits LimitsConfig
+	// Policy declares what is fetched and kept, see Policy.
+	Policy Policy
+	// BatchSize is the number of new stars written per INSERT statement.
+	BatchSize int
+	// CommitEvery commits the database transaction every CommitEvery new
+	// stars.
+	CommitEvery int
+	Logger      *log.Logger
+}
+
+// PrivateConfig controls what is stored about private repositories when
+// Options.StorePrivate is enabled.
+type PrivateConfig struct {
+	// Readme stores the README of private repositories.
+	Readme bool `toml:"readme"`
+	// Description stores the description of private repositories.
+	Description bool `toml:"description"`
+	// Topics stores the topics of private repositories.
+	Topics bool `toml:"topics"`
+	// HashNames replaces the name, full name and URL of private repositories
+	// with a hash of the full name.
+	HashNames bool `toml:"hash_names"`
+}
+
+// LimitsConfig caps the size of the data stored for each repository. Zero
+// disables a limit.
+type LimitsConfig struct {
+	// MaxReadmeBytes truncates READMEs larger than this.
+	MaxReadmeBytes int `toml:"max_readme_bytes"`
+	// MaxTopics keeps only the first MaxTopics topics.
+	MaxTopics int `toml:"max_topics"`
+	// MaxDescription truncates descriptions longer than this many characters.
+	MaxDescription int `toml:"max_description"`
+}
+
+// Stats summarizes the changes made by a Syncer.
+type Stats struct {
+	NewStars     int        `json:"new_stars"`
+	UpdatedStars int        `json:"updated_stars"`
+	Unstarred    int        `json:"unstarred"`
+	NotModified  bool       `json:"not_modified"`
+	Truncated    Truncation `json:"truncated"`
+	// Pruned is the number of unstarred stars removed by the policy.
+	Pruned int `json:"pruned"`
+	// NewlyArchived lists the repositories archived since the last sync.
+	NewlyArchived []string `json:"newly_archived"`
+	// Events are the changes of the star list, in the order they were
+	// stored.
+	Events []Event `json:"-"`
+	// Errors are the non-fatal errors, such as READMEs that couldn't be
+	// fetched.
+	Errors []error `json:"-"`
+}
+
+// Syncer syncs the stars of the GitHub user authenticated in gh to the
+// database. A Syncer is not safe for concurrent use.
+type Syncer struct {
+	gh       githubclient.Client
+	sess     db.Session
+	opts     Options
+	log      *log.Logger
+	stats    Stats
+	failures []Failure
+	// report are the errors of the run, see SaveReport.
+	report []SyncError
+	// stargazers are the upstream stargazers counts seen by the sync,
+	// keyed by repository ID.
+	stargazers map[int]int
+	// refreshed are the IDs of the stored repositories seen by the sync,
+	// their refresh time is saved in one go.
+	refreshed []int
+}
+
+// New returns a Syncer storing the stars fetched from gh in sess, which must
+// have been migrated by the store package.
+func New(gh githubclient.Client, sess db.Session, opts Options) *Syncer {
+	opts.BatchSize = max(opts.BatchSize, 1)
+	opts.CommitEvery = max(opts.CommitEvery, 1)
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.NewWithOptions(os.Stderr, log.Options{})
+	}
+
+	return &Syncer{gh: gh, sess: sess, opts: opts, log: logger}
+}
+
+// Stats returns the changes made so far.
+func (s *Syncer) Stats() Stats {
+	return s.stats
+}
+
+// Sync walks the starred repositories, adding the new ones to the database
+// and fetching missing READMEs when enabled.
+//
+// The ETags of the first and last pages of the star list are recorded after
+// every successful sync, so the next run can stop after a request or two
+// when nothing changed upstream.
+// Stored stars missing from a complete walk are marked as unstarred, and the
+// stargazers counts that changed are recorded in the stargazers history.
+func (s *Syncer) Sync(ctx context.Context) error {
+	stars := s.sess.Collection("starred_repos")
+	writer := s.newWriter()
+	s.stargazers = nil
+
+	opts := githubclient.StarredOptions{}
+	// README backfilling needs to walk the full list even if it didn't
+	// change, and so do the refreshes due by the policy.
+	if !s.opts.Force && !s.opts.Readmes {
+		due, err := s.refreshDue()
+		if err != nil {
+			return err
+		}
+		if due {
+			s.log.Infof("Refreshing every star, some weren't refreshed for %s", days(s.opts.Policy.RefreshEvery))
+		} else {
+			if opts, err = s.notModifiedOptions(); err != nil {
+				return err
+			}
+		}
+	}
+
+	var etag, lastETag, lastStarredAt string
+	var lastPage int
+	seen := map[int]bool{}
+	err := s.walkStarred(ctx, opts, func(page githubclient.Page) error {
+		if page.Number == 1 {
+			etag = page.ETag
+			if len(page.Repos) > 0 {
+				lastStarredAt = page.Repos[0].StarredAt.Format(time.RFC3339)
+			}
+		}
+		lastPage, lastETag = page.Number, page.ETag
+
+		pageCount := fmt.Sprintf("%d", page.Last)
+		if page.Last == 0 {
+			pageCount = fmt.Sprintf("%d", page.Number)
+		}
+		s.log.Infof("Fetching stars... (page %d/%s)", page.Number, pageCount)
+
+		for _, sr := range page.Repos {
+			repo := RepoFromGitHub(sr)
+			seen[repo.ID] = true
+			if err := s.syncRepo(ctx, stars, writer, repo); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if errors.Is(err, githubclient.ErrNotModified) {
+		s.log.Info("No changes upstream, nothing to do")
+		s.stats.NotModified = true
+		return s.pruneUnstarred()
+	}
+	if err != nil {
+		// Keep what was fetched so far, RetryFailed resumes the listing
+		// where it failed.
+		if ferr := writer.Flush(); ferr != nil {
+			return errors.Join(err, ferr)
+		}
+		return errors.Join(err, s.saveRefreshed(), s.saveFailures())
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := s.saveRefreshed(); err != nil {
+		return err
+	}
+
+	if err := s.saveStargazers(); err != nil {
+		return err
+	}
+
+	// A complete listing retries the failed pages, and READMEs when enabled.
+	retried := []string{FailurePage}
+	if s.opts.Readmes {
+		retried = append(retried, FailureReadme)
+	}
+	if err := s.saveFailures(retried...); err != nil {
+		return err
+	}
+
+	if err := s.markUnstarred(seen); err != nil {
+		return err
+	}
+	if err := s.pruneUnstarred(); err != nil {
+		return err
+	}
+
+	if len(s.stats.NewlyArchived) > 0 {
+		s.log.Warnf("%d starred repositories were archived since the last sync:", len(s.stats.NewlyArchived))
+		for _, name := range s.stats.NewlyArchived {
+			s.log.Warnf("  - %s", name)
+		}
+	}
+
+	if t := s.stats.Truncated; t.Any() {
+		s.log.Warnf(
+			"Sanitized upstream data: %d READMEs truncated (%d bytes), %d descriptions truncated, %d topic lists truncated, %d fields with invalid UTF-8",
+			t.Readmes, t.ReadmeBytes, t.Descriptions, t.Topics, t.InvalidUTF8,
+		)
+	}
+
+	if err := store.SetState(s.sess, stateLastStarredAt, lastStarredAt); err != nil {
+		return err
+	}
+	if err := store.SetState(s.sess, stateStarsLastPage, strconv.Itoa(lastPage)); err != nil {
+		return err
+	}
+	if err := store.SetState(s.sess, stateStarsLastETag, lastETag); err != nil {
+		return err
+	}
+	return store.SetState(s.sess, stateStarsETag, etag)
+}
+
+// notModifiedOptions returns the listing options stopping the walk when
+// the star list didn't change since the previous sync.
+func (s *Syncer) notModifiedOptions() (githubclient.StarredOptions, error) {
+	var opts githubclient.StarredOptions
+	etag, err := store.GetState(s.sess, stateStarsETag)
+	if err != nil {
+		return opts, err
+	}
+	lastPage, err := store.GetState(s.sess, stateStarsLastPage)
+	if err != nil {
+		return opts, err
+	}
+	lastETag, err := store.GetState(s.sess, stateStarsLastETag)
+	if err != nil {
+		return opts, err
+	}
+
+	opts.IfNoneMatch = etag
+	opts.LastETag = lastETag
+	if lastPage != "" {
+		if opts.LastPage, err = strconv.Atoi(lastPage); err != nil {
+			return opts, fmt.Errorf("invalid %s state %q: %w", stateStarsLastPage, lastPage, err)
+		}
+	}
+	// Databases synced before the last page was recorded don't know if the
+	// list has more than one page, so the walk can't stop early.
+	if lastPage == "" || (opts.LastPage > 1 && lastETag == "") {
+		opts = githubclient.StarredOptions{}
+	}
+
+	return opts, nil
+}
+
+// syncRepo adds the upstream repository to the database when new and
+// matching the filter, or refreshes the stored one.
+func (s *Syncer) syncRepo(ctx context.Context, stars db.Collection, writer *starWriter, repo store.Repository) error {
+	if repo.Private && !s.opts.StorePrivate {
+		s.log.Warnf("Skipping private repository %s", repo.FullName)
+		return nil
+	}
+
+	res := stars.Find(db.Cond{"id": repo.ID})
+	var r store.Repository
+	err := res.One(&r)
+	if err == nil {
+		s.recordStargazers(repo)
+		if err := s.refreshRepo(ctx, repo, r, res); err != nil {
+			return err
+		}
+		s.refreshed = append(s.refreshed, repo.ID)
+		return nil
+	}
+
+	if s.opts.Filter != nil && !s.opts.Filter(repo) {
+		s.log.Debugf("Skipping %s, doesn't match the sync filters", repo.FullName)
+		return nil
+	}
+
+	s.recordStargazers(repo)
+	return s.addNewRepo(ctx, repo, writer)
+}
+
+// RepoFromGitHub converts a starred repository returned by the GitHub API
+// to the database representation.
+func RepoFromGitHub(sr githubclient.StarredRepo) store.Repository {
+	r := sr.Repo
+	return store.Repository{
+		ID:              r.ID,
+		Name:            r.Name,
+		HTMLURL:         r.HTMLURL,
+		Description:     r.Description,
+		CreatedAt:       r.CreatedAt,
+		UpdatedAt:       r.UpdatedAt,
+		PushedAt:        r.PushedAt,
+		StargazersCount: r.StargazersCount,
+		Language:        r.Language,
+		FullName:        r.FullName,
+		Topics:          store.StringList(r.Topics),
+		IsTemplate:      r.IsTemplate,
+		Private:         r.Private,
+		Archived:        r.Archived,
+		Homepage:        r.Homepage,
+		StarredAt:       sr.StarredAt,
+		Source:          store.SourceStarred,
+	}
+}
+
+func (s *Syncer) addNewRepo(ctx context.Context, repo store.Repository, writer *starWriter) error {
+	now := time.Now().UTC()
+	repo.RefreshedAt = &now
+	if s.opts.Readmes && s.readmeWanted(repo) {
+		repo.ReadmeFetchedAt = &now
+		readme, err := s.gh.Readme(ctx, repo.FullName)
+		if err != nil {
+			s.reportError(ErrorReadme, repo.FullName, repo.ID, err)
+			s.recordFailure(FailureReadme, repo.FullName, repo.ID, err)
+		} else {
+			repo.Readme = store.NullString{String: readme, Valid: true}
+			repo.ReadmeLanguage = langdetect.Detect(readme)
+		}
+	}
+
+	return writer.Add(repo)
+}
+
+// refreshRepo updates the stored repository r with the upstream changes the
+// sync keeps track of: bookmarks becoming stars, archival status, the
+// metadata in the star list (description, topics, language, stargazers,
+// homepage and dates) and missing READMEs when enabled. Its refresh time is
+// saved by saveRefreshed, the metadata being current afterwards.
+func (s *Syncer) refreshRepo(ctx context.Context, upstream, r store.Repository, res db.Result) error {
+	s.log.Debugf("Repository %s already exists in the database", upstream.FullName)
+
+	// Sanitized and redacted like the stored row, to compare them. The
+	// truncations were counted when it was added.
+	fullName := upstream.FullName
+	var t Truncation
+	t.sanitizeRepo(&upstream, s.opts.Limits)
+	redactPrivate(&upstream, s.opts.Private)
+
+	changed, starred := false, false
+	if r.UnstarredAt != nil {
+		s.log.Debugf("Repository %s was starred again", fullName)
+		r.UnstarredAt = nil
+		r.StarredAt = upstream.StarredAt
+		changed, starred = true, true
+	}
+
+	if r.Source == store.SourceManual {
+		s.log.Debugf("Bookmarked repository %s is now starred", fullName)
+		r.Source = store.SourceStarred
+		r.StarredAt = upstream.StarredAt
+		changed, starred = true, true
+	}
+
+	if r.Archived != upstream.Archived {
+		r.ArchivedAt = nil
+		if upstream.Archived {
+			s.stats.NewlyArchived = append(s.stats.NewlyArchived, fullName)
+			now := time.Now().UTC()
+			r.ArchivedAt = &now
+		}
+		r.Archived = upstream.Archived
+		changed = true
+	}
+
+	if r.Homepage != upstream.Homepage {
+		r.Homepage = upstream.Homepage
+		changed = true
+	}
+
+	if r.Description != upstream.Description {
+		r.Description = upstream.Description
+		// Translated again by the next translate run.
+		r.Translation = ""
+		changed = true
+	}
+
+	if strings.Join(r.Topics, ",") != strings.Join(upstream.Topics, ",") {
+		r.Topics = upstream.Topics
+		changed = true
+	}
+
+	if r.Language != upstream.Language || r.StargazersCount != upstream.StargazersCount {
+		r.Language = upstream.Language
+		r.StargazersCount = upstream.StargazersCount
+		changed = true
+	}
+
+	if upstream.PushedAt.After(r.PushedAt) {
+		r.PushedAt = upstream.PushedAt
+		r.UpdatedAt = upstream.UpdatedAt
+		changed = true
+	}
+
+	if s.opts.Readmes && s.FetchMissingReadme(ctx, fullName, &r) {
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if err := res.Update(r); err != nil {
+		return err
+	}
+	s.log.Debugf("Updated %s", fullName)
+	s.stats.UpdatedStars++
+	if starred {
+		s.addEvent(EventStar, r)
+	}
+
+	return nil
+}
+
+// markUnstarred flags the stored stars not seen in the star list as
+// unstarred.
+func (s *Syncer) markUnstarred(seen map[int]bool) error {
+	var stored []store.Repository
+	err := s.sess.Collection("starred_repos").
+		Find(db.Cond{"source": store.SourceStarred, "unstarred_at IS": nil}).
+		Select("id", "name", "full_name", "html_url", "description", "language", "topics", "stargazers_count", "private", "source", "starred_at").
+		All(&stored)
+	if err != nil {
+		return err
+	}
+
+	var ids []int
+	var unstarred []store.Repository
+	for _, r := range stored {
+		if !seen[r.ID] {
+			s.log.Infof("Repository %s was unstarred", r.FullName)
+			ids = append(ids, r.ID)
+			unstarred = append(unstarred, r)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	s.stats.Unstarred += len(ids)
+	err = s.sess.Collection("starred_repos").
+		Find(db.Cond{"id IN": ids}).
+		Update(map[string]interface{}{"unstarred_at": time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	for _, r := range unstarred {
+		s.addEvent(EventUnstar, r)
+	}
+
+	return nil
+}
+
+// saveRefreshed records the refresh time of the stored repositories seen
+// since the last save, the metadata freshness search --max-age relies on.
+// New repositories get it when inserted.
+func (s *Syncer) saveRefreshed() error {
+	now := time.Now().UTC()
+	// Chunked to stay below the SQLite variables limit.
+	for start := 0; start < len(s.refreshed); start += refreshChunk {
+		ids := s.refreshed[start:min(start+refreshChunk, len(s.refreshed))]
+		err := s.sess.Collection("starred_repos").
+			Find(db.Cond{"id IN": ids}).
+			Update(map[string]interface{}{"refreshed_at": now})
+		if err != nil {
+			return err
+		}
+	}
+	s.refreshed = nil
+
+	return nil
+}
+
+// FetchMissingReadme fetches the README of r when not stored yet, returning
+// true if the README was found. The attempt is recorded in
+// r.ReadmeFetchedAt, r is not saved. fullName is the upstream name, which
+// may differ from the stored one when private repository names are hashed.
+func (s *Syncer) FetchMissingReadme(ctx context.Context, fullName string, r *store.Repository) bool {
+	if r.Readme.Valid {
+		s.log.Debug("README already exists")
+		return false
+	}
+
+	// Skipped repositories are recorded too, so the daemon backfill moves
+	// on to the next one until the retry delay passes.
+	now := time.Now().UTC()
+	r.ReadmeFetchedAt = &now
+	if r.Private && !s.opts.Private.Readme {
+		s.log.Debugf("Not storing README for private repository %s", fullName)
+		return false
+	}
+	if !s.opts.Policy.readmeLanguage(r.Language) {
+		s.log.Debugf("Not storing README for %s, the policy excludes %q", fullName, r.Language)
+		return false
+	}
+
+	s.log.Debugf("Updating README for %s", fullName)
+	readme, err := s.gh.Readme(ctx, fullName)
+	if err != nil {
+		s.reportError(ErrorReadme, fullName, r.ID, err)
+		s.recordFailure(FailureReadme, fullName, r.ID, err)
+		return false
+	}
+
+	r.Readme = store.NullString{String: s.stats.Truncated.sanitizeReadme(readme, s.opts.Limits), Valid: true}
+	r.ReadmeLanguage = langdetect.Detect(r.Readme.String)
+	return true
+}
+
+// FetchMissingReadmes fetches the READMEs of the repositories not having
+// one yet, returning how many were found. When the client implements
+// githubclient.ReadmeBatcher, up to batchSize READMEs are fetched per
+// request. The repositories are not saved.
+func (s *Syncer) FetchMissingReadmes(ctx context.Context, repos []*store.Repository, batchSize int) int {
+	found := 0
+	batcher, ok := s.gh.(githubclient.ReadmeBatcher)
+	if !ok || batchSize < 2 {
+		for _, r := range repos {
+			if ctx.Err() != nil {
+				break
+			}
+			if s.FetchMissingReadme(ctx, r.FullName, r) {
+				found++
+			}
+		}
+		return found
+	}
+
+	var pending []*store.Repository
+	for _, r := range repos {
+		if !r.Readme.Valid && s.readmeWanted(*r) {
+			pending = append(pending, r)
+		}
+	}
+
+	for start := 0; start < len(pending) && ctx.Err() == nil; start += batchSize {
+		batch := pending[start:min(start+batchSize, len(pending))]
+		names := make([]string, len(batch))
+		for i, r := range batch {
+			names[i] = r.FullName
+		}
+
+		readmes, err := batcher.Readmes(ctx, names)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			s.log.Warnf("Fetching %d READMEs at once failed, fetching them one by one: %s", len(batch), err)
+			for _, r := range batch {
+				if s.FetchMissingReadme(ctx, r.FullName, r) {
+					found++
+				}
+			}
+			continue
+		}
+
+		now := time.Now().UTC()
+		for _, r := range batch {
+			r.ReadmeFetchedAt = &now
+			readme, ok := readmes[r.FullName]
+			if !ok {
+				s.log.Debugf("No README found for %s", r.FullName)
+				continue
+			}
+			r.Readme = store.NullString{String: s.stats.Truncated.sanitizeReadme(readme, s.opts.Limits), Valid: true}
+			r.ReadmeLanguage = langdetect.Detect(r.Readme.String)
+			found++
+		}
+	}
+
+	return found
+}
+
+// Prepare sanitizes repo and redacts it when private, as done with every
+// repository before it's stored. Timestamps are converted to UTC.
+func (s *Syncer) Prepare(repo *store.Repository) {
+	s.stats.Truncated.sanitizeRepo(repo, s.opts.Limits)
+	redactPrivate(repo, s.opts.Private)
+	repo.CreatedAt = repo.CreatedAt.UTC()
+	repo.UpdatedAt = repo.UpdatedAt.UTC()
+	repo.PushedAt = repo.PushedAt.UTC()
+	repo.StarredAt = repo.StarredAt.UTC()
+}
@@ -0,0 +1,174 @@
+package stars
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient/githubtest"
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// testDB returns a migrated database in a temporary directory.
+func testDB(t *testing.T) db.Session {
+	t.Helper()
+	sess, err := store.Open(filepath.Join(t.TempDir(), "stars.db"), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sess.Close() })
+
+	return sess
+}
+
+// starred returns the star of the repository fullName, starred days ago.
+func starred(id int, fullName string, days int) githubclient.StarredRepo {
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	return githubclient.StarredRepo{
+		StarredAt: time.Now().UTC().Add(-time.Duration(days) * 24 * time.Hour).Truncate(time.Second),
+		Repo: githubclient.Repository{
+			ID:          id,
+			Name:        fullName[len("owner/"):],
+			FullName:    fullName,
+			HTMLURL:     "https://github.com/" + fullName,
+			Description: "Repository " + fullName,
+			Language:    "Go",
+			CreatedAt:   created,
+			UpdatedAt:   created,
+			PushedAt:    created,
+		},
+	}
+}
+
+// runSync runs a Syncer with opts against the fake server.
+func runSync(t *testing.T, srv *githubtest.Server, sess db.Session, opts Options) Stats {
+	t.Helper()
+	opts.Logger = log.New(io.Discard)
+	s := New(srv.Client(), sess, opts)
+	if err := s.Sync(context.Background()); err != nil {
+		t.Fatalf("sync: %s", err)
+	}
+
+	return s.Stats()
+}
+
+// stored returns the stored repositories, keyed by full name.
+func stored(t *testing.T, sess db.Session) map[string]store.Repository {
+	t.Helper()
+	var repos []store.Repository
+	if err := sess.Collection("starred_repos").Find().All(&repos); err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]store.Repository{}
+	for _, r := range repos {
+		byName[r.FullName] = r
+	}
+
+	return byName
+}
+
+func TestSync(t *testing.T) {
+	sess := testDB(t)
+	stars := []githubclient.StarredRepo{
+		starred(1, "owner/one", 1),
+		starred(2, "owner/two", 2),
+		starred(3, "owner/three", 3),
+	}
+	srv := githubtest.NewServer(stars...)
+	defer srv.Close()
+	srv.SetReadme("owner/one", "# One")
+
+	// The initial sync stores every star, with the READMEs found.
+	stats := runSync(t, srv, sess, Options{Readmes: true})
+	if stats.NewStars != 3 {
+		t.Errorf("new stars = %d, want 3", stats.NewStars)
+	}
+	repos := stored(t, sess)
+	if len(repos) != 3 {
+		t.Fatalf("stored %d repositories, want 3", len(repos))
+	}
+	if r := repos["owner/one"]; !r.Readme.Valid || r.Readme.String != "# One" {
+		t.Errorf("owner/one README = %+v, want # One", r.Readme)
+	}
+	if r := repos["owner/two"]; r.Readme.Valid || r.ReadmeFetchedAt == nil {
+		t.Errorf("owner/two README = %+v fetched at %v, want a recorded attempt and no README", r.Readme, r.ReadmeFetchedAt)
+	}
+	for _, r := range repos {
+		if r.Source != store.SourceStarred || r.UnstarredAt != nil {
+			t.Errorf("%s source %q unstarred at %v, want a current star", r.FullName, r.Source, r.UnstarredAt)
+		}
+	}
+
+	// Nothing changed upstream, the sync stops after the first page.
+	stats = runSync(t, srv, sess, Options{})
+	if !stats.NotModified {
+		t.Error("unchanged star list synced again")
+	}
+
+	// An unstarred repository is kept and marked.
+	srv.SetStars(stars[0], stars[2])
+	stats = runSync(t, srv, sess, Options{})
+	if stats.Unstarred != 1 || stats.NewStars != 0 {
+		t.Errorf("unstarred %d, new %d, want 1 unstarred", stats.Unstarred, stats.NewStars)
+	}
+	repos = stored(t, sess)
+	if r := repos["owner/two"]; r.UnstarredAt == nil {
+		t.Error("owner/two not marked as unstarred")
+	}
+	if r := repos["owner/one"]; r.UnstarredAt != nil || r.Readme.String != "# One" {
+		t.Errorf("owner/one unstarred at %v README %+v, want a current star keeping its README", r.UnstarredAt, r.Readme)
+	}
+	if len(stats.Events) != 1 || stats.Events[0].Type != EventUnstar {
+		t.Errorf("events = %+v, want an unstar", stats.Events)
+	}
+}
+
+func TestSyncPages(t *testing.T) {
+	sess := testDB(t)
+	var stars []githubclient.StarredRepo
+	for i := 1; i <= 5; i++ {
+		stars = append(stars, starred(i, fmt.Sprintf("owner/repo%d", i), i))
+	}
+	srv := githubtest.NewServer(stars...)
+	defer srv.Close()
+	srv.PerPage = 2
+
+	if stats := runSync(t, srv, sess, Options{}); stats.NewStars != 5 {
+		t.Fatalf("new stars = %d, want 5", stats.NewStars)
+	}
+	if repos := stored(t, sess); len(repos) != 5 {
+		t.Fatalf("stored %d repositories, want 5", len(repos))
+	}
+}
+
+func TestSyncUnstarPastFirstPage(t *testing.T) {
+	sess := testDB(t)
+	var stars []githubclient.StarredRepo
+	for i := 1; i <= 5; i++ {
+		stars = append(stars, starred(i, fmt.Sprintf("owner/repo%d", i), i))
+	}
+	srv := githubtest.NewServer(stars...)
+	defer srv.Close()
+	srv.PerPage = 2
+	runSync(t, srv, sess, Options{})
+
+	// The first page doesn't change, the last one does.
+	srv.SetStars(stars[0], stars[1], stars[3], stars[4])
+	stats := runSync(t, srv, sess, Options{})
+	if stats.NotModified || stats.Unstarred != 1 {
+		t.Fatalf("not modified %t, unstarred %d, want 1 unstarred", stats.NotModified, stats.Unstarred)
+	}
+	if r := stored(t, sess)["owner/repo3"]; r.UnstarredAt == nil {
+		t.Error("owner/repo3 not marked as unstarred")
+	}
+
+	if stats := runSync(t, srv, sess, Options{}); !stats.NotModified {
+		t.Error("unchanged star list synced again")
+	}
+}
@@ -0,0 +1,83 @@
+package stars
+
+import (
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// starWriter buffers new repositories and writes them to the database using
+// multi-row INSERT statements of up to batchSize rows, committing a
+// transaction every commitEvery rows.
+type starWriter struct {
+	syncer      *Syncer
+	batchSize   int
+	commitEvery int
+	pending     []store.Repository
+}
+
+func (s *Syncer) newWriter() *starWriter {
+	return &starWriter{
+		syncer:      s,
+		batchSize:   s.opts.BatchSize,
+		commitEvery: s.opts.CommitEvery,
+	}
+}
+
+// Add queues a repository for insertion, flushing the queue once commitEvery
+// repositories are pending. Repositories are sanitized and private ones
+// redacted according to the syncer options.
+func (w *starWriter) Add(repo store.Repository) error {
+	w.syncer.Prepare(&repo)
+	w.pending = append(w.pending, repo)
+	if len(w.pending) >= w.commitEvery {
+		return w.Flush()
+	}
+
+	return nil
+}
+
+// Flush writes all the pending repositories in a single transaction. When
+// the transaction fails, they're written one by one instead, reporting the
+// ones the database rejects and skipping them, so a bad row doesn't stop the
+// sync.
+func (w *starWriter) Flush() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	w.syncer.log.Debugf("Writing %d new stars to the database", len(w.pending))
+	err := w.syncer.sess.Tx(func(tx db.Session) error {
+		for start := 0; start < len(w.pending); start += w.batchSize {
+			end := min(start+w.batchSize, len(w.pending))
+			ins := tx.SQL().InsertInto("starred_repos")
+			for _, repo := range w.pending[start:end] {
+				ins = ins.Values(repo)
+			}
+			if _, err := ins.Exec(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	stored := w.pending
+	if err != nil {
+		w.syncer.log.Debugf("Writing %d new stars at once failed, writing them one by one: %s", len(w.pending), err)
+		stored = nil
+		for _, repo := range w.pending {
+			if _, ierr := w.syncer.sess.SQL().InsertInto("starred_repos").Values(repo).Exec(); ierr != nil {
+				w.syncer.reportError(ErrorInsert, repo.FullName, repo.ID, ierr)
+				continue
+			}
+			stored = append(stored, repo)
+		}
+	}
+
+	w.syncer.stats.NewStars += len(stored)
+	for _, repo := range stored {
+		w.syncer.addEvent(EventStar, repo)
+	}
+	w.pending = w.pending[:0]
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// BackupOptions configures the backups taken before migrating a database.
+type BackupOptions struct {
+	// Keep is the number of backups kept per database file, the oldest ones
+	// are removed. 0 disables the backups.
+	Keep int
+	// Dir is the directory backups are written to, the database one when
+	// empty.
+	Dir string
+}
+
+// backupTimeFormat names the backups after the time they're taken, sorting
+// them chronologically.
+const backupTimeFormat = "20060102T150405Z"
+
+// BackupBeforeMigrating copies the database file at path when migrations
+// are pending, keeping opts.Keep backups. It returns the path of the
+// backup, empty when none was needed: new databases, databases up to date
+// and dirty ones, whose backup would rotate out the good ones.
+func BackupBeforeMigrating(path string, opts BackupOptions) (string, error) {
+	if opts.Keep <= 0 {
+		return "", nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+
+	m, src, err := NewMigrate(path)
+	if err != nil {
+		return "", err
+	}
+	version, dirty, err := m.Version()
+	m.Close()
+	if errors.Is(err, migrate.ErrNilVersion) || dirty {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := src.Next(version); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	dir := opts.Dir
+	if dir == "" {
+		dir = filepath.Dir(path)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	base := filepath.Base(path)
+	dest := filepath.Join(dir, fmt.Sprintf("%s.%d.%s.bak", base, version, time.Now().UTC().Format(backupTimeFormat)))
+	if err := backup(path, dest); err != nil {
+		return "", err
+	}
+
+	return dest, pruneBackups(dir, base, opts.Keep)
+}
+
+// backup writes a consistent copy of the database at path to dest, even
+// with other connections writing to it.
+func backup(path, dest string) error {
+	sess, err := Connect(path, Options{})
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	// The sqlite adapter wraps statements in transactions, which VACUUM
+	// doesn't support: use the underlying database/sql handle.
+	_, err = sess.Driver().(*sql.DB).Exec("VACUUM INTO ?", dest)
+	return err
+}
+
+// pruneBackups removes the oldest backups of the database named base in
+// dir, keeping the newest keep.
+func pruneBackups(dir, base string, keep int) error {
+	matches, err := filepath.Glob(filepath.Join(dir, base+".*.bak"))
+	if err != nil {
+		return err
+	}
+
+	// Sorted by the time they were taken, the schema version in their name
+	// may go back after a downgrade.
+	taken := map[string]time.Time{}
+	var backups []string
+	for _, b := range matches {
+		name := strings.TrimSuffix(b, ".bak")
+		i := strings.LastIndex(name, ".")
+		t, err := time.Parse(backupTimeFormat, name[i+1:])
+		if err != nil {
+			continue
+		}
+		taken[b] = t
+		backups = append(backups, b)
+	}
+	if len(backups) <= keep {
+		return nil
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return taken[backups[i]].Before(taken[backups[j]])
+	})
+	for _, b := range backups[:len(backups)-keep] {
+		if err := os.Remove(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/upper/db/v4"
+)
+
+// DogsheepUser is the GitHub user the stars of a dogsheep database belong
+// to.
+type DogsheepUser struct {
+	ID      int
+	Login   string
+	HTMLURL string
+}
+
+// dogsheepSchema is the subset of the github-to-sqlite schema the stars
+// fill, with the full-text search tables sqlite-utils configures for it.
+// FTS4 is used as the sqlite driver is built without FTS5, Datasette
+// searches both.
+const dogsheepSchema = `
+CREATE TABLE [users] (
+	[login] TEXT,
+	[id] INTEGER PRIMARY KEY,
+	[html_url] TEXT,
+	[type] TEXT
+);
+CREATE TABLE [repos] (
+	[id] INTEGER PRIMARY KEY,
+	[name] TEXT,
+	[full_name] TEXT,
+	[private] INTEGER,
+	[owner] INTEGER REFERENCES [users]([id]),
+	[html_url] TEXT,
+	[description] TEXT,
+	[created_at] TEXT,
+	[updated_at] TEXT,
+	[pushed_at] TEXT,
+	[homepage] TEXT,
+	[stargazers_count] INTEGER,
+	[language] TEXT,
+	[archived] INTEGER,
+	[is_template] INTEGER,
+	[topics] TEXT,
+	[readme] TEXT
+);
+CREATE TABLE [stars] (
+	[user] INTEGER REFERENCES [users]([id]),
+	[repo] INTEGER REFERENCES [repos]([id]),
+	[starred_at] TEXT,
+	PRIMARY KEY ([user], [repo])
+);
+CREATE TABLE [following] (
+	[followed_id] INTEGER REFERENCES [users]([id]),
+	[follower_id] INTEGER REFERENCES [users]([id]),
+	[first_seen] TEXT,
+	PRIMARY KEY ([followed_id], [follower_id])
+);
+CREATE INDEX [idx_repos_owner] ON [repos] ([owner]);
+CREATE INDEX [idx_stars_repo] ON [stars] ([repo]);
+
+CREATE VIRTUAL TABLE [repos_fts] USING fts4([name], [description], [readme], content="repos");
+CREATE TRIGGER [repos_bu] BEFORE UPDATE ON [repos] BEGIN
+	DELETE FROM [repos_fts] WHERE docid = old.rowid;
+END;
+CREATE TRIGGER [repos_bd] BEFORE DELETE ON [repos] BEGIN
+	DELETE FROM [repos_fts] WHERE docid = old.rowid;
+END;
+CREATE TRIGGER [repos_au] AFTER UPDATE ON [repos] BEGIN
+	INSERT INTO [repos_fts] (docid, [name], [description], [readme]) VALUES (new.rowid, new.[name], new.[description], new.[readme]);
+END;
+CREATE TRIGGER [repos_ai] AFTER INSERT ON [repos] BEGIN
+	INSERT INTO [repos_fts] (docid, [name], [description], [readme]) VALUES (new.rowid, new.[name], new.[description], new.[readme]);
+END;
+
+CREATE VIRTUAL TABLE [users_fts] USING fts4([login], content="users");
+CREATE TRIGGER [users_bu] BEFORE UPDATE ON [users] BEGIN
+	DELETE FROM [users_fts] WHERE docid = old.rowid;
+END;
+CREATE TRIGGER [users_bd] BEFORE DELETE ON [users] BEGIN
+	DELETE FROM [users_fts] WHERE docid = old.rowid;
+END;
+CREATE TRIGGER [users_au] AFTER UPDATE ON [users] BEGIN
+	INSERT INTO [users_fts] (docid, [login]) VALUES (new.rowid, new.[login]);
+END;
+CREATE TRIGGER [users_ai] AFTER INSERT ON [users] BEGIN
+	INSERT INTO [users_fts] (docid, [login]) VALUES (new.rowid, new.[login]);
+END;
+`
+
+// WriteDogsheep writes the current stars in sess to dst, a new SQLite
+// database, in the schema of dogsheep's github-to-sqlite: the repos, the
+// stars of user and the users followed by them, so it can be explored with
+// Datasette and the dogsheep dashboards. github-to-sqlite can keep
+// updating it afterwards.
+//
+// Owners are only known, and linked to their repositories, when they are
+// user or users they follow: the stars don't record the owner IDs. Returns
+// the number of stars written.
+func WriteDogsheep(sess db.Session, dst string, user DogsheepUser) (int, error) {
+	var repos []Repository
+	err := sess.Collection("starred_repos").
+		Find(db.Cond{"source": SourceStarred, "unstarred_at IS": nil}).
+		OrderBy("starred_at").
+		All(&repos)
+	if err != nil {
+		return 0, fmt.Errorf("reading stars: %w", err)
+	}
+
+	type followed struct {
+		ID      int    `db:"id"`
+		Login   string `db:"login"`
+		HTMLURL string `db:"html_url"`
+	}
+	var following []followed
+	if err := sess.SQL().Select("id", "login", "html_url").From("following").OrderBy("login").All(&following); err != nil {
+		return 0, fmt.Errorf("reading followed users: %w", err)
+	}
+
+	out, err := Connect(dst, Options{})
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	err = out.Tx(func(tx db.Session) error {
+		sqlTx := tx.Driver().(*sql.Tx)
+		if _, err := sqlTx.Exec(dogsheepSchema); err != nil {
+			return fmt.Errorf("creating schema: %w", err)
+		}
+
+		owners := map[string]int{}
+		addUser := func(id int, login, htmlURL string) error {
+			owners[strings.ToLower(login)] = id
+			_, err := sqlTx.Exec("INSERT OR IGNORE INTO users (login, id, html_url, type) VALUES (?, ?, ?, 'User')", login, id, htmlURL)
+			return err
+		}
+		if err := addUser(user.ID, user.Login, user.HTMLURL); err != nil {
+			return fmt.Errorf("writing user %s: %w", user.Login, err)
+		}
+		now := dogsheepTime(time.Now())
+		for _, f := range following {
+			if err := addUser(f.ID, f.Login, f.HTMLURL); err != nil {
+				return fmt.Errorf("writing user %s: %w", f.Login, err)
+			}
+			_, err := sqlTx.Exec("INSERT INTO following (followed_id, follower_id, first_seen) VALUES (?, ?, ?)", f.ID, user.ID, now)
+			if err != nil {
+				return fmt.Errorf("writing followed user %s: %w", f.Login, err)
+			}
+		}
+
+		for _, r := range repos {
+			login, name, _ := strings.Cut(r.FullName, "/")
+			var owner sql.NullInt64
+			if id, ok := owners[strings.ToLower(login)]; ok {
+				owner = sql.NullInt64{Int64: int64(id), Valid: true}
+			}
+			topics, err := json.Marshal(append([]string{}, r.Topics...))
+			if err != nil {
+				return err
+			}
+			_, err = sqlTx.Exec(`INSERT INTO repos (id, name, full_name, private, owner, html_url, description,
+				created_at, updated_at, pushed_at, homepage, stargazers_count, language, archived, is_template, topics, readme)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				r.ID, name, r.FullName, r.Private, owner, r.HTMLURL, nullString(r.Description),
+				dogsheepTime(r.CreatedAt), dogsheepTime(r.UpdatedAt), dogsheepTime(r.PushedAt), nullString(r.Homepage),
+				r.StargazersCount, nullString(r.Language), r.Archived, r.IsTemplate, string(topics), r.Readme)
+			if err != nil {
+				return fmt.Errorf("writing %s: %w", r.FullName, err)
+			}
+			_, err = sqlTx.Exec("INSERT INTO stars (user, repo, starred_at) VALUES (?, ?, ?)", user.ID, r.ID, dogsheepTime(r.StarredAt))
+			if err != nil {
+				return fmt.Errorf("writing the star of %s: %w", r.FullName, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(repos), nil
+}
+
+// dogsheepTime formats t like the GitHub API does, as github-to-sqlite
+// stores it. The zero time is NULL.
+func dogsheepTime(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.UTC().Format(time.RFC3339), Valid: true}
+}
+
+// nullString returns NULL for empty strings, like the null fields of the
+// GitHub API.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,398 @@
+package store
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/upper/db/v4"
+)
+
+// SQL dialects of the dumps.
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+// DumpOptions configures a SQL dump of the database.
+type DumpOptions struct {
+	// Dialect is the SQL dialect of the statements, DialectSQLite when empty.
+	Dialect string
+	// DataOnly leaves the schema out, writing the rows only, to be loaded
+	// into an existing database.
+	DataOnly bool
+	// Progress, when set, is called once the rows of every table are
+	// written, with the number of rows and of tables written so far out of
+	// the total.
+	Progress func(table string, rows, done, total int)
+}
+
+// dumpTimeFormat is the format the sqlite driver stores times with, which
+// Postgres parses too.
+const dumpTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// dumpColumn is a column of a dumped table.
+type dumpColumn struct {
+	Name    string
+	Type    string
+	NotNull bool
+	Default sql.NullString
+	PK      int
+}
+
+// Dump writes the database in sess as SQL statements to w, in a single
+// transaction: the schema, unless opts.DataOnly, and an INSERT per row.
+// SQLite dumps keep the migrations version and rebuild the search index, so
+// the database is ready to be used by the exporter. Postgres dumps only have
+// the data tables, with their types mapped to Postgres ones. Returns the
+// number of rows written.
+func Dump(w io.Writer, sess db.Session, opts DumpOptions) (int, error) {
+	switch opts.Dialect {
+	case "":
+		opts.Dialect = DialectSQLite
+	case DialectSQLite, DialectPostgres:
+	default:
+		return 0, fmt.Errorf("unknown SQL dialect %q, expected %s or %s", opts.Dialect, DialectSQLite, DialectPostgres)
+	}
+	conn := sess.Driver().(*sql.DB)
+	sqlite := opts.Dialect == DialectSQLite
+
+	tables, err := dumpTables(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	if sqlite {
+		fmt.Fprintln(bw, "PRAGMA foreign_keys=OFF;")
+		fmt.Fprintln(bw, "BEGIN TRANSACTION;")
+	} else {
+		fmt.Fprintln(bw, "BEGIN;")
+	}
+
+	// The migrations version only makes sense to the exporter, and clashes
+	// with the one of the database loading the rows.
+	var dumped []string
+	for _, table := range tables {
+		if table != "schema_migrations" || (sqlite && !opts.DataOnly) {
+			dumped = append(dumped, table)
+		}
+	}
+
+	rows := 0
+	for i, table := range dumped {
+		columns, err := dumpColumns(conn, table)
+		if err != nil {
+			return rows, err
+		}
+		if !opts.DataOnly {
+			if err := writeCreateTable(bw, conn, table, columns, sqlite); err != nil {
+				return rows, err
+			}
+		}
+		n, err := writeInserts(bw, conn, table, columns, sqlite)
+		rows += n
+		if err != nil {
+			return rows, err
+		}
+		if opts.Progress != nil {
+			if err := bw.Flush(); err != nil {
+				return rows, err
+			}
+			opts.Progress(table, n, i+1, len(dumped))
+		}
+	}
+
+	if !opts.DataOnly {
+		if err := writeSchemaObjects(bw, conn, tables, sqlite); err != nil {
+			return rows, err
+		}
+	}
+	fmt.Fprintln(bw, "COMMIT;")
+
+	return rows, bw.Flush()
+}
+
+// dumpTables returns the names of the tables of the database, leaving out
+// the virtual tables, their shadow tables and the SQLite internal ones.
+func dumpTables(conn *sql.DB) ([]string, error) {
+	rows, err := conn.Query("SELECT name FROM pragma_table_list WHERE schema = 'main' AND type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+
+	return tables, rows.Err()
+}
+
+func dumpColumns(conn *sql.DB, table string) ([]dumpColumn, error) {
+	rows, err := conn.Query(`SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?) ORDER BY cid`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []dumpColumn
+	for rows.Next() {
+		var c dumpColumn
+		if err := rows.Scan(&c.Name, &c.Type, &c.NotNull, &c.Default, &c.PK); err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+
+	return columns, rows.Err()
+}
+
+// writeCreateTable writes the CREATE TABLE statement of table: the original
+// one for SQLite, one built from its columns for Postgres.
+func writeCreateTable(w io.Writer, conn *sql.DB, table string, columns []dumpColumn, sqlite bool) error {
+	if sqlite {
+		var stmt string
+		if err := conn.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&stmt); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(w, "%s;\n", stmt)
+		return err
+	}
+
+	var defs, pk []string
+	for _, c := range columns {
+		def := quoteIdent(c.Name) + " " + postgresType(c.Type)
+		if c.NotNull {
+			def += " NOT NULL"
+		}
+		if c.Default.Valid {
+			def += " DEFAULT " + c.Default.String
+		}
+		defs = append(defs, def)
+	}
+	sorted := append([]dumpColumn(nil), columns...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].PK < sorted[j].PK })
+	for _, c := range sorted {
+		if c.PK > 0 {
+			pk = append(pk, quoteIdent(c.Name))
+		}
+	}
+	if len(pk) > 0 {
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(pk, ", ")+")")
+	}
+	_, err := fmt.Fprintf(w, "CREATE TABLE %s (\n\t%s\n);\n", quoteIdent(table), strings.Join(defs, ",\n\t"))
+
+	return err
+}
+
+// writeInserts writes an INSERT statement per row of table, returning the
+// number of rows.
+func writeInserts(w io.Writer, conn *sql.DB, table string, columns []dumpColumn, sqlite bool) (int, error) {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = quoteIdent(c.Name)
+	}
+	list := strings.Join(names, ", ")
+
+	rows, err := conn.Query("SELECT " + list + " FROM " + quoteIdent(table))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	literals := make([]string, len(columns))
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+		for i, v := range values {
+			literals[i] = sqlLiteral(v, sqlite, !sqlite && postgresType(columns[i].Type) == "BOOLEAN")
+		}
+		if _, err := fmt.Fprintf(w, "INSERT INTO %s (%s) VALUES (%s);\n", quoteIdent(table), list, strings.Join(literals, ", ")); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, rows.Err()
+}
+
+// writeSchemaObjects writes the indexes and, for SQLite, the virtual tables
+// and triggers, once the rows are in so they're indexed in one go.
+func writeSchemaObjects(w io.Writer, conn *sql.DB, tables []string, sqlite bool) error {
+	if !sqlite {
+		for _, table := range tables {
+			if table == "schema_migrations" {
+				continue
+			}
+			if err := writePostgresIndexes(w, conn, table); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	rows, err := conn.Query(`SELECT type, name, sql FROM sqlite_master WHERE sql IS NOT NULL AND (
+		type IN ('index', 'trigger') OR (type = 'table' AND sql LIKE 'CREATE VIRTUAL TABLE%'))
+		ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 ELSE 2 END, name`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind, name, stmt string
+		if err := rows.Scan(&kind, &name, &stmt); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s;\n", stmt)
+		// External content full text indexes are rebuilt from the content
+		// table rather than dumped.
+		if kind == "table" && strings.Contains(strings.ToLower(stmt), "using fts") {
+			fmt.Fprintf(w, "INSERT INTO %s(%s) VALUES('rebuild');\n", quoteIdent(name), quoteIdent(name))
+		}
+	}
+
+	return rows.Err()
+}
+
+// writePostgresIndexes writes the indexes of table, including the ones of
+// its UNIQUE constraints. Primary keys are part of the table.
+func writePostgresIndexes(w io.Writer, conn *sql.DB, table string) error {
+	rows, err := conn.Query("SELECT name, \"unique\", origin FROM pragma_index_list(?) WHERE origin IN ('c', 'u') ORDER BY name", table)
+	if err != nil {
+		return err
+	}
+	type index struct {
+		name   string
+		unique bool
+		origin string
+	}
+	var indexes []index
+	for rows.Next() {
+		var i index
+		if err := rows.Scan(&i.name, &i.unique, &i.origin); err != nil {
+			rows.Close()
+			return err
+		}
+		indexes = append(indexes, i)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, i := range indexes {
+		var columns []string
+		cols, err := conn.Query("SELECT name FROM pragma_index_info(?) ORDER BY seqno", i.name)
+		if err != nil {
+			return err
+		}
+		for cols.Next() {
+			var c string
+			if err := cols.Scan(&c); err != nil {
+				cols.Close()
+				return err
+			}
+			columns = append(columns, c)
+		}
+		cols.Close()
+		if err := cols.Err(); err != nil {
+			return err
+		}
+
+		name := i.name
+		if i.origin == "u" {
+			name = table + "_" + strings.Join(columns, "_") + "_key"
+		}
+		for j, c := range columns {
+			columns[j] = quoteIdent(c)
+		}
+		unique := ""
+		if i.unique {
+			unique = "UNIQUE "
+		}
+		fmt.Fprintf(w, "CREATE %sINDEX %s ON %s (%s);\n", unique, quoteIdent(name), quoteIdent(table), strings.Join(columns, ", "))
+	}
+
+	return nil
+}
+
+// postgresType maps a SQLite column type to a Postgres one, following the
+// SQLite type affinity rules.
+func postgresType(t string) string {
+	t = strings.ToUpper(t)
+	switch {
+	case strings.Contains(t, "INT"):
+		return "BIGINT"
+	case strings.Contains(t, "BOOL"):
+		return "BOOLEAN"
+	case strings.Contains(t, "DATE"), strings.Contains(t, "TIME"):
+		return "TIMESTAMPTZ"
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return "DOUBLE PRECISION"
+	case strings.Contains(t, "BLOB"):
+		return "BYTEA"
+	}
+	return "TEXT"
+}
+
+// sqlLiteral returns v as a SQL literal. Booleans are stored as integers by
+// SQLite, boolean is set for the Postgres boolean columns.
+func sqlLiteral(v any, sqlite, boolean bool) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if sqlite {
+			if v {
+				return "1"
+			}
+			return "0"
+		}
+		return strings.ToUpper(strconv.FormatBool(v))
+	case int64:
+		if boolean {
+			return strings.ToUpper(strconv.FormatBool(v != 0))
+		}
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return quoteString(v.Format(dumpTimeFormat))
+	case []byte:
+		if sqlite {
+			return "X'" + hex.EncodeToString(v) + "'"
+		}
+		return "'\\x" + hex.EncodeToString(v) + "'::bytea"
+	case string:
+		return quoteString(v)
+	}
+	return quoteString(fmt.Sprint(v))
+}
+
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
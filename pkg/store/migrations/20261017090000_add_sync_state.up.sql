@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS sync_state (
+	key TEXT PRIMARY KEY,
+	value TEXT
+);
@@ -0,0 +1 @@
+ALTER TABLE starred_repos DROP COLUMN source;
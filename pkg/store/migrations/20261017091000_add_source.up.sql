@@ -0,0 +1 @@
+ALTER TABLE starred_repos ADD COLUMN source TEXT NOT NULL DEFAULT 'starred';
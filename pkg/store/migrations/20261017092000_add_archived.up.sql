@@ -0,0 +1 @@
+ALTER TABLE starred_repos ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS path_bookmarks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	full_name TEXT NOT NULL,
+	ref TEXT NOT NULL,
+	path TEXT NOT NULL,
+	url TEXT NOT NULL UNIQUE,
+	note TEXT,
+	created_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS path_bookmarks_full_name ON path_bookmarks (full_name COLLATE NOCASE);
@@ -0,0 +1,2 @@
+ALTER TABLE starred_repos DROP COLUMN pin_priority;
+ALTER TABLE starred_repos DROP COLUMN pinned;
@@ -0,0 +1,2 @@
+ALTER TABLE starred_repos ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE starred_repos ADD COLUMN pin_priority INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE starred_repos ADD COLUMN unstarred_at DATETIME;
@@ -0,0 +1 @@
+ALTER TABLE starred_repos ADD COLUMN readme_fetched_at DATETIME;
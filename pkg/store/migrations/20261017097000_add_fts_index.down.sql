@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS starred_repos_fts_ai;
+DROP TRIGGER IF EXISTS starred_repos_fts_au;
+DROP TRIGGER IF EXISTS starred_repos_fts_bd;
+DROP TRIGGER IF EXISTS starred_repos_fts_bu;
+DROP TABLE IF EXISTS starred_repos_fts;
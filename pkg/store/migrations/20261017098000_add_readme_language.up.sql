@@ -0,0 +1 @@
+ALTER TABLE starred_repos ADD COLUMN readme_language TEXT NOT NULL DEFAULT '';
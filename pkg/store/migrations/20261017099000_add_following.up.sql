@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS following (
+	id INTEGER PRIMARY KEY,
+	login TEXT NOT NULL,
+	html_url TEXT NOT NULL,
+	fetched_at DATETIME NOT NULL
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS sync_failures (
+	kind TEXT NOT NULL,
+	item TEXT NOT NULL,
+	repo_id INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	failed_at DATETIME NOT NULL,
+	PRIMARY KEY (kind, item)
+);
@@ -0,0 +1 @@
+-- The original time zone offsets aren't kept, nothing to revert.
@@ -0,0 +1,16 @@
+-- Timestamps written with a time zone offset are converted to UTC, so they
+-- compare and export consistently.
+UPDATE starred_repos SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', created_at)
+	WHERE created_at IS NOT NULL AND created_at NOT LIKE '%+00:00' AND created_at NOT LIKE '%Z';
+UPDATE starred_repos SET updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', updated_at)
+	WHERE updated_at IS NOT NULL AND updated_at NOT LIKE '%+00:00' AND updated_at NOT LIKE '%Z';
+UPDATE starred_repos SET pushed_at = strftime('%Y-%m-%d %H:%M:%f+00:00', pushed_at)
+	WHERE pushed_at IS NOT NULL AND pushed_at NOT LIKE '%+00:00' AND pushed_at NOT LIKE '%Z';
+UPDATE starred_repos SET starred_at = strftime('%Y-%m-%d %H:%M:%f+00:00', starred_at)
+	WHERE starred_at IS NOT NULL AND starred_at NOT LIKE '%+00:00' AND starred_at NOT LIKE '%Z';
+UPDATE starred_repos SET unstarred_at = strftime('%Y-%m-%d %H:%M:%f+00:00', unstarred_at)
+	WHERE unstarred_at IS NOT NULL AND unstarred_at NOT LIKE '%+00:00' AND unstarred_at NOT LIKE '%Z';
+UPDATE starred_repos SET readme_fetched_at = strftime('%Y-%m-%d %H:%M:%f+00:00', readme_fetched_at)
+	WHERE readme_fetched_at IS NOT NULL AND readme_fetched_at NOT LIKE '%+00:00' AND readme_fetched_at NOT LIKE '%Z';
+UPDATE path_bookmarks SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', created_at)
+	WHERE created_at IS NOT NULL AND created_at NOT LIKE '%+00:00' AND created_at NOT LIKE '%Z';
@@ -0,0 +1 @@
+ALTER TABLE starred_repos ADD COLUMN homepage TEXT NOT NULL DEFAULT '';
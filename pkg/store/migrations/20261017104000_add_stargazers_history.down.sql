@@ -0,0 +1 @@
+DROP TABLE IF EXISTS stargazers_history;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS stargazers_history (
+	repo_id INTEGER NOT NULL,
+	stargazers_count INTEGER NOT NULL,
+	recorded_at DATETIME NOT NULL,
+	PRIMARY KEY (repo_id, recorded_at)
+);
+CREATE INDEX IF NOT EXISTS stargazers_history_recorded_at ON stargazers_history (recorded_at);
@@ -0,0 +1,37 @@
+ALTER TABLE starred_repos ADD COLUMN reason TEXT NOT NULL DEFAULT '';
+
+DROP TRIGGER IF EXISTS starred_repos_fts_ai;
+DROP TRIGGER IF EXISTS starred_repos_fts_au;
+DROP TRIGGER IF EXISTS starred_repos_fts_bd;
+DROP TRIGGER IF EXISTS starred_repos_fts_bu;
+DROP TABLE IF EXISTS starred_repos_fts;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS starred_repos_fts USING fts4(
+	content="starred_repos",
+	full_name,
+	description,
+	description_translated,
+	topics,
+	readme,
+	reason
+);
+
+CREATE TRIGGER IF NOT EXISTS starred_repos_fts_bu BEFORE UPDATE ON starred_repos BEGIN
+	DELETE FROM starred_repos_fts WHERE docid = old.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS starred_repos_fts_bd BEFORE DELETE ON starred_repos BEGIN
+	DELETE FROM starred_repos_fts WHERE docid = old.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS starred_repos_fts_au AFTER UPDATE ON starred_repos BEGIN
+	INSERT INTO starred_repos_fts(docid, full_name, description, description_translated, topics, readme, reason)
+	VALUES (new.rowid, new.full_name, new.description, new.description_translated, new.topics, new.readme, new.reason);
+END;
+
+CREATE TRIGGER IF NOT EXISTS starred_repos_fts_ai AFTER INSERT ON starred_repos BEGIN
+	INSERT INTO starred_repos_fts(docid, full_name, description, description_translated, topics, readme, reason)
+	VALUES (new.rowid, new.full_name, new.description, new.description_translated, new.topics, new.readme, new.reason);
+END;
+
+INSERT INTO starred_repos_fts(starred_repos_fts) VALUES ('rebuild');
@@ -0,0 +1 @@
+ALTER TABLE starred_repos ADD COLUMN archived_at DATETIME;
@@ -0,0 +1 @@
+ALTER TABLE starred_repos ADD COLUMN refreshed_at DATETIME;
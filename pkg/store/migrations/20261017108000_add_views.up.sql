@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS views (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	repo_id INTEGER NOT NULL,
+	full_name TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	viewed_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS views_repo_id ON views (repo_id);
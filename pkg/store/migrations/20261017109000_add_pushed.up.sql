@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS pushed (
+	service TEXT NOT NULL,
+	repo_id INTEGER NOT NULL,
+	remote_id TEXT NOT NULL,
+	hash TEXT NOT NULL,
+	readme_hash TEXT NOT NULL DEFAULT '',
+	pushed_at DATETIME NOT NULL,
+	PRIMARY KEY (service, repo_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE starred_repos DROP COLUMN reason_at;
+ALTER TABLE starred_repos DROP COLUMN pinned_at;
@@ -0,0 +1,2 @@
+ALTER TABLE starred_repos ADD COLUMN pinned_at DATETIME;
+ALTER TABLE starred_repos ADD COLUMN reason_at DATETIME;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS sync_errors (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_at DATETIME NOT NULL,
+	kind TEXT NOT NULL,
+	item TEXT NOT NULL DEFAULT '',
+	repo_id INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL,
+	occurred_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sync_errors_run_at ON sync_errors (run_at);
@@ -0,0 +1,15 @@
+-- Only updates of the indexed columns reindex a star, so saving the
+-- refresh time, pins or unstars doesn't rewrite the index of every row.
+DROP TRIGGER IF EXISTS starred_repos_fts_au;
+DROP TRIGGER IF EXISTS starred_repos_fts_bu;
+
+CREATE TRIGGER IF NOT EXISTS starred_repos_fts_bu
+BEFORE UPDATE OF full_name, description, description_translated, topics, readme, reason ON starred_repos BEGIN
+	DELETE FROM starred_repos_fts WHERE docid = old.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS starred_repos_fts_au
+AFTER UPDATE OF full_name, description, description_translated, topics, readme, reason ON starred_repos BEGIN
+	INSERT INTO starred_repos_fts(docid, full_name, description, description_translated, topics, readme, reason)
+	VALUES (new.rowid, new.full_name, new.description, new.description_translated, new.topics, new.readme, new.reason);
+END;
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/upper/db/v4"
+)
+
+// GetState returns the value stored for key in the sync_state table, or an
+// empty string when not set.
+func GetState(sess db.Session, key string) (string, error) {
+	var row struct {
+		Value string `db:"value"`
+	}
+	err := sess.Collection("sync_state").Find(db.Cond{"key": key}).One(&row)
+	if errors.Is(err, db.ErrNoMoreRows) {
+		return "", nil
+	}
+
+	return row.Value, err
+}
+
+// SetState stores value for key in the sync_state table.
+func SetState(sess db.Session, key, value string) error {
+	_, err := sess.SQL().Exec(
+		`INSERT INTO sync_state (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+		key, value,
+	)
+	return err
+}
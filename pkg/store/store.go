@@ -0,0 +1,231 @@
+// Package store is the SQLite storage of the starred repositories: the
+// schema migrations, the stored types and the connection setup.
+package store
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/mattn/go-sqlite3"
+	"github.com/upper/db/v4"
+	"github.com/upper/db/v4/adapter/sqlite"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Repository is a starred or bookmarked repository, as stored in the
+// starred_repos table.
+type Repository struct {
+	ID              int            `json:"id" db:"id"`
+	Name            string         `json:"name" db:"name"`
+	HTMLURL         string         `json:"html_url" db:"html_url"`
+	Description     string         `json:"description" db:"description"`
+	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at" db:"updated_at"`
+	PushedAt        time.Time      `json:"pushed_at" db:"pushed_at"`
+	StargazersCount int            `json:"stargazers_count" db:"stargazers_count"`
+	Language        string         `json:"language" db:"language"`
+	FullName        string         `json:"full_name" db:"full_name"`
+	Topics          StringList     `json:"topics" db:"topics"`
+	IsTemplate      bool           `json:"is_template" db:"is_template"`
+	Private         bool           `json:"private" db:"private"`
+	StarredAt       time.Time      `json:"starred_at" db:"starred_at"`
+	Readme          NullString     `json:"readme" db:"readme"`
+	Source          string         `json:"source" db:"source"`
+	Archived        bool           `json:"archived" db:"archived"`
+	ArchivedAt      *time.Time     `json:"archived_at,omitempty" db:"archived_at"`
+	Homepage        string         `json:"homepage,omitempty" db:"homepage"`
+	Pinned          bool           `json:"pinned" db:"pinned"`
+	PinPriority     int            `json:"pin_priority" db:"pin_priority"`
+	PinnedAt        *time.Time     `json:"pinned_at,omitempty" db:"pinned_at"`
+	UnstarredAt     *time.Time     `json:"unstarred_at,omitempty" db:"unstarred_at"`
+	ReadmeFetchedAt *time.Time     `json:"readme_fetched_at,omitempty" db:"readme_fetched_at"`
+	RefreshedAt     *time.Time     `json:"refreshed_at,omitempty" db:"refreshed_at"`
+	ReadmeLanguage  string         `json:"readme_language,omitempty" db:"readme_language"`
+	Translation     string         `json:"description_translated,omitempty" db:"description_translated"`
+	Reason          string         `json:"reason,omitempty" db:"reason"`
+	ReasonAt        *time.Time     `json:"reason_at,omitempty" db:"reason_at"`
+	PathBookmarks   []PathBookmark `json:"path_bookmarks,omitempty" db:"-"`
+}
+
+// Repository sources.
+const (
+	// SourceStarred repositories come from the GitHub star list.
+	SourceStarred = "starred"
+	// SourceManual repositories were bookmarked using the ingest API.
+	SourceManual = "manual"
+)
+
+// PathBookmark is a bookmarked sub-path of a repository, such as a package
+// in a monorepo.
+type PathBookmark struct {
+	ID        int       `json:"id" db:"id,omitempty"`
+	FullName  string    `json:"full_name" db:"full_name"`
+	Ref       string    `json:"ref" db:"ref"`
+	Path      string    `json:"path" db:"path"`
+	URL       string    `json:"url" db:"url"`
+	Note      string    `json:"note,omitempty" db:"note"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// StringList is a list of strings stored as a comma separated column.
+type StringList []string
+
+func (sl StringList) Value() (driver.Value, error) {
+	return strings.Join(sl, ","), nil
+}
+
+func (sl *StringList) Scan(value interface{}) error {
+	if value == nil {
+		*sl = nil
+		return nil
+	}
+
+	if bv, err := driver.String.ConvertValue(value); err == nil {
+		if v, ok := bv.(string); ok {
+			*sl = strings.Split(v, ",")
+			return nil
+		}
+	}
+
+	return fmt.Errorf("failed to scan StringList")
+}
+
+// NullString is a nullable text column, encoded in JSON as a string or
+// null instead of the fields of sql.NullString.
+type NullString sql.NullString
+
+func (ns NullString) Value() (driver.Value, error) {
+	return sql.NullString(ns).Value()
+}
+
+func (ns *NullString) Scan(value interface{}) error {
+	return (*sql.NullString)(ns).Scan(value)
+}
+
+func (ns NullString) MarshalJSON() ([]byte, error) {
+	if !ns.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(ns.String)
+}
+
+func (ns *NullString) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*ns = NullString{}
+		return nil
+	}
+	if err := json.Unmarshal(b, &ns.String); err != nil {
+		return err
+	}
+	ns.Valid = true
+	return nil
+}
+
+// Options configures the database connections.
+type Options struct {
+	// Extensions are the paths of the SQLite extensions loaded on every
+	// new connection.
+	Extensions []string
+}
+
+// Open migrates the database file at path to the latest schema and opens
+// it.
+func Open(path string, opts Options) (db.Session, error) {
+	if err := Migrate(path); err != nil {
+		return nil, err
+	}
+
+	return Connect(path, opts)
+}
+
+// NewMigrate returns a migrate instance for the database file at path along
+// with the embedded migrations source.
+func NewMigrate(path string) (*migrate.Migrate, source.Driver, error) {
+	d, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", d, fmt.Sprintf("sqlite3://%s", path))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return m, d, nil
+}
+
+// Migrate applies the pending migrations to the database file at path.
+func Migrate(path string) error {
+	m, _, err := NewMigrate(path)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	err = m.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	return nil
+}
+
+var (
+	driversMu sync.Mutex
+	drivers   = map[string]string{}
+)
+
+// Connect opens the database file at path without migrating it, loading
+// the SQLite extensions in opts.
+func Connect(path string, opts Options) (db.Session, error) {
+	settings := sqlite.ConnectionURL{
+		Database: path,
+	}
+	if len(opts.Extensions) == 0 {
+		return sqlite.Open(settings)
+	}
+
+	sqlDB, err := sql.Open(extensionsDriver(opts.Extensions), settings.String())
+	if err != nil {
+		return nil, err
+	}
+	// sql.Open doesn't connect, make sure the extensions can be loaded.
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	return sqlite.New(sqlDB)
+}
+
+// extensionsDriver returns the name of a database/sql driver loading the
+// given extensions, registering it the first time it's needed.
+func extensionsDriver(extensions []string) string {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	key := strings.Join(extensions, "\x00")
+	if name, ok := drivers[key]; ok {
+		return name
+	}
+
+	name := fmt.Sprintf("sqlite3_extensions_%d", len(drivers))
+	sql.Register(name, &sqlite3.SQLiteDriver{
+		Extensions: extensions,
+	})
+	drivers[key] = name
+
+	return name
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rubiojr/gh-stars-exporter/pkg/stars"
+)
+
+// policyFile is a sync policy file, declaring what syncs fetch and keep:
+//
+//	store_private = false
+//	readmes = true
+//	readme_languages = ["go", "rust"]
+//	prune_unstarred_after = "90d"
+//	refresh_every = "7d"
+//
+// store_private and readmes are the --store-private and --get-readme flags,
+// which take precedence when given.
+type policyFile struct {
+	StorePrivate        *bool    `toml:"store_private"`
+	Readmes             *bool    `toml:"readmes"`
+	ReadmeLanguages     []string `toml:"readme_languages"`
+	PruneUnstarredAfter string   `toml:"prune_unstarred_after"`
+	RefreshEvery        string   `toml:"refresh_every"`
+}
+
+// syncPolicy is the policy of the syncs, loaded from --policy.
+var syncPolicy stars.Policy
+
+// loadPolicy reads the policy file at path, setting the global flags it
+// declares unless given in the command line. Unknown keys are rejected, a
+// typo would silently change what's stored.
+func loadPolicy(path string) (stars.Policy, error) {
+	var f policyFile
+	md, err := toml.DecodeFile(path, &f)
+	if err != nil {
+		return stars.Policy{}, err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, k := range undecoded {
+			keys[i] = k.String()
+		}
+		sort.Strings(keys)
+		return stars.Policy{}, fmt.Errorf("unknown policy keys: %s", strings.Join(keys, ", "))
+	}
+
+	p := stars.Policy{ReadmeLanguages: f.ReadmeLanguages}
+	if f.PruneUnstarredAfter != "" {
+		if p.PruneUnstarredAfter, err = parseAge(f.PruneUnstarredAfter); err != nil {
+			return p, fmt.Errorf("prune_unstarred_after: %w", err)
+		}
+	}
+	if f.RefreshEvery != "" {
+		if p.RefreshEvery, err = parseAge(f.RefreshEvery); err != nil {
+			return p, fmt.Errorf("refresh_every: %w", err)
+		}
+	}
+
+	set := map[string]bool{}
+	flag.Visit(func(fl *flag.Flag) {
+		set[fl.Name] = true
+	})
+	if f.StorePrivate != nil && !set["store-private"] {
+		storePrivate = *f.StorePrivate
+	}
+	if f.Readmes != nil && !set["get-readme"] {
+		getReadme = *f.Readmes
+	}
+	logger.Debugf("Loaded sync policy from %s", path)
+
+	return p, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startProfiling starts the pprof HTTP endpoint and the CPU profile when
+// requested. The returned function stops the CPU profile and writes the heap
+// profile, and must be called before the program exits.
+func startProfiling() (func(), error) {
+	if pprofAddr != "" {
+		logger.Infof("pprof endpoint listening on http://%s/debug/pprof/", pprofAddr)
+		go func() {
+			if err := http.ListenAndServe(pprofAddr, nil); err != nil {
+				logger.Errorf("pprof endpoint: %s", err)
+			}
+		}()
+	}
+
+	var cpuFile *os.File
+	if cpuProfile != "" {
+		f, err := os.Create(cpuProfile)
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		cpuFile = f
+	}
+
+	return func() {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			cpuFile.Close()
+			logger.Infof("CPU profile written to %s", cpuProfile)
+		}
+
+		if memProfile != "" {
+			if err := writeHeapProfile(memProfile); err != nil {
+				logger.Errorf("writing heap profile: %s", err)
+				return
+			}
+			logger.Infof("Heap profile written to %s", memProfile)
+		}
+	}, nil
+}
+
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	runtime.GC()
+	return pprof.WriteHeapProfile(f)
+}
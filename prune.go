@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/upper/db/v4"
+)
+
+// pruneCmd removes the stars unstarred upstream, which are otherwise kept in
+// the database flagged with unstarred_at. The repositories to be removed are
+// listed and confirmation asked before removing anything.
+func pruneCmd(args []string) error {
+	flags := flag.NewFlagSet("prune", flag.ExitOnError)
+	olderThan := flags.String("older-than", "0d", "Only prune stars unstarred longer ago than this (e.g. 90d)")
+	keep := flags.Bool("keep-unstarred", false, "Keep unstarred repositories as tombstones, removing their READMEs only")
+	yes := flags.Bool("yes", false, "Don't ask for confirmation")
+	flags.Parse(args)
+
+	age, err := parseAge(*olderThan)
+	if err != nil {
+		return err
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	cond := db.Cond{"unstarred_at <": time.Now().UTC().Add(-age)}
+	if *keep {
+		cond["readme IS NOT"] = nil
+	}
+	res := sess.Collection("starred_repos").Find(cond)
+
+	var repos []Repository
+	if err := res.Select("id", "full_name", "unstarred_at").OrderBy("unstarred_at").All(&repos); err != nil {
+		return err
+	}
+	if len(repos) == 0 {
+		logger.Info("Nothing to prune")
+		return nil
+	}
+
+	action := "Remove"
+	if *keep {
+		action = "Remove the README of"
+	}
+	for _, r := range repos {
+		fmt.Printf("%s (unstarred %s)\n", r.FullName, displayTime(*r.UnstarredAt).Format("2006-01-02"))
+	}
+
+	if !*yes {
+		ok, err := confirm(os.Stdin, fmt.Sprintf("%s %d repositories?", action, len(repos)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			logger.Info("Nothing pruned")
+			return nil
+		}
+	}
+
+	if *keep {
+		err = res.Update(map[string]interface{}{"readme": nil, "readme_language": ""})
+	} else {
+		err = res.Delete()
+	}
+	if err != nil {
+		return err
+	}
+	logger.Infof("Pruned %d repositories", len(repos))
+
+	return nil
+}
+
+// confirm asks a yes/no question on stderr, reading the answer from in.
+// Answering requires a terminal, --yes must be used otherwise.
+func confirm(in *os.File, question string) (bool, error) {
+	if stat, err := in.Stat(); err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+		return false, fmt.Errorf("confirmation required, use --yes when not running in a terminal")
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes", nil
+}
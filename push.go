@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+const pushUsage = `Usage: gh-stars-exporter push <service>
+
+Services:
+  linkding    Post the stars as bookmarks to a linkding instance
+  shaarli     Post the stars as links to a Shaarli instance
+  wallabag    Save the stars as articles in a wallabag instance, with their READMEs
+  notion      Keep a Notion database with a page per star
+`
+
+// Keys stored in the sync_state table, the starred time of the last star
+// pushed to each service.
+const (
+	stateLastPushLinkding = "last_push_linkding_at"
+	stateLastPushShaarli  = "last_push_shaarli_at"
+	stateLastPushWallabag = "last_push_wallabag_at"
+)
+
+// pushOptions modifies a push.
+type pushOptions struct {
+	// Since is the --since flag, last-run pushes the stars starred since
+	// the previous push. Services keeping a copy of every star ignore it.
+	Since string
+	// Private makes the Shaarli links private.
+	Private bool
+}
+
+// pushers maps the services to the functions pushing the stars to them.
+var pushers = map[string]func(ctx context.Context, sess db.Session, opts pushOptions) error{
+	"linkding": pushLinkding,
+	"shaarli":  pushShaarli,
+	"wallabag": pushWallabag,
+	"notion":   pushNotion,
+	"airtable": pushAirtable,
+}
+
+// pushCmd groups the integrations pushing the stars to other services.
+func pushCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, pushUsage)
+		return errUsage
+	}
+	push, ok := pushers[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, pushUsage)
+		return errUsage
+	}
+
+	flags := flag.NewFlagSet("push "+args[0], flag.ExitOnError)
+	opts := pushOptions{}
+	flags.StringVar(&opts.Since, "since", "last-run", "Push the stars starred after this date (YYYY-MM-DD, RFC 3339, a duration such as 7d, or last-run)")
+	flags.BoolVar(&opts.Private, "private", false, "Make the links private (shaarli)")
+	flags.Parse(args[1:])
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	return push(ctx, sess, opts)
+}
+
+// pushStars pushes the public stars starred after the given --since flag,
+// oldest first, with push, recording the starred time of the last one
+// pushed under key so that the next push only sends the newer ones. The
+// progress is recorded when a push fails too, up to the stars starred at the
+// same time as the failed one, which are pushed again next time.
+func pushStars(sess db.Session, since, key string, push func(r Repository) error) error {
+	from, err := resolveSince(sess, since, key)
+	if err != nil {
+		return err
+	}
+
+	var repos []Repository
+	err = sess.Collection("starred_repos").
+		Find(db.Cond{"source": sourceStarred, "unstarred_at IS": nil, "private": false}).
+		And(db.Raw("julianday(starred_at) > julianday(?)", from.UTC())).
+		OrderBy(db.Raw("julianday(starred_at)"), "id").
+		All(&repos)
+	if err != nil {
+		return err
+	}
+
+	pushed := 0
+	// done is the starred time up to which all the stars were pushed.
+	var done, last time.Time
+	for _, r := range repos {
+		if !r.StarredAt.Equal(last) {
+			done = last
+		}
+		r.Topics = normalizeTopics(r.Topics, config.Topics.Aliases)
+		if err = push(r); err != nil {
+			err = fmt.Errorf("pushing %s: %w", r.FullName, err)
+			break
+		}
+		logger.Debugf("Pushed %s", r.FullName)
+		last = r.StarredAt
+		pushed++
+	}
+	logger.Infof("Pushed %d stars", pushed)
+
+	if err == nil {
+		done = last
+	}
+	if !done.IsZero() {
+		if serr := store.SetState(sess, key, done.UTC().Format(time.RFC3339Nano)); serr != nil && err == nil {
+			err = serr
+		}
+	}
+
+	return err
+}
+
+type linkdingBookmark struct {
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Notes       string   `json:"notes,omitempty"`
+	TagNames    []string `json:"tag_names"`
+}
+
+// pushLinkding posts the stars as bookmarks to the linkding instance from
+// the [push.linkding] configuration section, titled with the repository
+// name, described with its description and tagged with its topics. linkding
+// updates the bookmarks already stored for the same URL.
+func pushLinkding(ctx context.Context, sess db.Session, opts pushOptions) error {
+	cfg := config.Push.Linkding
+	if cfg.URL == "" || cfg.Token == "" {
+		return fmt.Errorf("no linkding instance configured, set url and token in the [push.linkding] section of %s", configFile)
+	}
+
+	client := newHTTPClient(httpTimeout)
+	endpoint := strings.TrimSuffix(cfg.URL, "/") + "/api/bookmarks/"
+	return pushStars(sess, opts.Since, stateLastPushLinkding, func(r Repository) error {
+		tags := []string{}
+		for _, t := range r.Topics {
+			if t != "" {
+				tags = append(tags, t)
+			}
+		}
+		body, err := json.Marshal(linkdingBookmark{
+			URL:         r.HTMLURL,
+			Title:       r.FullName,
+			Description: strings.Join(strings.Fields(r.Description), " "),
+			Notes:       r.Reason,
+			TagNames:    tags,
+		})
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Token "+cfg.Token)
+
+		return doPush(client, req, nil)
+	})
+}
+
+// pushStatusError is an error status returned by a service, with the start
+// of the response body.
+type pushStatusError struct {
+	Status string
+	Code   int
+	Body   string
+	// RetryAfter is the Retry-After header of rate limited requests.
+	RetryAfter string
+}
+
+func (e *pushStatusError) Error() string {
+	return e.Status + ": " + e.Body
+}
+
+// doPush sends req, decoding the JSON response into v unless nil. Error
+// statuses fail with a *pushStatusError.
+func doPush(client *http.Client, req *http.Request, v any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &pushStatusError{
+			Status:     resp.Status,
+			Code:       resp.StatusCode,
+			Body:       strings.TrimSpace(string(b)),
+			RetryAfter: resp.Header.Get("Retry-After"),
+		}
+	}
+	if v != nil {
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+
+	return err
+}
+
+// retryPush calls do, retrying it up to 3 times when the service rate limits
+// it, after the Retry-After time or wait when the response has none.
+func retryPush(ctx context.Context, service string, wait time.Duration, do func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := do()
+		var serr *pushStatusError
+		if !errors.As(err, &serr) || serr.Code != http.StatusTooManyRequests || attempt == 3 {
+			return err
+		}
+		after := wait
+		if s, err := strconv.Atoi(serr.RetryAfter); err == nil {
+			after = time.Duration(s) * time.Second
+		}
+		logger.Debugf("%s rate limit reached, retrying in %s", service, after)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(after):
+		}
+	}
+}
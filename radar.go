@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// radarFirstRun is how far back radar looks the first time it runs.
+const radarFirstRun = 7 * 24 * time.Hour
+
+// radarCmd prints a ranked Markdown list of the starred repositories pushed
+// to since the given date, suitable as the body of a digest email.
+func radarCmd(args []string) error {
+	flags := flag.NewFlagSet("radar", flag.ExitOnError)
+	since := flags.String("since", "last-run", "Start date (YYYY-MM-DD, RFC 3339, a duration such as 7d, or last-run)")
+	limit := flags.Int("limit", 20, "Maximum number of repositories listed, 0 lists all")
+	flags.Parse(args)
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	from, err := resolveSince(sess, *since, stateLastRadar)
+	if err != nil {
+		return err
+	}
+	if from.IsZero() {
+		from = time.Now().Add(-radarFirstRun)
+	}
+
+	now := time.Now().UTC()
+	if err := writeRadar(sess, os.Stdout, from, *limit); err != nil {
+		return err
+	}
+
+	return store.SetState(sess, stateLastRadar, now.Format(time.RFC3339))
+}
+
+// writeRadar lists the repositories pushed to since from, pinned ones first,
+// then by popularity.
+func writeRadar(sess db.Session, w io.Writer, from time.Time, limit int) error {
+	from = from.UTC()
+
+	var updated []Repository
+	q := sess.Collection("starred_repos").
+		Find(db.Cond{"pushed_at >": from, "unstarred_at IS": nil}).
+		OrderBy("-pinned", "-pin_priority", "-stargazers_count", "-pushed_at")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	if err := q.All(&updated); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "# Radar since %s\n", displayTime(from).Format("2006-01-02"))
+	if len(updated) == 0 {
+		fmt.Fprintf(w, "\nNothing new.\n")
+		return nil
+	}
+
+	fmt.Fprintf(w, "\n## Recently pushed (%d)\n\n", len(updated))
+	for i, r := range updated {
+		fmt.Fprintf(w, "%d. %s\n", i+1, radarEntry(r))
+	}
+
+	return nil
+}
+
+func radarEntry(r Repository) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s](%s)", r.FullName, r.HTMLURL)
+	if r.Pinned {
+		b.WriteString(" (pinned)")
+	}
+	fmt.Fprintf(&b, ", pushed %s, %d stars", displayTime(r.PushedAt).Format("2006-01-02"), r.StargazersCount)
+	if r.Archived {
+		b.WriteString(", archived")
+	}
+	if r.Description != "" {
+		fmt.Fprintf(&b, ": %s", strings.TrimSpace(r.Description))
+	}
+
+	return b.String()
+}
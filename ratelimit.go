@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitsConfig throttles the requests to the services other than
+// GitHub, per host, so link checks and pushes stay polite toward them.
+type RateLimitsConfig struct {
+	// Default applies to the hosts not listed in Hosts, e.g. 5/s, 30/m or
+	// 1000/h. "none" doesn't limit them.
+	Default string `toml:"default"`
+	// Hosts overrides the default for some hosts, e.g.
+	// "web.archive.org" = "15/m". "*.example.com" matches the subdomains of
+	// example.com.
+	Hosts map[string]string `toml:"hosts"`
+}
+
+// validate checks the rates are valid.
+func (c RateLimitsConfig) validate() error {
+	if _, err := parseRate(c.Default); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for host, r := range c.Hosts {
+		if _, err := parseRate(r); err != nil {
+			return fmt.Errorf("%s: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// interval returns the time between two requests to host, 0 when
+// unlimited.
+func (c RateLimitsConfig) interval(host string) time.Duration {
+	host = strings.ToLower(host)
+	r, ok := c.Hosts[host]
+	if !ok {
+		r = c.Default
+		// The most specific wildcard wins.
+		match := ""
+		for pattern, pr := range c.Hosts {
+			suffix, wildcard := strings.CutPrefix(pattern, "*")
+			if wildcard && strings.HasSuffix(host, suffix) && len(suffix) > len(match) {
+				match, r = suffix, pr
+			}
+		}
+	}
+	interval, _ := parseRate(r)
+	return interval
+}
+
+// parseRate parses a rate, N/s, N/m or N/h, returning the time between two
+// requests. Empty and "none" are no limit.
+func parseRate(s string) (time.Duration, error) {
+	if s == "" || s == "none" {
+		return 0, nil
+	}
+	n, unit, ok := strings.Cut(s, "/")
+	count, err := strconv.Atoi(strings.TrimSpace(n))
+	if !ok || err != nil || count < 1 {
+		return 0, fmt.Errorf("invalid rate %q, expected N/s, N/m or N/h", s)
+	}
+
+	var per time.Duration
+	switch strings.TrimSpace(unit) {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return 0, fmt.Errorf("invalid rate %q, expected N/s, N/m or N/h", s)
+	}
+
+	return per / time.Duration(count), nil
+}
+
+// hostSlots schedules the requests to every host, shared by all the rate
+// limited clients so concurrent requests to a host queue up.
+var hostSlots = struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+}{next: map[string]time.Time{}}
+
+// waitForHost waits for the turn of a request to host.
+func waitForHost(ctx context.Context, host string) error {
+	interval := config.RateLimits.interval(host)
+	if interval == 0 {
+		return nil
+	}
+
+	hostSlots.mu.Lock()
+	now := time.Now()
+	at := hostSlots.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	hostSlots.next[host] = at.Add(interval)
+	hostSlots.mu.Unlock()
+
+	wait := time.Until(at)
+	if wait <= 0 {
+		return nil
+	}
+	logger.Debugf("Waiting %s for the rate limit of %s", wait.Round(time.Millisecond), host)
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// newHTTPClient returns the client of the requests to the services other
+// than GitHub, rate limited per host by the [rate_limits] configuration.
+// timeout applies to every request from the moment its turn comes, time
+// spent waiting for it doesn't count.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: &rateLimitedTransport{timeout: timeout}}
+}
+
+// rateLimitedTransport is the http.RoundTripper of newHTTPClient.
+type rateLimitedTransport struct {
+	timeout time.Duration
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := waitForHost(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	if t.timeout <= 0 {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := http.DefaultTransport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The timeout covers reading the body too.
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// cancelBody cancels the context of the request once the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/stars"
+	"github.com/upper/db/v4"
+)
+
+// fetchReadmesCmd fetches the missing READMEs of the stored stars matching
+// the given language and popularity, so the database can be enriched only
+// where it matters.
+func fetchReadmesCmd(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("fetch-readmes", flag.ExitOnError)
+	language := flags.String("language", "", "Only fetch READMEs of repositories written in this language")
+	minStars := flags.Int("min-stars", 0, "Only fetch READMEs of repositories with at least this many stars")
+	limit := flags.Int("limit", 0, "Maximum number of READMEs fetched, 0 fetches all")
+	batch := flags.Int("batch", 50, "Number of READMEs fetched per GraphQL query, 1 fetches them one by one using the REST API")
+	flags.Parse(args)
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	cond := db.And(db.Cond{
+		"readme IS":           nil,
+		"unstarred_at IS":     nil,
+		"stargazers_count >=": *minStars,
+	})
+	if *language != "" {
+		cond = cond.And(db.Raw("language = ? COLLATE NOCASE", *language))
+	}
+	// Hashed private repository names can't be resolved upstream.
+	if !config.Private.Readme || config.Private.HashNames {
+		cond = cond.And(db.Cond{"private": false})
+	}
+
+	res := sess.Collection("starred_repos").Find(cond).OrderBy("-stargazers_count")
+	if *limit > 0 {
+		res = res.Limit(*limit)
+	}
+	var repos []*Repository
+	if err := res.All(&repos); err != nil {
+		return err
+	}
+
+	gh, err := newGitHubClient()
+	if err != nil {
+		return err
+	}
+	syncer := newSyncer(gh, sess)
+	started := time.Now()
+	fetched, err := fetchReadmes(ctx, sess, syncer, repos, max(*batch, 1))
+	if rerr := syncer.SaveReport(started, err); rerr != nil {
+		logger.Warnf("Saving the error report: %s", rerr)
+	}
+	if err != nil {
+		return err
+	}
+	logger.Infof("Fetched %d READMEs", fetched)
+
+	return nil
+}
+
+// fetchReadmes fetches the missing READMEs of repos, size at a time,
+// returning the number of READMEs found.
+func fetchReadmes(ctx context.Context, sess db.Session, syncer *stars.Syncer, repos []*Repository, size int) (int, error) {
+	fetched := 0
+	for start := 0; start < len(repos); start += size {
+		if err := ctx.Err(); err != nil {
+			return fetched, err
+		}
+
+		batch := repos[start:min(start+size, len(repos))]
+		if len(batch) == 1 {
+			logger.Infof("Fetching README for %s (%d/%d)", batch[0].FullName, start+1, len(repos))
+		} else {
+			logger.Infof("Fetching READMEs (%d-%d/%d)", start+1, start+len(batch), len(repos))
+		}
+		fetched += syncer.FetchMissingReadmes(ctx, batch, size)
+		for _, r := range batch {
+			if err := sess.Collection("starred_repos").Find(r.ID).Update(r); err != nil {
+				return fetched, err
+			}
+		}
+	}
+
+	return fetched, nil
+}
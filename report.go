@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/stars"
+	"github.com/upper/db/v4"
+)
+
+const reportUsage = `Usage: gh-stars-exporter report <command>
+
+Commands:
+  advisories  Report the known security advisories affecting the packages of the starred repos
+  bloat       List the largest rows, optionally truncating or dropping READMEs
+  eras        Count the stars by the era repositories were created in and the year starred
+  errors      Print the errors of the last sync run: failed inserts, READMEs and API responses
+  following   Correlate the followed users with the owners of starred repos
+  linkcheck   Check the homepage URLs of the starred repos, flagging dead links
+  scatter     Print the age, activity and popularity of every star, for plotting
+  trending    Rank the starred repos by stargazers growth since the previous sync
+`
+
+// reportCmd groups the database reports.
+func reportCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, reportUsage)
+		return errUsage
+	}
+
+	switch args[0] {
+	case "advisories":
+		return reportAdvisories(ctx, args[1:])
+	case "bloat":
+		return reportBloat(args[1:])
+	case "eras":
+		return reportEras(args[1:])
+	case "errors":
+		return reportErrors(args[1:])
+	case "following":
+		return reportFollowing(args[1:])
+	case "linkcheck":
+		return reportLinkcheck(args[1:])
+	case "scatter":
+		return reportScatter(args[1:])
+	case "trending":
+		return reportTrending(args[1:])
+	default:
+		fmt.Fprint(os.Stderr, reportUsage)
+		return errUsage
+	}
+}
+
+// rowSizeExpr is the approximate size in bytes of the text stored in a
+// starred_repos row.
+const rowSizeExpr = `LENGTH(CAST(COALESCE(readme, '') AS BLOB)) + LENGTH(CAST(COALESCE(description, '') AS BLOB)) + LENGTH(CAST(COALESCE(topics, '') AS BLOB))`
+
+type bloatRow struct {
+	ID         int    `db:"id"`
+	FullName   string `db:"full_name"`
+	Size       int64  `db:"size"`
+	ReadmeSize int64  `db:"readme_size"`
+}
+
+// reportBloat lists the largest rows of the database. READMEs over a size can
+// be truncated or dropped, vacuuming the database afterwards.
+func reportBloat(args []string) error {
+	flags := flag.NewFlagSet("bloat", flag.ExitOnError)
+	limit := flags.Int("limit", 20, "Number of rows listed")
+	truncate := flags.String("truncate", "", "Truncate READMEs larger than this size (e.g. 256KiB)")
+	drop := flags.String("drop", "", "Remove READMEs larger than this size (e.g. 1MiB)")
+	flags.Parse(args)
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	changed := false
+	if *drop != "" {
+		size, err := parseBytes(*drop)
+		if err != nil {
+			return err
+		}
+		res, err := sess.SQL().Exec(`UPDATE starred_repos SET readme = NULL, readme_language = '' WHERE LENGTH(CAST(readme AS BLOB)) > ?`, size)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		logger.Infof("Removed %d READMEs larger than %s", n, humanBytes(size))
+		changed = changed || n > 0
+	}
+
+	if *truncate != "" {
+		size, err := parseBytes(*truncate)
+		if err != nil {
+			return err
+		}
+		n, err := truncateReadmes(sess, size)
+		if err != nil {
+			return err
+		}
+		logger.Infof("Truncated %d READMEs larger than %s", n, humanBytes(size))
+		changed = changed || n > 0
+	}
+
+	if changed {
+		logger.Info("Vacuuming the database")
+		// The sqlite adapter wraps statements in transactions, which VACUUM
+		// doesn't support.
+		if _, err := sess.Driver().(*sql.DB).Exec("VACUUM"); err != nil {
+			return err
+		}
+	}
+
+	return writeBloat(sess, os.Stdout, *limit)
+}
+
+func writeBloat(sess db.Session, out io.Writer, limit int) error {
+	var rows []bloatRow
+	err := sess.SQL().
+		Select("id", "full_name",
+			db.Raw(rowSizeExpr+" AS size"),
+			db.Raw("LENGTH(CAST(COALESCE(readme, '') AS BLOB)) AS readme_size")).
+		From("starred_repos").
+		OrderBy("-size").
+		Limit(limit).
+		All(&rows)
+	if err != nil {
+		return err
+	}
+
+	var total sql.NullInt64
+	row, err := sess.SQL().QueryRow("SELECT SUM(" + rowSizeExpr + ") FROM starred_repos")
+	if err != nil {
+		return err
+	}
+	if err := row.Scan(&total); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "Repository\tSize\tREADME\tShare")
+	for _, r := range rows {
+		share := 0.0
+		if total.Int64 > 0 {
+			share = float64(r.Size) * 100 / float64(total.Int64)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.1f%%\n", r.FullName, humanBytes(r.Size), humanBytes(r.ReadmeSize), share)
+	}
+	fmt.Fprintf(w, "\nTotal\t%s\n", humanBytes(total.Int64))
+
+	return w.Flush()
+}
+
+// truncateReadmes truncates the READMEs larger than size bytes, returning
+// how many were truncated.
+func truncateReadmes(sess db.Session, size int64) (int, error) {
+	var repos []Repository
+	err := sess.Collection("starred_repos").
+		Find(db.Raw("LENGTH(CAST(readme AS BLOB)) > ?", size)).
+		Select("id", "readme").
+		All(&repos)
+	if err != nil {
+		return 0, err
+	}
+
+	limits := stars.LimitsConfig{MaxReadmeBytes: int(size)}
+	err = sess.Tx(func(tx db.Session) error {
+		for _, r := range repos {
+			readme := stars.SanitizeReadme(r.Readme.String, limits)
+			if _, err := tx.SQL().Update("starred_repos").Set("readme", readme).Where("id = ?", r.ID).Exec(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return len(repos), err
+}
+
+// parseBytes parses a size in bytes, optionally followed by a binary unit
+// (K, KiB, M, MiB, G, GiB) or a decimal one (KB, MB, GB).
+func parseBytes(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1},
+	}
+
+	mult := int64(1)
+	for _, u := range units {
+		if n, ok := strings.CutSuffix(s, u.suffix); ok {
+			s, mult = n, u.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return n * mult, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/upper/db/v4"
+)
+
+// scatterPoint is a starred repository as a point of an age vs. activity
+// scatter plot.
+type scatterPoint struct {
+	FullName        string    `json:"full_name"`
+	Language        string    `json:"language"`
+	CreatedAt       time.Time `json:"created_at"`
+	PushedAt        time.Time `json:"pushed_at"`
+	StargazersCount int       `json:"stargazers_count"`
+	StarredAt       time.Time `json:"starred_at"`
+}
+
+// reportScatter prints the creation and last push dates, stargazers and
+// starring date of every star, ready to be plotted. It runs both stats
+// scatter and report scatter.
+func reportScatter(args []string) error {
+	flags := flag.NewFlagSet("scatter", flag.ExitOnError)
+	format := flags.String("format", "json", "Output format: json or csv")
+	jsonOut := flags.Bool("json", false, "Print JSON, same as --format json")
+	flags.Parse(args)
+
+	if *jsonOut {
+		*format = "json"
+	}
+
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown format %q, expected json or csv", *format)
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	var stars []Repository
+	err = sess.Collection("starred_repos").
+		Find(db.Cond{"source": sourceStarred, "unstarred_at IS": nil}).
+		Select("full_name", "language", "created_at", "pushed_at", "stargazers_count", "starred_at").
+		OrderBy("starred_at").
+		All(&stars)
+	if err != nil {
+		return err
+	}
+
+	points := make([]scatterPoint, 0, len(stars))
+	for _, r := range stars {
+		points = append(points, scatterPoint{
+			FullName:        r.FullName,
+			Language:        r.Language,
+			CreatedAt:       r.CreatedAt.UTC(),
+			PushedAt:        r.PushedAt.UTC(),
+			StargazersCount: r.StargazersCount,
+			StarredAt:       r.StarredAt.UTC(),
+		})
+	}
+
+	if *format == "csv" {
+		return writeScatterCSV(os.Stdout, points)
+	}
+
+	b, err := json.MarshalIndent(points, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(string(b))
+	return err
+}
+
+func writeScatterCSV(out io.Writer, points []scatterPoint) error {
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"full_name", "language", "created_at", "pushed_at", "stargazers_count", "starred_at"}); err != nil {
+		return err
+	}
+
+	for _, p := range points {
+		record := []string{
+			p.FullName,
+			p.Language,
+			p.CreatedAt.Format(time.RFC3339),
+			p.PushedAt.Format(time.RFC3339),
+			strconv.Itoa(p.StargazersCount),
+			p.StarredAt.Format(time.RFC3339),
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
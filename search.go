@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/export"
+)
+
+// searchCmd prints the stars matching a full text search of their names,
+// descriptions, topics and READMEs, or a saved search, in any export
+// format.
+func searchCmd(args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	format := flags.String("format", "markdown", "Output format: "+exportFormats())
+	opts := exportOptions{}
+	flags.Var(&opts.Only, "only", "Only list stars matching owner:NAME, topic:NAME, language:NAME or readme-language:CODE (repeatable)")
+	flags.IntVar(&opts.Limit, "limit", 0, "Maximum number of results, 0 lists all")
+	flags.BoolVar(&opts.SSH, "ssh", false, "Use SSH clone URLs (repos.txt and clone-script)")
+	maxAge := flags.String("max-age", "", "Leave out the stars not refreshed within this age, e.g. 90d")
+	flags.BoolVar(&opts.Freshness, "freshness", true, "Show when the metadata and README were refreshed (markdown)")
+	output := flags.String("output", "", "Write the results to a file instead of stdout, replacing it atomically")
+	gzip := flags.Bool("gzip", false, "Compress the results with gzip")
+	saved := flags.String("saved", "", "Run the saved search of the [searches.NAME] configuration section, narrowed by the QUERY given")
+	flags.Parse(args)
+
+	opts.Query = strings.Join(flags.Args(), " ")
+	if *saved != "" {
+		s, err := savedSearchOptions(*saved)
+		if err != nil {
+			return err
+		}
+		// Flags given in the command line take precedence.
+		if len(opts.Only) == 0 {
+			opts.Only = s.Only
+		}
+		if opts.Limit == 0 {
+			opts.Limit = s.Limit
+		}
+		if *maxAge == "" {
+			opts.MaxAge = s.MaxAge
+		}
+		opts.Query = strings.TrimSpace(s.Query + " " + opts.Query)
+	} else if opts.Query == "" {
+		return fmt.Errorf("usage: gh-stars-exporter search [flags] QUERY")
+	}
+	if !export.HasFormat(*format) {
+		return fmt.Errorf("unknown format %q, expected one of %s", *format, exportFormats())
+	}
+	if err := applyGzip(&opts, *gzip); err != nil {
+		return err
+	}
+	if *maxAge != "" {
+		age, err := parseAge(*maxAge)
+		if err != nil {
+			return fmt.Errorf("--max-age: %w", err)
+		}
+		opts.MaxAge = age
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	if *output != "" {
+		return exportToFile(sess, *output, *format, opts)
+	}
+
+	n, err := exportStars(sess, os.Stdout, *format, opts)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("%d stars found", n)
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient/githubtest"
+	"github.com/rubiojr/gh-stars-exporter/pkg/stars"
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// searchJSON runs a search with opts, returning the stars found.
+func searchJSON(t *testing.T, sess db.Session, opts exportOptions) []Repository {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := exportStars(sess, &buf, "json", opts); err != nil {
+		t.Fatalf("search %q: %s", opts.Query, err)
+	}
+	var found []Repository
+	if err := json.Unmarshal(buf.Bytes(), &found); err != nil {
+		t.Fatalf("decoding %s: %s", buf.String(), err)
+	}
+
+	return found
+}
+
+func TestSearchRefreshedDescription(t *testing.T) {
+	sess, err := store.Open(filepath.Join(t.TempDir(), "stars.db"), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+
+	star := githubclient.StarredRepo{
+		StarredAt: time.Now().UTC().Add(-24 * time.Hour).Truncate(time.Second),
+		Repo: githubclient.Repository{
+			ID:          1,
+			Name:        "tool",
+			FullName:    "owner/tool",
+			HTMLURL:     "https://github.com/owner/tool",
+			Description: "An abandoned prototype",
+		},
+	}
+	srv := githubtest.NewServer(star)
+	defer srv.Close()
+	sync := func() {
+		t.Helper()
+		if err := stars.New(srv.Client(), sess, stars.Options{Logger: log.New(io.Discard)}).Sync(context.Background()); err != nil {
+			t.Fatalf("sync: %s", err)
+		}
+	}
+	sync()
+
+	// The description changes upstream long after the last refresh.
+	stale := time.Now().UTC().Add(-200 * 24 * time.Hour)
+	if err := sess.Collection("starred_repos").Find(1).Update(map[string]interface{}{"refreshed_at": stale}); err != nil {
+		t.Fatal(err)
+	}
+	if found := searchJSON(t, sess, exportOptions{Query: "prototype", MaxAge: 90 * 24 * time.Hour}); len(found) != 0 {
+		t.Fatalf("stale star found: %+v", found)
+	}
+	star.Repo.Description = "A maintained command line tool"
+	srv.SetStars(star)
+	sync()
+
+	found := searchJSON(t, sess, exportOptions{Query: "maintained", MaxAge: 90 * 24 * time.Hour})
+	if len(found) != 1 {
+		t.Fatalf("found %d stars, want owner/tool", len(found))
+	}
+	if r := found[0]; r.Description != star.Repo.Description {
+		t.Errorf("description = %q, want %q", r.Description, star.Repo.Description)
+	}
+	if r := found[0]; r.RefreshedAt == nil || !r.RefreshedAt.After(stale.Add(time.Hour)) {
+		t.Errorf("refreshed at %v, want after %v", r.RefreshedAt, stale)
+	}
+	if found := searchJSON(t, sess, exportOptions{Query: "prototype"}); len(found) != 0 {
+		t.Errorf("old description still found: %+v", found)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/export"
+)
+
+// savedSearchOptions returns the export options of the saved search name.
+func savedSearchOptions(name string) (exportOptions, error) {
+	opts := exportOptions{}
+	s, ok := config.Searches[name]
+	if !ok {
+		return opts, fmt.Errorf("unknown saved search %q, expected one of %s", name, strings.Join(savedSearchNames(), ", "))
+	}
+
+	opts.Query = s.Query
+	opts.Limit = s.Limit
+	for _, f := range s.Only {
+		if err := opts.Only.Set(f); err != nil {
+			return opts, fmt.Errorf("saved search %s: %w", name, err)
+		}
+	}
+	if s.MaxAge != "" {
+		age, err := parseAge(s.MaxAge)
+		if err != nil {
+			return opts, fmt.Errorf("saved search %s: max_age: %w", name, err)
+		}
+		opts.MaxAge = age
+	}
+
+	return opts, nil
+}
+
+// savedSearchNames returns the names of the saved searches, sorted.
+func savedSearchNames() []string {
+	names := []string{}
+	for name := range config.Searches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// apiSavedSearch is a saved search listed by /api/searches.
+type apiSavedSearch struct {
+	Name   string   `json:"name"`
+	Query  string   `json:"query,omitempty"`
+	Only   []string `json:"only,omitempty"`
+	MaxAge string   `json:"max_age,omitempty"`
+	Limit  int      `json:"limit,omitempty"`
+}
+
+// handleSearches lists the saved searches.
+func (s *server) handleSearches(w http.ResponseWriter, r *http.Request) {
+	searches := []apiSavedSearch{}
+	for _, name := range savedSearchNames() {
+		saved := config.Searches[name]
+		searches = append(searches, apiSavedSearch{
+			Name:   name,
+			Query:  saved.Query,
+			Only:   saved.Only,
+			MaxAge: saved.MaxAge,
+			Limit:  saved.Limit,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(searches); err != nil {
+		logger.Errorf("writing response: %s", err)
+	}
+}
+
+// handleSavedSearch runs the saved search in the path, listing the public
+// stars matching it like /api/stars does. READMEs are left out unless
+// readme=true.
+func (s *server) handleSavedSearch(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, ok := config.Searches[name]; !ok {
+		http.Error(w, fmt.Sprintf("unknown saved search %q", name), http.StatusNotFound)
+		return
+	}
+	opts, err := savedSearchOptions(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	readme, _ := strconv.ParseBool(r.URL.Query().Get("readme"))
+
+	opts.Public = true
+	stars, err := filteredStars(s.sess, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := export.Write(w, "json", stars, export.Options{NoReadme: !readme}); err != nil {
+		logger.Errorf("writing response: %s", err)
+	}
+}
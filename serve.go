@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+	"github.com/rubiojr/gh-stars-exporter/pkg/stars"
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// maxIngestBody limits the size of the /ingest request body.
+const maxIngestBody = 1 << 20
+
+type server struct {
+	sess        db.Session
+	gh          githubclient.Client
+	ingestToken string
+	// pagination is the default pagination style of /api/stars.
+	pagination string
+
+	// ingestMu serializes the writes done by /ingest.
+	ingestMu sync.Mutex
+}
+
+// serveCmd runs the HTTP server until ctx is cancelled.
+func serveCmd(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "Address to listen on")
+	ingestToken := flags.String("ingest-token", os.Getenv("GHSTARS_INGEST_TOKEN"), "Token required by the /ingest endpoint (disabled when empty)")
+	pagination := flags.String("pagination", paginationCursor, "Default pagination style of /api/stars: offset or cursor")
+	flags.Parse(args)
+	if err := flagsFromEnv(flags); err != nil {
+		return err
+	}
+	if *pagination != paginationOffset && *pagination != paginationCursor {
+		return fmt.Errorf("unknown pagination style %q, expected offset or cursor", *pagination)
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	s := &server{
+		sess:        sess,
+		ingestToken: *ingestToken,
+		pagination:  *pagination,
+	}
+	if s.ingestToken != "" {
+		gh, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		s.gh = gh
+	} else {
+		logger.Warn("No ingest token configured, /ingest is disabled")
+	}
+
+	return listenAndServe(ctx, *addr, s.routes())
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /ingest", s.handleIngest)
+	mux.HandleFunc("GET /api/check", s.handleCheck)
+	mux.HandleFunc("GET /api/stars", s.handleStars)
+	mux.HandleFunc("GET /api/searches", s.handleSearches)
+	mux.HandleFunc("GET /api/searches/{name}", s.handleSavedSearch)
+	mux.HandleFunc("GET /feed/topic/{name}", s.feedHandler("topic"))
+	mux.HandleFunc("GET /feed/language/{name}", s.feedHandler("language"))
+	mux.HandleFunc("GET /healthz", handleHealthz)
+	mux.Handle("GET /readyz", readyzHandler(s.sess))
+
+	return mux
+}
+
+type ingestRequest struct {
+	URLs []string `json:"urls"`
+}
+
+type ingestResponse struct {
+	Added    []string `json:"added"`
+	Existing []string `json:"existing"`
+	Errors   []string `json:"errors"`
+}
+
+// handleIngest stores the GitHub repositories in the request body as manual
+// bookmarks. Requests must be authenticated with the ingest token, either as
+// a bearer token or with an X-Hub-Signature-256 HMAC of the body.
+func (s *server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if s.ingestToken == "" {
+		http.Error(w, "ingest disabled", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !s.authorized(r, body) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ingestRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.ingestMu.Lock()
+	defer s.ingestMu.Unlock()
+
+	resp := ingestResponse{Added: []string{}, Existing: []string{}, Errors: []string{}}
+	for _, u := range req.URLs {
+		fullName, added, err := s.ingest(r.Context(), u)
+		switch {
+		case err != nil:
+			logger.Warnf("Ingesting %s: %s", u, err)
+			resp.Errors = append(resp.Errors, fmt.Sprintf("%s: %s", u, err))
+		case added:
+			logger.Infof("Bookmarked %s", fullName)
+			resp.Added = append(resp.Added, fullName)
+		default:
+			resp.Existing = append(resp.Existing, fullName)
+		}
+	}
+
+	writeJSON(w, resp)
+}
+
+type checkResponse struct {
+	URL        string `json:"url"`
+	FullName   string `json:"full_name,omitempty"`
+	Starred    bool   `json:"starred"`
+	Bookmarked bool   `json:"bookmarked"`
+	StarredAt  string `json:"starred_at,omitempty"`
+	// UnstarredAt is set for the stars removed upstream since, which
+	// aren't starred anymore.
+	UnstarredAt string `json:"unstarred_at,omitempty"`
+}
+
+// handleCheck reports whether the GitHub URL passed in the url query
+// parameter is already in the database, for browser extensions showing an
+// "already starred" indicator. It doesn't hit the GitHub API. Private
+// repositories are only reported to requests authorized with the ingest
+// token.
+func (s *server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	u := r.URL.Query().Get("url")
+	fullName, err := parseRepoURL(u)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cond := db.And(db.Raw("full_name = ? COLLATE NOCASE", fullName))
+	if s.ingestToken == "" || !s.authorized(r, nil) {
+		cond = cond.And(db.Cond{"private": false})
+	}
+
+	resp := checkResponse{URL: u}
+	var repo Repository
+	err = s.sess.Collection("starred_repos").Find(cond).One(&repo)
+	switch {
+	case errors.Is(err, db.ErrNoMoreRows):
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	default:
+		resp.FullName = repo.FullName
+		resp.Starred = repo.Source == sourceStarred && repo.UnstarredAt == nil
+		resp.Bookmarked = repo.Source == sourceManual
+		resp.StarredAt = repo.StarredAt.UTC().Format(time.RFC3339)
+		if repo.UnstarredAt != nil {
+			resp.UnstarredAt = repo.UnstarredAt.UTC().Format(time.RFC3339)
+		}
+	}
+
+	writeJSON(w, resp)
+}
+
+func (s *server) authorized(r *http.Request, body []byte) bool {
+	if sig, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256="); ok {
+		mac := hmac.New(sha256.New, []byte(s.ingestToken))
+		mac.Write(body)
+		expected := hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(sig), []byte(expected))
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.ingestToken)) == 1
+}
+
+// ingest resolves the repository URL and stores it as a manual bookmark,
+// unless it's already in the database.
+func (s *server) ingest(ctx context.Context, repoURL string) (string, bool, error) {
+	fullName, err := parseRepoURL(repoURL)
+	if err != nil {
+		return "", false, err
+	}
+
+	return addBookmark(ctx, s.gh, s.sess, fullName, time.Now().UTC())
+}
+
+// addBookmark fetches the repository fullName and stores it as a manual
+// bookmark made at the given time, unless it's already in the database.
+// It returns the upstream full name and whether the bookmark was added.
+func addBookmark(ctx context.Context, gh githubclient.Client, sess db.Session, fullName string, at time.Time) (string, bool, error) {
+	upstream, err := gh.Repo(ctx, fullName)
+	if err != nil {
+		return fullName, false, err
+	}
+
+	starred := sess.Collection("starred_repos")
+	exists, err := starred.Find(db.Cond{"id": upstream.ID}).Exists()
+	if err != nil || exists {
+		return upstream.FullName, false, err
+	}
+
+	repo := stars.RepoFromGitHub(githubclient.StarredRepo{Repo: upstream, StarredAt: at})
+	repo.Source = sourceManual
+	if repo.Private && !storePrivate {
+		return upstream.FullName, false, fmt.Errorf("private repository")
+	}
+	if getReadme {
+		if readme, err := gh.Readme(ctx, repo.FullName); err == nil {
+			repo.Readme = store.NullString{String: readme, Valid: true}
+		}
+	}
+	newSyncer(gh, sess).Prepare(&repo)
+
+	_, err = starred.Insert(repo)
+	return upstream.FullName, err == nil, err
+}
+
+// repoNameSegment matches the characters GitHub allows in owners and
+// repository names.
+var repoNameSegment = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// parseRepoURL returns the owner/name of a GitHub repository URL such as
+// https://github.com/owner/name/tree/main. Bare owner/name strings are
+// accepted too. Names with characters GitHub doesn't allow are rejected
+// before they reach the API or the database.
+func parseRepoURL(s string) (string, error) {
+	if !strings.Contains(s, "://") && strings.HasPrefix(s, "github.com/") {
+		s = "https://" + s
+	}
+
+	path := s
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return "", err
+		}
+		if !strings.EqualFold(u.Hostname(), "github.com") && !strings.EqualFold(u.Hostname(), "www.github.com") {
+			return "", fmt.Errorf("not a GitHub URL")
+		}
+		path = u.Path
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 2 {
+		return "", fmt.Errorf("not a repository URL")
+	}
+	owner, name := parts[0], strings.TrimSuffix(parts[1], ".git")
+	for _, segment := range []string{owner, name} {
+		if !repoNameSegment.MatchString(segment) || segment == "." || segment == ".." {
+			return "", fmt.Errorf("not a repository URL")
+		}
+	}
+
+	return owner + "/" + name, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Errorf("writing response: %s", err)
+	}
+}
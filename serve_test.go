@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestParseRepoURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "https://github.com/rubiojr/gh-stars-exporter", want: "rubiojr/gh-stars-exporter"},
+		{in: "https://www.github.com/owner/name/tree/main/pkg", want: "owner/name"},
+		{in: "github.com/owner/name.git", want: "owner/name"},
+		{in: "owner/my_repo.js", want: "owner/my_repo.js"},
+		{in: "https://gitlab.com/owner/name", err: true},
+		{in: "owner", err: true},
+		{in: "owner/", err: true},
+		{in: "/name", err: true},
+		{in: "owner/..", err: true},
+		{in: "../name", err: true},
+		{in: "owner/.", err: true},
+		{in: "owner/.git", err: true},
+		{in: "owner/na me", err: true},
+		{in: "owner/name%2F..", err: true},
+		{in: "https://github.com/owner/na%0Ame", err: true},
+		{in: "owner/name?x=1", err: true},
+		{in: "own;er/name", err: true},
+		{in: "owner/näme", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRepoURL(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseRepoURL(%q) = %q, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseRepoURL(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
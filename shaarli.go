@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/upper/db/v4"
+)
+
+type shaarliLink struct {
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Private     bool     `json:"private"`
+}
+
+// pushShaarli posts the stars as links to the Shaarli instance from the
+// [push.shaarli] configuration section, titled with the repository name,
+// described with its description and the reason it was starred, and tagged
+// with its topics. Links already stored are left alone.
+func pushShaarli(ctx context.Context, sess db.Session, opts pushOptions) error {
+	cfg := config.Push.Shaarli
+	if cfg.URL == "" || cfg.Secret == "" {
+		return fmt.Errorf("no Shaarli instance configured, set url and secret in the [push.shaarli] section of %s", configFile)
+	}
+
+	client := newHTTPClient(httpTimeout)
+	endpoint := strings.TrimSuffix(cfg.URL, "/") + "/api/v1/links"
+	return pushStars(sess, opts.Since, stateLastPushShaarli, func(r Repository) error {
+		description := strings.Join(strings.Fields(r.Description), " ")
+		if r.Reason != "" {
+			description = strings.TrimSpace(description + "\n\nWhy: " + r.Reason)
+		}
+		tags := []string{}
+		for _, t := range r.Topics {
+			if t != "" {
+				tags = append(tags, t)
+			}
+		}
+		body, err := json.Marshal(shaarliLink{
+			URL:         r.HTMLURL,
+			Title:       r.FullName,
+			Description: description,
+			Tags:        tags,
+			Private:     opts.Private,
+		})
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+shaarliToken(cfg.Secret, time.Now()))
+
+		err = doPush(client, req, nil)
+		// Shaarli refuses duplicated URLs with a 409 Conflict.
+		var serr *pushStatusError
+		if errors.As(err, &serr) && serr.Code == http.StatusConflict {
+			logger.Debugf("%s is already in Shaarli", r.FullName)
+			return nil
+		}
+		return err
+	})
+}
+
+// shaarliToken returns the JWT authenticating a Shaarli API request, signed
+// with the API secret and issued at now. Shaarli accepts tokens issued up to
+// nine minutes before.
+func shaarliToken(secret string, now time.Time) string {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"typ":"JWT","alg":"HS512"}`))
+	payload := enc.EncodeToString([]byte(fmt.Sprintf(`{"iat":%d}`, now.Unix())))
+
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write([]byte(header + "." + payload))
+
+	return header + "." + payload + "." + enc.EncodeToString(mac.Sum(nil))
+}
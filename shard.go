@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/stars"
+)
+
+// Default shard budgets, in GraphQL rate limit points out of the 5000
+// available per hour.
+const (
+	defaultShardBudget  = 500
+	defaultShardReserve = 1000
+)
+
+// syncShard syncs the next shard of the star list, see stars.SyncShard.
+func syncShard(ctx context.Context, syncer *stars.Syncer, budget, reserve int) (stars.Shard, error) {
+	if budget < 1 {
+		return stars.Shard{}, fmt.Errorf("the shard budget must be greater than zero")
+	}
+	if getReadme {
+		logger.Warn("READMEs aren't fetched when sharding, backfill them with fetch-readmes")
+	}
+
+	shard, err := syncer.SyncShard(ctx, stars.ShardOptions{Budget: budget, Reserve: reserve})
+	if err != nil {
+		return shard, err
+	}
+
+	if shard.Done {
+		logger.Infof("Star list complete after %d pages, next shards only fetch new stars", shard.Pages)
+	} else {
+		logger.Infof("Shard stopped after %d pages and %d rate limit points, run it again to continue (%d points left until %s)",
+			shard.Pages, shard.Spent, shard.Remaining, displayTime(shard.ResetAt).Format(time.Kitchen))
+	}
+
+	return shard, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/githubclient"
+	"github.com/rubiojr/gh-stars-exporter/pkg/stars"
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// starCmd stars repositories on GitHub and stores them right away, along
+// with the reason they were starred for.
+func starCmd(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("star", flag.ExitOnError)
+	reason := flags.String("reason", "", "Why the repositories are starred, shown in the exports and searchable")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return fmt.Errorf("usage: gh-stars-exporter star [--reason TEXT] REPO...")
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	gh, err := newGitHubClient()
+	if err != nil {
+		return err
+	}
+	for _, name := range flags.Args() {
+		fullName, err := parseRepoURL(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if err := gh.Star(ctx, fullName); err != nil {
+			return err
+		}
+		if err := storeStar(ctx, gh, sess, fullName, strings.TrimSpace(*reason)); err != nil {
+			return fmt.Errorf("storing %s: %w", fullName, err)
+		}
+		logger.Infof("Starred %s", fullName)
+	}
+
+	return nil
+}
+
+// storeStar stores the repository fullName, just starred, with its reason.
+// Repositories already in the database only get the reason updated, the
+// next sync tracks them becoming stars again.
+func storeStar(ctx context.Context, gh githubclient.Client, sess db.Session, fullName, reason string) error {
+	upstream, err := gh.Repo(ctx, fullName)
+	if err != nil {
+		return err
+	}
+
+	starred := sess.Collection("starred_repos")
+	res := starred.Find(db.Cond{"id": upstream.ID})
+	if exists, err := res.Exists(); err != nil {
+		return err
+	} else if exists {
+		if reason == "" {
+			return nil
+		}
+		return res.Update(map[string]interface{}{"reason": reason, "reason_at": time.Now().UTC()})
+	}
+
+	repo := stars.RepoFromGitHub(githubclient.StarredRepo{Repo: upstream, StarredAt: time.Now().UTC()})
+	if repo.Private && !storePrivate {
+		logger.Warnf("Not storing %s, private repositories are stored with --store-private", repo.FullName)
+		return nil
+	}
+	if reason != "" {
+		now := time.Now().UTC()
+		repo.Reason, repo.ReasonAt = reason, &now
+	}
+	if getReadme {
+		if readme, err := gh.Readme(ctx, repo.FullName); err == nil {
+			repo.Readme = store.NullString{String: readme, Valid: true}
+		}
+	}
+	newSyncer(gh, sess).Prepare(&repo)
+
+	_, err = starred.Insert(repo)
+	return err
+}
+
+// reasonCmd prints the reason a stored repository was starred for, or
+// replaces it when given. An empty reason clears it.
+func reasonCmd(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: gh-stars-exporter reason REPO [TEXT]")
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	res, err := findRepo(sess, args[0])
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		var repo Repository
+		if err := res.One(&repo); err != nil {
+			return err
+		}
+		if repo.Reason == "" {
+			return errors.New("no reason recorded for " + repo.FullName)
+		}
+		fmt.Println(repo.Reason)
+		return nil
+	}
+
+	return res.Update(map[string]interface{}{"reason": strings.TrimSpace(args[1]), "reason_at": time.Now().UTC()})
+}
@@ -0,0 +1,9 @@
+package main
+
+// Keys stored in the sync_state table.
+const (
+	stateLastChangelog = "last_changelog_at"
+	stateLastRadar     = "last_radar_at"
+	stateLastBanner    = "last_banner_at"
+	stateLastDigest    = "last_digest_at"
+)
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const statsUsage = `Usage: gh-stars-exporter stats <command>
+
+Commands:
+  scatter  Print the age, activity and popularity of every star, for plotting
+`
+
+// statsCmd groups the statistics of the stars meant for other tools.
+func statsCmd(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, statsUsage)
+		return errUsage
+	}
+
+	switch args[0] {
+	case "scatter":
+		return reportScatter(args[1:])
+	default:
+		fmt.Fprint(os.Stderr, statsUsage)
+		return errUsage
+	}
+}
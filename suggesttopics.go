@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/store"
+	"github.com/upper/db/v4"
+)
+
+// maxTopics is the number of topics GitHub allows per repository.
+const maxTopics = 20
+
+// topicCandidate is a topic suggested for a repository.
+type topicCandidate struct {
+	topic string
+	// score ranks the candidates: the evidence found in the repository.
+	score int
+	// uses is the number of stars tagged with the topic.
+	uses int
+}
+
+// suggestTopicsCmd suggests topics for the user's own repositories found
+// among the stars, learned from the topics of the rest of the stars, and
+// adds them to the repositories on GitHub with --apply.
+func suggestTopicsCmd(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("suggest-topics", flag.ExitOnError)
+	owner := flags.String("owner", "", "Suggest topics for the repositories of this user or organization instead of the authenticated user's")
+	limit := flags.Int("limit", 5, "Maximum number of topics suggested per repository")
+	apply := flags.Bool("apply", false, "Add the suggested topics to the repositories on GitHub")
+	flags.Parse(args)
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	gh, err := newGitHubClient()
+	if err != nil {
+		return err
+	}
+	login := *owner
+	if login == "" {
+		user, err := gh.User(ctx)
+		if err != nil {
+			return err
+		}
+		login = user.Login
+	}
+
+	var stars []Repository
+	err = sess.Collection("starred_repos").
+		Find(db.Cond{"source": sourceStarred, "unstarred_at IS": nil}).
+		OrderBy("full_name").
+		All(&stars)
+	if err != nil {
+		return err
+	}
+
+	vocabulary := topicVocabulary(stars)
+	found := 0
+	for _, r := range stars {
+		if !strings.EqualFold(strings.SplitN(r.FullName, "/", 2)[0], login) {
+			continue
+		}
+		found++
+
+		suggested := suggestTopics(r, vocabulary, *limit)
+		if len(suggested) == 0 {
+			continue
+		}
+		fmt.Printf("%s: %s\n", r.FullName, strings.Join(suggested, ", "))
+		if !*apply {
+			continue
+		}
+
+		// The stored topics may be stale, don't drop the ones added since.
+		upstream, err := gh.Repo(ctx, r.FullName)
+		if err != nil {
+			return err
+		}
+		topics := mergeTopics(upstream.Topics, suggested)
+		if err := gh.ReplaceTopics(ctx, r.FullName, topics); err != nil {
+			return err
+		}
+		if err := sess.Collection("starred_repos").Find(r.ID).Update(map[string]interface{}{"topics": store.StringList(topics)}); err != nil {
+			return err
+		}
+		logger.Infof("Updated the topics of %s", r.FullName)
+	}
+	if found == 0 {
+		logger.Infof("None of the stars is owned by %s", login)
+	}
+
+	return nil
+}
+
+// topicVocabulary returns the topics the suggestions are drawn from, the
+// ones tagging at least two stars, along with their number of uses.
+func topicVocabulary(stars []Repository) map[string]int {
+	uses := map[string]int{}
+	for _, r := range stars {
+		for _, topic := range normalizeTopics(r.Topics, config.Topics.Aliases) {
+			uses[strings.ToLower(topic)]++
+		}
+	}
+	for topic, n := range uses {
+		if n < 2 {
+			delete(uses, topic)
+		}
+	}
+
+	return uses
+}
+
+// suggestTopics returns up to limit topics of the vocabulary that r doesn't
+// have, ranked by where they're found: the language, the description and
+// the README, the most used topics first on ties.
+func suggestTopics(r Repository, vocabulary map[string]int, limit int) []string {
+	have := map[string]bool{}
+	for _, topic := range normalizeTopics(r.Topics, config.Topics.Aliases) {
+		have[strings.ToLower(topic)] = true
+	}
+	language := strings.ToLower(r.Language)
+	description := strings.ToLower(r.Description)
+	readme := strings.ToLower(r.Readme.String)
+
+	var candidates []topicCandidate
+	for topic, uses := range vocabulary {
+		if have[topic] {
+			continue
+		}
+		score := 0
+		if topic == language {
+			score += 3
+		}
+		if mentionsTopic(description, topic) {
+			score += 2
+		}
+		// Short topics like "go" or "ui" are mentioned in any README.
+		if len(topic) > 3 && mentionsTopic(readme, topic) {
+			score++
+		}
+		if score > 0 {
+			candidates = append(candidates, topicCandidate{topic: topic, score: score, uses: uses})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.uses != b.uses {
+			return a.uses > b.uses
+		}
+		return a.topic < b.topic
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	suggested := make([]string, len(candidates))
+	for i, c := range candidates {
+		suggested[i] = c.topic
+	}
+
+	return suggested
+}
+
+// mentionsTopic reports whether the lowercase text mentions topic as a
+// whole word, its dashes matching spaces too: "command-line" is mentioned
+// in "a command line tool".
+func mentionsTopic(text, topic string) bool {
+	for _, variant := range []string{topic, strings.ReplaceAll(topic, "-", " ")} {
+		for i := 0; i < len(text); {
+			j := strings.Index(text[i:], variant)
+			if j < 0 {
+				break
+			}
+			start, end := i+j, i+j+len(variant)
+			if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+				return true
+			}
+			i = start + 1
+		}
+	}
+
+	return false
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b == '-' || b == '_'
+}
+
+// mergeTopics returns the current topics followed by the suggested ones
+// missing, within the GitHub limit.
+func mergeTopics(current, suggested []string) []string {
+	topics := append([]string{}, current...)
+	seen := map[string]bool{}
+	for _, topic := range current {
+		seen[strings.ToLower(topic)] = true
+	}
+	for _, topic := range suggested {
+		if len(topics) == maxTopics {
+			break
+		}
+		if !seen[topic] {
+			topics = append(topics, topic)
+		}
+	}
+
+	return topics
+}
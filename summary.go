@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/stars"
+)
+
+// runSummary is printed to stdout as JSON at the end of the run when
+// --summary-json is enabled, so wrappers don't need to scrape the logs.
+type runSummary struct {
+	Sync       *syncSummary   `json:"sync,omitempty"`
+	Export     *exportSummary `json:"export,omitempty"`
+	DurationMS int64          `json:"duration_ms"`
+	Errors     []string       `json:"errors"`
+
+	started time.Time
+}
+
+type syncSummary struct {
+	stars.Stats
+	// Shard is set when syncing a shard of the star list.
+	Shard      *stars.Shard `json:"shard,omitempty"`
+	DurationMS int64        `json:"duration_ms"`
+}
+
+type exportSummary struct {
+	Format     string `json:"format"`
+	Repos      int    `json:"repos"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+var summary = &runSummary{started: time.Now(), Errors: []string{}}
+
+// addError records a non-fatal error in the summary, when enabled.
+func (s *runSummary) addError(err error) {
+	if !summaryJSON {
+		return
+	}
+	s.Errors = append(s.Errors, err.Error())
+}
+
+// print writes the summary to stdout when --summary-json is enabled.
+func (s *runSummary) print() {
+	if !summaryJSON {
+		return
+	}
+
+	s.DurationMS = time.Since(s.started).Milliseconds()
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		logger.Errorf("encoding summary: %s", err)
+		return
+	}
+	fmt.Fprintln(os.Stdout, string(b))
+}
+
+// failed records err in the summary, prints it and returns err with msg
+// prepended, for the command to fail with.
+func failed(msg string, err error) error {
+	err = fmt.Errorf("%s: %w", msg, err)
+	summary.addError(err)
+	summary.print()
+
+	return err
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rubiojr/gh-stars-exporter/pkg/stars"
+	"github.com/upper/db/v4"
+)
+
+// reportErrors prints the errors of the last sync run, or of the last runs
+// kept, instead of digging them out of the logs.
+func reportErrors(args []string) error {
+	flags := flag.NewFlagSet("errors", flag.ExitOnError)
+	all := flags.Bool("all", false, "Print the errors of every run kept, not only the last one")
+	kind := flags.String("kind", "", "Only print the errors of a kind: insert, readme, decode or sync")
+	asJSON := flags.Bool("json", false, "Print the errors as JSON")
+	flags.Parse(args)
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	lastRun, err := stars.LastRun(sess)
+	if err != nil {
+		return err
+	}
+	if lastRun.IsZero() {
+		logger.Info("No sync run recorded yet")
+		return nil
+	}
+
+	cond := db.Cond{}
+	if !*all {
+		cond["run_at"] = lastRun
+	}
+	if *kind != "" {
+		cond["kind"] = *kind
+	}
+	var errs []stars.SyncError
+	err = sess.Collection("sync_errors").Find(cond).OrderBy("-run_at", "kind", "item", "id").All(&errs)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if errs == nil {
+			errs = []stars.SyncError{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(errs)
+	}
+
+	if len(errs) == 0 {
+		logger.Infof("The last run, on %s, had no errors", displayTime(lastRun).Format(time.DateTime))
+		return nil
+	}
+
+	return writeSyncErrors(os.Stdout, errs)
+}
+
+// writeSyncErrors prints errs in a table per run, the most recent run
+// first.
+func writeSyncErrors(out io.Writer, errs []stars.SyncError) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for i, e := range errs {
+		if i == 0 || !e.RunAt.Equal(errs[i-1].RunAt) {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			n := 0
+			for _, o := range errs[i:] {
+				if o.RunAt.Equal(e.RunAt) {
+					n++
+				}
+			}
+			fmt.Fprintf(w, "Run of %s, %d errors\n", displayTime(e.RunAt).Format(time.DateTime), n)
+			fmt.Fprintln(w, "KIND\tREPOSITORY\tERROR")
+		}
+		item := e.Item
+		if item == "" {
+			item = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Kind, item, strings.ReplaceAll(e.Error, "\n", " "))
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,12 @@
+package main
+
+import "time"
+
+// displayLocation is the time zone dates are displayed in, set with --tz.
+// Timestamps are always stored in UTC.
+var displayLocation = time.Local
+
+// displayTime returns t in the time zone dates are displayed in.
+func displayTime(t time.Time) time.Time {
+	return t.In(displayLocation)
+}
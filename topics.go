@@ -0,0 +1,32 @@
+package main
+
+import "strings"
+
+// normalizeTopics maps topics through the [topics] aliases, removing the
+// duplicates the merges produce.
+func normalizeTopics(topics []string, aliases map[string]string) []string {
+	if len(aliases) == 0 {
+		return topics
+	}
+
+	seen := map[string]bool{}
+	normalized := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		topic = normalizeTopic(topic, aliases)
+		if seen[topic] {
+			continue
+		}
+		seen[topic] = true
+		normalized = append(normalized, topic)
+	}
+
+	return normalized
+}
+
+func normalizeTopic(topic string, aliases map[string]string) string {
+	if alias, ok := aliases[strings.ToLower(topic)]; ok {
+		return alias
+	}
+
+	return topic
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/upper/db/v4"
+)
+
+// translateCmd translates the descriptions of the stored repositories not
+// written in the target language, using the LibreTranslate compatible
+// endpoint from the [translate] configuration section.
+func translateCmd(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("translate", flag.ExitOnError)
+	limit := flags.Int("limit", 100, "Maximum number of descriptions translated")
+	flags.Parse(args)
+
+	cfg := config.Translate
+	if cfg.Endpoint == "" {
+		return fmt.Errorf("no translation endpoint configured, set endpoint in the [translate] section of %s", configFile)
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	var repos []Repository
+	err = sess.Collection("starred_repos").
+		Find(db.Cond{"description !=": "", "description_translated": ""}).
+		OrderBy("-starred_at").
+		All(&repos)
+	if err != nil {
+		return err
+	}
+
+	client := newHTTPClient(httpTimeout)
+	translated := 0
+	for _, r := range repos {
+		if translated == *limit {
+			break
+		}
+		if !needsTranslation(r, cfg.Target) {
+			continue
+		}
+
+		text, err := translateText(ctx, client, cfg, r.Description)
+		if err != nil {
+			return fmt.Errorf("translating the description of %s: %w", r.FullName, err)
+		}
+		if text == "" || text == r.Description {
+			continue
+		}
+
+		_, err = sess.SQL().Update("starred_repos").Set("description_translated", text).Where("id = ?", r.ID).Exec()
+		if err != nil {
+			return err
+		}
+		logger.Debugf("Translated %s: %s", r.FullName, text)
+		translated++
+	}
+	logger.Infof("Translated %d descriptions", translated)
+
+	return nil
+}
+
+// needsTranslation reports whether the description of r is likely written
+// in a language other than target: its README is written in another
+// language, or the description uses a script other than Latin.
+func needsTranslation(r Repository, target string) bool {
+	if r.ReadmeLanguage != "" && r.ReadmeLanguage != target {
+		return true
+	}
+
+	for _, c := range r.Description {
+		if unicode.IsLetter(c) && !unicode.Is(unicode.Latin, c) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type translateRequest struct {
+	Q      string `json:"q"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Format string `json:"format"`
+	APIKey string `json:"api_key,omitempty"`
+}
+
+type translateResponse struct {
+	TranslatedText string `json:"translatedText"`
+	Error          string `json:"error"`
+}
+
+// translateText translates text to the target language using the
+// LibreTranslate API.
+func translateText(ctx context.Context, client *http.Client, cfg TranslateConfig, text string) (string, error) {
+	body, err := json.Marshal(translateRequest{
+		Q:      text,
+		Source: "auto",
+		Target: cfg.Target,
+		Format: "text",
+		APIKey: cfg.APIKey,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var tr translateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return "", fmt.Errorf("%s: %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", resp.Status, tr.Error)
+	}
+
+	return strings.TrimSpace(tr.TranslatedText), nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/upper/db/v4"
+)
+
+// historyRow is a stargazers snapshot of a starred repository.
+type historyRow struct {
+	RepoID          int       `db:"repo_id"`
+	FullName        string    `db:"full_name"`
+	StargazersCount int       `db:"stargazers_count"`
+	RecordedAt      time.Time `db:"recorded_at"`
+}
+
+// trend is the stargazers growth of a repository between the two latest
+// snapshots.
+type trend struct {
+	FullName string
+	Stars    int
+	Growth   int
+	// Percent is the growth relative to the previous count.
+	Percent float64
+	// Spike is set when the growth rate is well above the historical
+	// growth rate of the repository.
+	Spike bool
+}
+
+// reportTrending ranks the starred repositories by their stargazers growth
+// since the previous snapshot, flagging unusual spikes.
+func reportTrending(args []string) error {
+	flags := flag.NewFlagSet("trending", flag.ExitOnError)
+	limit := flags.Int("limit", 20, "Number of repositories listed")
+	spike := flags.Float64("spike", 5, "Growth rate, relative to the historical rate, flagged as a spike")
+	minGrowth := flags.Int("min-growth", 10, "Minimum number of new stargazers flagged as a spike")
+	flags.Parse(args)
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	var snapshots []historyRow
+	err = sess.SQL().
+		Select(db.Raw("DISTINCT recorded_at")).
+		From("stargazers_history").
+		OrderBy("-recorded_at").
+		Limit(2).
+		All(&snapshots)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) < 2 {
+		return fmt.Errorf("not enough stargazers snapshots, trending needs at least two syncs")
+	}
+
+	var history []historyRow
+	err = sess.SQL().
+		Select("h.repo_id", "r.full_name", "h.stargazers_count", "h.recorded_at").
+		From("stargazers_history AS h").
+		Join("starred_repos AS r").On("r.id = h.repo_id").
+		Where(db.Cond{"r.source": sourceStarred, "r.unstarred_at IS": nil}).
+		OrderBy("h.repo_id", "h.recorded_at").
+		All(&history)
+	if err != nil {
+		return err
+	}
+
+	trends := buildTrends(history, snapshots[1].RecordedAt, snapshots[0].RecordedAt, *spike, *minGrowth)
+	logger.Infof("Stargazers growth since %s", displayTime(snapshots[1].RecordedAt).Format(time.DateTime))
+
+	return writeTrending(os.Stdout, trends, *limit)
+}
+
+// buildTrends computes the growth of every repository between the previous
+// and latest snapshots. history holds the snapshots of each repository in
+// chronological order, only recorded when the count changed.
+func buildTrends(history []historyRow, previous, latest time.Time, spike float64, minGrowth int) []trend {
+	var trends []trend
+	for start := 0; start < len(history); {
+		end := start
+		for end < len(history) && history[end].RepoID == history[start].RepoID {
+			end++
+		}
+		if t, ok := repoTrend(history[start:end], previous, latest, spike, minGrowth); ok {
+			trends = append(trends, t)
+		}
+		start = end
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Growth != trends[j].Growth {
+			return trends[i].Growth > trends[j].Growth
+		}
+		return trends[i].FullName < trends[j].FullName
+	})
+
+	return trends
+}
+
+func repoTrend(rows []historyRow, previous, latest time.Time, spike float64, minGrowth int) (trend, bool) {
+	// The count at a snapshot is the last one recorded up to it.
+	prev := -1
+	for i, r := range rows {
+		if r.RecordedAt.After(previous) {
+			break
+		}
+		prev = i
+	}
+	if prev < 0 {
+		// Starred after the previous snapshot.
+		return trend{}, false
+	}
+
+	last := rows[len(rows)-1]
+	t := trend{
+		FullName: last.FullName,
+		Stars:    last.StargazersCount,
+		Growth:   last.StargazersCount - rows[prev].StargazersCount,
+	}
+	if t.Growth <= 0 {
+		return trend{}, false
+	}
+	if n := rows[prev].StargazersCount; n > 0 {
+		t.Percent = float64(t.Growth) / float64(n) * 100
+	}
+
+	// Compare the growth rate with the one from the first snapshot of the
+	// repository to the previous one.
+	first := rows[0]
+	if t.Growth >= minGrowth && first.RecordedAt.Before(previous) {
+		rate := float64(t.Growth) / latest.Sub(previous).Hours()
+		historical := float64(rows[prev].StargazersCount-first.StargazersCount) / previous.Sub(first.RecordedAt).Hours()
+		t.Spike = historical <= 0 || rate >= spike*historical
+	}
+
+	return t, true
+}
+
+func writeTrending(out io.Writer, trends []trend, limit int) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "Repository\tStars\tGrowth\tPercent\tSpike")
+	for i, t := range trends {
+		if i == limit {
+			break
+		}
+		spike := ""
+		if t.Spike {
+			spike = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%d\t+%d\t%.1f%%\t%s\n", t.FullName, t.Stars, t.Growth, t.Percent, spike)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/upper/db/v4"
+)
+
+type wallabagToken struct {
+	AccessToken string `json:"access_token"`
+}
+
+type wallabagEntry struct {
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Tags    string `json:"tags,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// pushWallabag saves the stars as articles in the wallabag instance from
+// the [push.wallabag] configuration section, titled with the repository
+// name and tagged with its topics. The article body is the README when
+// stored, so wallabag doesn't fetch the GitHub page. Entries
+// already stored for the same URL are updated.
+func pushWallabag(ctx context.Context, sess db.Session, opts pushOptions) error {
+	cfg := config.Push.Wallabag
+	if cfg.URL == "" || cfg.ClientID == "" || cfg.Username == "" {
+		return fmt.Errorf("no wallabag instance configured, set url, client_id, client_secret, username and password in the [push.wallabag] section of %s", configFile)
+	}
+
+	client := newHTTPClient(httpTimeout)
+	base := strings.TrimSuffix(cfg.URL, "/")
+	token, err := wallabagLogin(ctx, client, base, cfg)
+	if err != nil {
+		return fmt.Errorf("authenticating to wallabag: %w", err)
+	}
+
+	return pushStars(sess, opts.Since, stateLastPushWallabag, func(r Repository) error {
+		var tags []string
+		for _, t := range r.Topics {
+			if t != "" {
+				tags = append(tags, t)
+			}
+		}
+		body, err := json.Marshal(wallabagEntry{
+			URL:     r.HTMLURL,
+			Title:   r.FullName,
+			Tags:    strings.Join(tags, ","),
+			Content: wallabagContent(r),
+		})
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", base+"/api/entries.json", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return doPush(client, req, nil)
+	})
+}
+
+// wallabagLogin returns an API access token, using the OAuth password
+// grant.
+func wallabagLogin(ctx context.Context, client *http.Client, base string, cfg WallabagConfig) (string, error) {
+	form := url.Values{
+		"grant_type":    {"password"},
+		"client_id":     {cfg.ClientID},
+		"client_secret": {cfg.ClientSecret},
+		"username":      {cfg.Username},
+		"password":      {cfg.Password},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", base+"/oauth/v2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token wallabagToken
+	if err := doPush(client, req, &token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("no access token returned")
+	}
+
+	return token.AccessToken, nil
+}
+
+// wallabagContent is the HTML body of the article of r: its description,
+// the reason it was starred and its README, preformatted. Empty when the
+// README isn't stored, wallabag fetches the page then.
+func wallabagContent(r Repository) string {
+	readme := strings.TrimSpace(r.Readme.String)
+	if readme == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	if d := strings.TrimSpace(r.Description); d != "" {
+		fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(d))
+	}
+	if r.Reason != "" {
+		fmt.Fprintf(&b, "<p>Why: %s</p>\n", html.EscapeString(r.Reason))
+	}
+	fmt.Fprintf(&b, "<pre>%s</pre>\n", html.EscapeString(readme))
+
+	return b.String()
+}
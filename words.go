@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/upper/db/v4"
+)
+
+// stopWords are the words left out of the word frequencies: common English
+// words and the ones every README is full of.
+var stopWords = wordSet(`
+a about above after again against all almost also although always am among an and any are aren't as at
+be because been before being below between both but by can can't cannot could couldn't did didn't do does
+doesn't doing don't down during each either else etc even ever every few for from further get gets getting
+got had hadn't has hasn't have haven't having he her here hers herself him himself his how however i if in
+into is isn't it it's its itself just let like made make makes many may me might more most much must my
+myself need needs no nor not now of off often on once one only or other others our ours ourselves out over
+own per please rather same she should shouldn't since so some such than that that's the their theirs them
+themselves then there there's these they this those though through thus to too under until up upon us use
+used uses using via very was wasn't we well were weren't what when where whether which while who whom whose
+why will with within without would wouldn't yet you your yours yourself yourselves
+
+also new see example examples readme github com www http https html png jpg svg gif img src href io org
+md badge badges shields build status main master branch license licensed copyright file files run running
+install installation installing usage default version release releases note true false null yes
+`)
+
+// wordCount is a word of the word frequencies, in the {"text", "value"}
+// shape word cloud libraries like d3-cloud take.
+type wordCount struct {
+	Text string `json:"text"`
+	// Value is the number of stars mentioning the word, so that a README
+	// repeating it doesn't outweigh the rest.
+	Value int `json:"value"`
+	// Occurrences is the number of times the word is used overall.
+	Occurrences int `json:"occurrences"`
+}
+
+var (
+	markdownCode  = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+	markdownLinks = regexp.MustCompile(`https?://\S+|<[^>]+>|\]\([^)]*\)`)
+)
+
+// wordsCmd exports the frequencies of the words used in the descriptions
+// and READMEs of the stars as JSON, ready to be rendered as a word cloud.
+func wordsCmd(args []string) error {
+	flags := flag.NewFlagSet("words", flag.ExitOnError)
+	limit := flags.Int("limit", 100, "Maximum number of words exported, 0 exports them all")
+	minLength := flags.Int("min-length", 3, "Minimum length of the words counted")
+	source := flags.String("source", "all", "Text the words are counted in: description, readme or all")
+	stopFile := flags.String("stop-words", "", "File with more words to leave out, separated by spaces or newlines")
+	flags.Parse(args)
+
+	if *source != "all" && *source != "description" && *source != "readme" {
+		return fmt.Errorf("unknown --source %q, expected description, readme or all", *source)
+	}
+	stop := stopWords
+	if *stopFile != "" {
+		b, err := os.ReadFile(*stopFile)
+		if err != nil {
+			return fmt.Errorf("--stop-words: %w", err)
+		}
+		stop = wordSet(string(b))
+		for w := range stopWords {
+			stop[w] = true
+		}
+	}
+
+	sess, err := dbInit()
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sess.Close()
+
+	var stars []Repository
+	err = sess.Collection("starred_repos").
+		Find(db.Cond{"source": sourceStarred, "unstarred_at IS": nil}).
+		All(&stars)
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]*wordCount{}
+	for _, r := range stars {
+		var text []string
+		if *source != "readme" {
+			text = append(text, r.Description)
+		}
+		if *source != "description" {
+			text = append(text, r.Readme.String)
+		}
+
+		seen := map[string]bool{}
+		for _, w := range countedWords(strings.Join(text, "\n"), *minLength, stop) {
+			c := counts[w]
+			if c == nil {
+				c = &wordCount{Text: w}
+				counts[w] = c
+			}
+			c.Occurrences++
+			if !seen[w] {
+				seen[w] = true
+				c.Value++
+			}
+		}
+	}
+
+	words := make([]wordCount, 0, len(counts))
+	for _, c := range counts {
+		words = append(words, *c)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		a, b := words[i], words[j]
+		if a.Value != b.Value {
+			return a.Value > b.Value
+		}
+		if a.Occurrences != b.Occurrences {
+			return a.Occurrences > b.Occurrences
+		}
+		return a.Text < b.Text
+	})
+	if *limit > 0 && len(words) > *limit {
+		words = words[:*limit]
+	}
+
+	b, err := json.MarshalIndent(words, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(string(b))
+
+	return err
+}
+
+// countedWords returns the lowercase words of text of at least minLength
+// letters that aren't stop words, leaving out code, links and HTML tags.
+// Words made of digits only aren't counted.
+func countedWords(text string, minLength int, stop map[string]bool) []string {
+	text = markdownCode.ReplaceAllString(text, " ")
+	text = markdownLinks.ReplaceAllString(text, " ")
+
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '-'
+	}) {
+		w = strings.Trim(w, "'-")
+		if len([]rune(w)) < minLength || stop[w] || strings.Trim(w, "0123456789") == "" {
+			continue
+		}
+		words = append(words, w)
+	}
+
+	return words
+}
+
+// wordSet returns the words of s, separated by spaces or newlines.
+func wordSet(s string) map[string]bool {
+	set := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		set[strings.ToLower(scanner.Text())] = true
+	}
+	return set
+}